@@ -0,0 +1,444 @@
+package accesscontrol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// PendingAdmin 待接受的管理员提名
+// 被提名账户需要使用自己的证书调用AcceptAdmin后才会成为管理员，避免输错账户标识导致权限丢失
+type PendingAdmin struct {
+	Account      string `json:"account"`      // 被提名账户标识
+	ProposedBy   string `json:"proposedBy"`   // 提名的管理员账户标识
+	ReplaceAdmin string `json:"replaceAdmin"` // 接受后需要移除的管理员账户标识，为空表示仅新增管理员
+	ProposedAt   int64  `json:"proposedAt"`   // 提名时间（交易时间戳，Unix秒）
+	ExpiresAt    int64  `json:"expiresAt"`    // 失效时间（Unix秒），超过后不能再接受，需要重新提名
+}
+
+const (
+	// pendingAdminsKey 待接受的管理员提名在链上的存储键
+	// 格式：permission:pendingAdmins
+	pendingAdminsKey = "permission:pendingAdmins"
+
+	// adminNominationTTL 管理员提名的有效期（秒），按交易时间戳判断
+	adminNominationTTL int64 = 7 * 24 * 3600
+)
+
+// ================== 管理员管理相关 ==================
+
+// ProposeAdmin 提名新管理员（两步交接的第一步）
+// 权限要求：只有管理员可以调用此方法，开启多管理员审批后需通过CreateConfigProposal发起
+func (c *PermissionChaincode) ProposeAdmin(ctx contractapi.TransactionContextInterface, newAdmin string) error {
+	return c.proposeAdmin(ctx, newAdmin, false)
+}
+
+// AcceptAdmin 接受管理员提名（两步交接的第二步）
+// 权限要求：只能由被提名账户使用自己的证书调用
+func (c *PermissionChaincode) AcceptAdmin(ctx contractapi.TransactionContextInterface) error {
+	caller := common.GetCaller(ctx)
+	log.Printf("开始接受管理员提名 - 调用者: %s", caller)
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		log.Printf("获取项目配置失败: %v", err)
+		return err
+	}
+	// 被冻结的账户不能通过接受提名成为管理员
+	if err := checkNotFrozen(ctx, caller); err != nil {
+		return err
+	}
+
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	pending, err := c.getPendingAdmins(ctx)
+	if err != nil {
+		return err
+	}
+	nomination, ok := pending[caller]
+	if !ok {
+		log.Printf("接受管理员提名失败 - 调用者未被提名: %s", caller)
+		return errors.New("caller has no pending admin nomination")
+	}
+	if nomination.expired(txTime.Unix()) {
+		log.Printf("接受管理员提名失败 - 提名已过期: %s, 过期时间: %d", caller, nomination.expiresAt())
+		return errors.New("admin nomination has expired")
+	}
+	// 兼容修复前遗留的提名：提名人已不是管理员时提名无效
+	if !isAdminAccount(cfg.Admins, nomination.ProposedBy) {
+		log.Printf("接受管理员提名失败 - 提名人已不是管理员: %s", nomination.ProposedBy)
+		return errors.New("admin nomination was made by an account that is no longer admin")
+	}
+	delete(pending, caller)
+
+	oldAdmins := append([]string{}, cfg.Admins...)
+	if !isAdminAccount(cfg.Admins, caller) {
+		cfg.Admins = append(cfg.Admins, caller)
+	}
+	// 管理员转移：移除提名时指定的原管理员，但不能移除最后一个管理员
+	removed := ""
+	if nomination.ReplaceAdmin != "" && !common.MatchAccount(nomination.ReplaceAdmin, caller) {
+		if stored, ok := findAdmin(cfg.Admins, nomination.ReplaceAdmin); ok {
+			cfg.Admins = removeString(cfg.Admins, stored)
+			removed = stored
+			// 被替换的管理员发起或指向其的其他提名一并作废
+			purgeAdminNominations(pending, stored)
+		}
+	}
+	pruneExpiredNominations(pending, txTime.Unix())
+
+	if err := c.putPendingAdmins(ctx, pending); err != nil {
+		log.Printf("管理员提名更新存储失败: %v", err)
+		return err
+	}
+	if err := putProjectConfig(ctx, cfg); err != nil {
+		log.Printf("项目配置更新存储失败: %v", err)
+		return err
+	}
+	log.Printf("管理员列表更新 - 新增管理员: %s, 移除管理员: %s, 当前管理员数量: %d", caller, removed, len(cfg.Admins))
+	if err := recordAudit(ctx, "AcceptAdmin", "admins", oldAdmins, cfg.Admins); err != nil {
+		return err
+	}
+
+	eventPayload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode":  cfg.ServiceCode,
+		"projectCode":  cfg.ProjectCode,
+		"newAdmin":     caller,
+		"proposedBy":   nomination.ProposedBy,
+		"removedAdmin": removed,
+		"admins":       cfg.Admins})
+	return common.EmitEvent(ctx, "AdminAccepted", eventPayload)
+}
+
+// CancelAdminProposal 撤回尚未被接受的管理员提名
+// 权限要求：只有管理员可以调用此方法
+func (c *PermissionChaincode) CancelAdminProposal(ctx contractapi.TransactionContextInterface, newAdmin string) error {
+	log.Printf("开始撤回管理员提名 - 被提名账户: %s", newAdmin)
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		log.Printf("获取项目配置失败: %v", err)
+		return err
+	}
+	if !common.IsAdmin(ctx, cfg.Admins) {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: CancelAdminProposal", common.GetCaller(ctx))
+		return errors.New("only admin can cancel admin proposal")
+	}
+	if err := checkNotFrozen(ctx, common.GetCaller(ctx)); err != nil {
+		return err
+	}
+
+	pending, err := c.getPendingAdmins(ctx)
+	if err != nil {
+		return err
+	}
+	nomination, ok := pending[newAdmin]
+	if !ok {
+		log.Printf("撤回管理员提名失败 - 提名不存在: %s", newAdmin)
+		return errors.New("admin proposal not found")
+	}
+	delete(pending, newAdmin)
+	if err := c.putPendingAdmins(ctx, pending); err != nil {
+		log.Printf("管理员提名更新存储失败: %v", err)
+		return err
+	}
+	log.Printf("管理员提名已撤回 - 被提名账户: %s", newAdmin)
+	if err := recordAudit(ctx, "CancelAdminProposal", newAdmin, nomination, nil); err != nil {
+		return err
+	}
+
+	eventPayload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode": cfg.ServiceCode,
+		"projectCode": cfg.ProjectCode,
+		"newAdmin":    newAdmin,
+		"sender":      common.GetCaller(ctx)})
+	return common.EmitEvent(ctx, "AdminProposalCancelled", eventPayload)
+}
+
+// RemoveAdmin 移除管理员，不允许移除最后一个管理员
+// 权限要求：只有管理员可以调用此方法
+func (c *PermissionChaincode) RemoveAdmin(ctx contractapi.TransactionContextInterface, admin string) error {
+	log.Printf("开始移除管理员 - 管理员: %s", admin)
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		log.Printf("获取项目配置失败: %v", err)
+		return err
+	}
+	if !common.IsAdmin(ctx, cfg.Admins) {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: RemoveAdmin", common.GetCaller(ctx))
+		return errors.New("only admin can remove admin")
+	}
+	if err := checkNotFrozen(ctx, common.GetCaller(ctx)); err != nil {
+		return err
+	}
+	log.Printf("权限校验通过 - 调用者: %s, 操作: RemoveAdmin", common.GetCaller(ctx))
+	if err := checkSingleAdminOperation(cfg, ProposalOpRemoveAdmin); err != nil {
+		return err
+	}
+
+	eventName, payload, err := c.applyRemoveAdmin(ctx, cfg, admin)
+	if err != nil {
+		return err
+	}
+	return common.EmitEvent(ctx, eventName, payload)
+}
+
+// GetPendingAdmins 查询所有未过期的待接受管理员提名
+func (c *PermissionChaincode) GetPendingAdmins(ctx contractapi.TransactionContextInterface) ([]PendingAdmin, error) {
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := c.getPendingAdmins(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]PendingAdmin, 0, len(pending))
+	for _, nomination := range pending {
+		if !nomination.expired(txTime.Unix()) {
+			result = append(result, nomination)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Account < result[j].Account })
+	return result, nil
+}
+
+// ================== 内部方法 ==================
+
+// proposeAdmin 内部方法：校验管理员身份后提名新管理员，transfer为true时表示接受后移除调用者
+func (c *PermissionChaincode) proposeAdmin(ctx contractapi.TransactionContextInterface, newAdmin string, transfer bool) error {
+	caller := common.GetCaller(ctx)
+	operation := ProposalOpProposeAdmin
+	replaceAdmin := ""
+	if transfer {
+		operation = ProposalOpTransferAdminRole
+		replaceAdmin = caller
+	}
+	log.Printf("开始提名管理员 - 新管理员: %s, 替换管理员: %s", newAdmin, replaceAdmin)
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		log.Printf("获取项目配置失败: %v", err)
+		return err
+	}
+	if !common.IsAdmin(ctx, cfg.Admins) {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: %s", caller, operation)
+		return errors.New("only admin can propose admin")
+	}
+	if err := checkNotFrozen(ctx, caller); err != nil {
+		return err
+	}
+	log.Printf("权限校验通过 - 调用者: %s, 操作: %s", caller, operation)
+	if err := checkSingleAdminOperation(cfg, operation); err != nil {
+		return err
+	}
+
+	eventName, payload, err := c.applyAdminNomination(ctx, cfg, newAdmin, caller, replaceAdmin)
+	if err != nil {
+		return err
+	}
+	return common.EmitEvent(ctx, eventName, payload)
+}
+
+// applyAdminNomination 内部方法：记录管理员提名，返回待发送的事件
+func (c *PermissionChaincode) applyAdminNomination(ctx contractapi.TransactionContextInterface, cfg *common.ProjectConfig, newAdmin, proposedBy, replaceAdmin string) (string, []byte, error) {
+	if err := common.ValidateAccount(newAdmin); err != nil {
+		log.Printf("参数校验失败 - %v", err)
+		return "", nil, err
+	}
+	if isAdminAccount(cfg.Admins, newAdmin) {
+		log.Printf("状态校验失败 - 新管理员已存在: %s", newAdmin)
+		return "", nil, errors.New("new admin is already in admin list")
+	}
+
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	pending, err := c.getPendingAdmins(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	var oldNomination *PendingAdmin
+	if existing, ok := pending[newAdmin]; ok {
+		oldNomination = &existing
+	}
+	pruneExpiredNominations(pending, txTime.Unix())
+	nomination := PendingAdmin{
+		Account:      newAdmin,
+		ProposedBy:   proposedBy,
+		ReplaceAdmin: replaceAdmin,
+		ProposedAt:   txTime.Unix(),
+		ExpiresAt:    txTime.Unix() + adminNominationTTL,
+	}
+	pending[newAdmin] = nomination
+	if err := c.putPendingAdmins(ctx, pending); err != nil {
+		log.Printf("管理员提名存储失败: %v", err)
+		return "", nil, err
+	}
+	log.Printf("管理员提名存储成功 - 新管理员: %s, 等待其使用自身证书调用AcceptAdmin", newAdmin)
+	operation := ProposalOpProposeAdmin
+	if replaceAdmin != "" {
+		operation = ProposalOpTransferAdminRole
+	}
+	if err := recordAudit(ctx, operation, newAdmin, oldNomination, nomination); err != nil {
+		return "", nil, err
+	}
+
+	eventPayload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode": cfg.ServiceCode,
+		"projectCode": cfg.ProjectCode,
+		"nomination":  nomination})
+	return "AdminProposed", eventPayload, nil
+}
+
+// applyRemoveAdmin 内部方法：移除管理员，不允许移除最后一个管理员或使管理员数量低于审批阈值
+func (c *PermissionChaincode) applyRemoveAdmin(ctx contractapi.TransactionContextInterface, cfg *common.ProjectConfig, admin string) (string, []byte, error) {
+	if strings.TrimSpace(admin) == "" {
+		log.Printf("参数校验失败 - 管理员为空")
+		return "", nil, errors.New("admin cannot be empty")
+	}
+	stored, ok := findAdmin(cfg.Admins, admin)
+	if !ok {
+		log.Printf("状态校验失败 - 账户不是管理员: %s", admin)
+		return "", nil, errors.New("account is not admin")
+	}
+	if len(cfg.Admins) <= 1 {
+		log.Printf("状态校验失败 - 不能移除最后一个管理员: %s", admin)
+		return "", nil, errors.New("cannot remove the last admin")
+	}
+	if len(cfg.Admins)-1 < cfg.ApprovalThreshold {
+		log.Printf("状态校验失败 - 移除后管理员数量低于审批阈值: %d", cfg.ApprovalThreshold)
+		return "", nil, fmt.Errorf("cannot remove admin: admin count would fall below approval threshold %d", cfg.ApprovalThreshold)
+	}
+
+	oldAdmins := append([]string{}, cfg.Admins...)
+	cfg.Admins = removeString(cfg.Admins, stored)
+	if err := putProjectConfig(ctx, cfg); err != nil {
+		log.Printf("项目配置更新存储失败: %v", err)
+		return "", nil, err
+	}
+	log.Printf("管理员列表更新 - 移除管理员: %s, 当前管理员数量: %d", stored, len(cfg.Admins))
+
+	// 同一写入中作废该管理员发起的、以及提名该管理员的待接受提名，避免被移除的管理员留下的提名在之后被接受
+	pending, err := c.getPendingAdmins(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	if purged := purgeAdminNominations(pending, stored); len(purged) > 0 {
+		if err := c.putPendingAdmins(ctx, pending); err != nil {
+			log.Printf("管理员提名更新存储失败: %v", err)
+			return "", nil, err
+		}
+		log.Printf("作废被移除管理员相关的提名 - 被提名账户: %v", purged)
+	}
+	if err := recordAudit(ctx, ProposalOpRemoveAdmin, "admins", oldAdmins, cfg.Admins); err != nil {
+		return "", nil, err
+	}
+
+	eventPayload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode":  cfg.ServiceCode,
+		"projectCode":  cfg.ProjectCode,
+		"removedAdmin": stored,
+		"admins":       cfg.Admins,
+		"sender":       common.GetCaller(ctx)})
+	return "AdminRemoved", eventPayload, nil
+}
+
+// getPendingAdmins 内部方法：获取待接受的管理员提名
+func (c *PermissionChaincode) getPendingAdmins(ctx contractapi.TransactionContextInterface) (map[string]PendingAdmin, error) {
+	pending := make(map[string]PendingAdmin)
+	b, err := ctx.GetStub().GetState(pendingAdminsKey)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return pending, nil
+	}
+	if err := json.Unmarshal(b, &pending); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// putPendingAdmins 内部方法：存储待接受的管理员提名
+func (c *PermissionChaincode) putPendingAdmins(ctx contractapi.TransactionContextInterface, pending map[string]PendingAdmin) error {
+	if len(pending) == 0 {
+		return ctx.GetStub().DelState(pendingAdminsKey)
+	}
+	b, _ := json.Marshal(pending)
+	return ctx.GetStub().PutState(pendingAdminsKey, b)
+}
+
+// expiresAt 提名的失效时间，早期未记录失效时间的提名按提名时间加有效期计算
+func (n PendingAdmin) expiresAt() int64 {
+	if n.ExpiresAt > 0 {
+		return n.ExpiresAt
+	}
+	return n.ProposedAt + adminNominationTTL
+}
+
+// expired 判断提名在指定时间（Unix秒）是否已过期
+func (n PendingAdmin) expired(now int64) bool {
+	return now > n.expiresAt()
+}
+
+// pruneExpiredNominations 移除已过期的提名
+func pruneExpiredNominations(pending map[string]PendingAdmin, now int64) {
+	for account, nomination := range pending {
+		if nomination.expired(now) {
+			delete(pending, account)
+		}
+	}
+}
+
+// purgeAdminNominations 移除由指定管理员发起或提名指定管理员的提名，返回被移除提名的被提名账户
+// admin为管理员列表中的存储值，可能为旧格式（仅SKI），按MatchAccount匹配
+func purgeAdminNominations(pending map[string]PendingAdmin, admin string) []string {
+	purged := make([]string, 0)
+	for account, nomination := range pending {
+		if common.MatchAccount(admin, nomination.ProposedBy) || common.MatchAccount(admin, nomination.Account) {
+			delete(pending, account)
+			purged = append(purged, account)
+		}
+	}
+	sort.Strings(purged)
+	return purged
+}
+
+// findAdmin 按MatchAccount在管理员列表中查找账户，返回列表中的存储值（兼容未迁移的旧格式管理员）
+func findAdmin(admins []string, account string) (string, bool) {
+	for _, admin := range admins {
+		if common.MatchAccount(admin, account) {
+			return admin, true
+		}
+	}
+	return "", false
+}
+
+// containsString 判断字符串切片中是否包含指定元素
+func containsString(list []string, target string) bool {
+	for _, item := range list {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
+
+// removeString 返回移除指定元素后的新切片
+func removeString(list []string, target string) []string {
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		if item != target {
+			result = append(result, item)
+		}
+	}
+	return result
+}
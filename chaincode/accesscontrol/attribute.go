@@ -0,0 +1,260 @@
+package accesscontrol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// AttributePolicy 证书属性策略
+// 调用者证书中包含 AttrName=AttrValue 的Fabric CA属性时，自动获得策略中的函数权限和角色权限
+// 例如：did.admin=true -> RegisterDid、UpdateDidDocument
+// 证书属性由各组织自己的CA签发，只有MspId所属组织的证书属性可以匹配策略
+type AttributePolicy struct {
+	Name      string   `json:"name"`                           // 策略名称，唯一标识
+	MspId     string   `json:"mspId"`                          // 证书所属组织MSP ID，如 Org1MSP，必填
+	AttrName  string   `json:"attrName"`                       // 证书属性名，如 role、did.admin
+	AttrValue string   `json:"attrValue"`                      // 证书属性值，如 issuer、true
+	FuncNames []string `json:"funcNames" metadata:",optional"` // 授予的函数名列表
+	RoleNames []string `json:"roleNames" metadata:",optional"` // 授予的角色列表
+}
+
+const (
+	// attrPolicyPrefix 证书属性策略在链上的存储键前缀
+	// 格式：permission:attrPolicy:{policyName}
+	attrPolicyPrefix = "permission:attrPolicy:"
+)
+
+// ================== 证书属性策略相关 ==================
+
+// SetAttributePolicy 创建或更新证书属性策略
+// 权限要求：只有管理员可以调用此方法
+func (c *PermissionChaincode) SetAttributePolicy(ctx contractapi.TransactionContextInterface, policy AttributePolicy) error {
+	log.Printf("开始设置证书属性策略 - 策略: %s, 组织: %s, 属性: %s=%s", policy.Name, policy.MspId, policy.AttrName, policy.AttrValue)
+	cfg, err := c.checkAdminOperation(ctx, "SetAttributePolicy")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(policy.Name) == "" || strings.TrimSpace(policy.AttrName) == "" || strings.TrimSpace(policy.AttrValue) == "" {
+		log.Printf("参数校验失败 - 策略名称、属性名或属性值为空")
+		return errors.New("name, attrName and attrValue cannot be empty")
+	}
+	policy.MspId = strings.TrimSpace(policy.MspId)
+	if policy.MspId == "" || strings.Contains(policy.MspId, ":") {
+		log.Printf("参数校验失败 - 组织MSP ID无效: %s", policy.MspId)
+		return errors.New("invalid mspId")
+	}
+	if len(policy.FuncNames) == 0 && len(policy.RoleNames) == 0 {
+		log.Printf("参数校验失败 - 策略未授予任何函数或角色")
+		return errors.New("funcNames and roleNames cannot both be empty")
+	}
+	if len(policy.FuncNames) > 0 {
+		if policy.FuncNames, err = normalizeFuncNames(policy.FuncNames); err != nil {
+			log.Printf("参数校验失败 - %v", err)
+			return err
+		}
+	}
+	for _, roleName := range policy.RoleNames {
+		role, err := c.getRole(ctx, roleName)
+		if err != nil {
+			return err
+		}
+		if role == nil {
+			log.Printf("参数校验失败 - 角色不存在: %s", roleName)
+			return fmt.Errorf("role %s not found", roleName)
+		}
+	}
+
+	old, err := c.getAttributePolicy(ctx, policy.Name)
+	if err != nil {
+		return err
+	}
+	b, _ := json.Marshal(policy)
+	if err := ctx.GetStub().PutState(attrPolicyPrefix+policy.Name, b); err != nil {
+		log.Printf("证书属性策略存储失败: %v", err)
+		return err
+	}
+	log.Printf("证书属性策略存储成功 - 策略: %s, 函数: %v, 角色: %v", policy.Name, policy.FuncNames, policy.RoleNames)
+	if err := recordAudit(ctx, "SetAttributePolicy", policy.Name, old, policy); err != nil {
+		return err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode": cfg.ServiceCode,
+		"projectCode": cfg.ProjectCode,
+		"policy":      policy,
+		"oldPolicy":   old,
+		"sender":      common.GetCaller(ctx)})
+	return common.EmitEvent(ctx, "AttributePolicySet", payload)
+}
+
+// DeleteAttributePolicy 删除证书属性策略
+// 权限要求：只有管理员可以调用此方法
+func (c *PermissionChaincode) DeleteAttributePolicy(ctx contractapi.TransactionContextInterface, name string) error {
+	log.Printf("开始删除证书属性策略 - 策略: %s", name)
+	cfg, err := c.checkAdminOperation(ctx, "DeleteAttributePolicy")
+	if err != nil {
+		return err
+	}
+	policy, err := c.getAttributePolicy(ctx, name)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		log.Printf("证书属性策略删除失败 - 策略不存在: %s", name)
+		return errors.New("attribute policy not found")
+	}
+	if err := ctx.GetStub().DelState(attrPolicyPrefix + name); err != nil {
+		log.Printf("证书属性策略删除失败: %v", err)
+		return err
+	}
+	log.Printf("证书属性策略删除成功 - 策略: %s", name)
+	if err := recordAudit(ctx, "DeleteAttributePolicy", name, policy, nil); err != nil {
+		return err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode": cfg.ServiceCode,
+		"projectCode": cfg.ProjectCode,
+		"policy":      policy,
+		"sender":      common.GetCaller(ctx)})
+	return common.EmitEvent(ctx, "AttributePolicyDeleted", payload)
+}
+
+// GetAttributePolicy 查询证书属性策略
+func (c *PermissionChaincode) GetAttributePolicy(ctx contractapi.TransactionContextInterface, name string) (*AttributePolicy, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.New("name cannot be empty")
+	}
+	if err := c.checkNotPaused(ctx); err != nil {
+		return nil, err
+	}
+	policy, err := c.getAttributePolicy(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, errors.New("attribute policy not found")
+	}
+	return policy, nil
+}
+
+// ListAttributePolicies 查询所有证书属性策略
+func (c *PermissionChaincode) ListAttributePolicies(ctx contractapi.TransactionContextInterface) ([]*AttributePolicy, error) {
+	if err := c.checkNotPaused(ctx); err != nil {
+		return nil, err
+	}
+	return c.listAttributePolicies(ctx)
+}
+
+// ================== 内部方法 ==================
+
+// getAttributePolicy 内部方法：获取证书属性策略，不存在时返回nil
+func (c *PermissionChaincode) getAttributePolicy(ctx contractapi.TransactionContextInterface, name string) (*AttributePolicy, error) {
+	b, err := ctx.GetStub().GetState(attrPolicyPrefix + name)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var policy AttributePolicy
+	if err := json.Unmarshal(b, &policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// listAttributePolicies 内部方法：获取所有证书属性策略
+func (c *PermissionChaincode) listAttributePolicies(ctx contractapi.TransactionContextInterface) ([]*AttributePolicy, error) {
+	iterator, err := ctx.GetStub().GetStateByRange(attrPolicyPrefix, attrPolicyPrefix+"~")
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	policies := make([]*AttributePolicy, 0)
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var policy AttributePolicy
+		if err := json.Unmarshal(kv.Value, &policy); err != nil {
+			return nil, err
+		}
+		policies = append(policies, &policy)
+	}
+	return policies, nil
+}
+
+// addAttributeSelectors 内部方法：根据调用者证书属性匹配策略，将授予的函数权限合并到selectors
+// 证书属性只能从当前交易的提交者证书中读取，因此仅对调用者本人生效；只匹配调用者所属组织的策略，
+// 未指定MspId的早期策略不再匹配任何账户
+func (c *PermissionChaincode) addAttributeSelectors(ctx contractapi.TransactionContextInterface, selectors map[string]bool) error {
+	policies, err := c.listAttributePolicies(ctx)
+	if err != nil {
+		return err
+	}
+	mspID := common.GetCallerMSPID(ctx)
+	for _, policy := range policies {
+		if policy.MspId == "" || policy.MspId != mspID {
+			continue
+		}
+		value, found, err := common.GetCallerAttribute(ctx, policy.AttrName)
+		if err != nil {
+			return err
+		}
+		if !found || value != policy.AttrValue {
+			continue
+		}
+		log.Printf("证书属性策略匹配 - 策略: %s, 属性: %s=%s", policy.Name, policy.AttrName, policy.AttrValue)
+		for _, funcName := range policy.FuncNames {
+			selectors[funcName] = true
+		}
+		for _, roleName := range policy.RoleNames {
+			role, err := c.getRole(ctx, roleName)
+			if err != nil {
+				return err
+			}
+			if role == nil {
+				continue
+			}
+			for _, funcName := range role.FuncNames {
+				selectors[funcName] = true
+			}
+		}
+	}
+	return nil
+}
+
+// removeRoleFromAttributePolicies 内部方法：从所有证书属性策略中移除指定角色，策略不再授予任何权限时删除该策略
+func (c *PermissionChaincode) removeRoleFromAttributePolicies(ctx contractapi.TransactionContextInterface, roleName string) error {
+	policies, err := c.listAttributePolicies(ctx)
+	if err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		if !containsString(policy.RoleNames, roleName) {
+			continue
+		}
+		policy.RoleNames = removeString(policy.RoleNames, roleName)
+		key := attrPolicyPrefix + policy.Name
+		if len(policy.FuncNames) == 0 && len(policy.RoleNames) == 0 {
+			err = ctx.GetStub().DelState(key)
+		} else {
+			b, _ := json.Marshal(policy)
+			err = ctx.GetStub().PutState(key, b)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
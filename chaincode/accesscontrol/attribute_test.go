@@ -0,0 +1,58 @@
+package accesscontrol_test
+
+import (
+	"strings"
+	"testing"
+
+	"sbp-did-chaincode/accesscontrol"
+)
+
+func TestAttributePolicyMspId(t *testing.T) {
+	p := newPermissionTest(t)
+	admin := p.newAccount()
+	attrs := map[string]string{"role": "issuer"}
+	org1 := p.newAccountWithAttributes("Org1MSP", attrs)
+	org2 := p.newAccountWithAttributes("Org2MSP", attrs)
+	p.must(p.cc.InitProject(p.as(admin), "sbp", false, false, false, true, "svc", "proj", false))
+	p.must(p.cc.SetAttributePolicy(p.as(admin), accesscontrol.AttributePolicy{
+		Name: "org1-issuer", MspId: "Org1MSP", AttrName: "role", AttrValue: "issuer", FuncNames: []string{"RegisterIssuer"},
+	}))
+	p.must(p.cc.SetAttributePolicy(p.as(admin), accesscontrol.AttributePolicy{
+		Name: "org2-issuer", MspId: "Org2MSP", AttrName: "role", AttrValue: "issuer", FuncNames: []string{"StoreVCHash"},
+	}))
+
+	tests := []struct {
+		name     string
+		caller   testAccount
+		funcName string
+		want     bool
+	}{
+		{"matching msp", org1, "RegisterIssuer", true},
+		{"other msp with the same attribute", org2, "RegisterIssuer", false},
+		{"org2 policy does not match org1", org1, "StoreVCHash", false},
+		{"org2 policy matches org2", org2, "StoreVCHash", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.cc.HasSelectorPermission(p.as(tt.caller), tt.caller.account, tt.funcName)
+			p.must(err)
+			if got != tt.want {
+				t.Fatalf("got permission %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetAttributePolicyRequiresMspId(t *testing.T) {
+	p := newPermissionTest(t)
+	admin := p.newAccount()
+	p.must(p.cc.InitProject(p.as(admin), "sbp", false, false, false, true, "svc", "proj", false))
+	for _, mspID := range []string{"", "  ", "Org1MSP:abcd"} {
+		err := p.cc.SetAttributePolicy(p.as(admin), accesscontrol.AttributePolicy{
+			Name: "issuer", MspId: mspID, AttrName: "role", AttrValue: "issuer", FuncNames: []string{"RegisterIssuer"},
+		})
+		if err == nil || !strings.Contains(err.Error(), "invalid mspId") {
+			t.Fatalf("mspId %q: got err %v, want invalid mspId", mspID, err)
+		}
+	}
+}
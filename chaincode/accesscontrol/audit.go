@@ -0,0 +1,143 @@
+package accesscontrol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// AuditRecord 配置及权限变更审计记录，只追加不修改
+type AuditRecord struct {
+	TxId      string `json:"txId"`      // 变更所在交易ID
+	Timestamp int64  `json:"timestamp"` // 变更时间（Unix秒，取交易时间戳）
+	Actor     string `json:"actor"`     // 执行变更的账户标识
+	Operation string `json:"operation"` // 变更操作，与对应的合约方法名一致，如 ChangeMethod、BatchOperateSelectorPermissions
+	Target    string `json:"target"`    // 变更对象，如配置项名称、账户标识、角色名称
+	OldValue  string `json:"oldValue"`  // 变更前的值（JSON），新增时为null
+	NewValue  string `json:"newValue"`  // 变更后的值（JSON），删除时为null
+}
+
+// AuditRecordPage 审计记录分页查询结果
+type AuditRecordPage struct {
+	Records      []*AuditRecord `json:"records"`      // 当前页中符合过滤条件的审计记录
+	Bookmark     string         `json:"bookmark"`     // 下一页书签，为空表示没有更多数据
+	FetchedCount int32          `json:"fetchedCount"` // 当前页扫描的记录数（过滤前）
+}
+
+const (
+	// auditRecordPrefix 审计记录在链上的存储键前缀
+	// 格式：permission:audit:{timestamp(19位补零)}:{txId}:{seq(4位补零)}:{operation}:{target}，按时间顺序排列，
+	// seq为记录在交易内的序号，同一交易（如批量授权）多次变更同一对象时各自保留一条记录
+	auditRecordPrefix = "permission:audit:"
+)
+
+// ================== 审计记录相关 ==================
+
+// QueryAuditRecords 分页查询配置及权限变更审计记录
+// 参数说明：
+// - actor: 按执行者过滤，为空表示不过滤
+// - operation: 按变更操作过滤，为空表示不过滤
+// - fromTime: 起始时间（Unix秒，包含），为0表示不限制
+// - toTime: 截止时间（Unix秒，包含），为0表示不限制
+// - pageSize: 单页扫描记录数，取值范围 1~200
+// - bookmark: 上一页返回的书签，首页传空字符串
+//
+// 权限要求：私有项目中需要管理员或被授权QueryAuditRecords
+// 注意：过滤在分页扫描之后进行，单页返回的记录数可能少于pageSize，需根据Bookmark继续查询
+func (c *PermissionChaincode) QueryAuditRecords(ctx contractapi.TransactionContextInterface, actor, operation string, fromTime, toTime int64, pageSize int32, bookmark string) (*AuditRecordPage, error) {
+	if fromTime < 0 || toTime < 0 {
+		return nil, errors.New("fromTime and toTime cannot be negative")
+	}
+	if toTime != 0 && toTime < fromTime {
+		return nil, errors.New("toTime must not be earlier than fromTime")
+	}
+	if err := checkPageSize(pageSize); err != nil {
+		return nil, err
+	}
+	hasPermission, err := c.CheckQueryFuncSelectorPermission(ctx, common.GetCaller(ctx), "QueryAuditRecords")
+	if err != nil {
+		return nil, err
+	}
+	if !hasPermission {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: QueryAuditRecords", common.GetCaller(ctx))
+		return nil, errors.New("no permission to query audit records")
+	}
+
+	startKey := auditTimeKey(fromTime)
+	endKey := auditRecordPrefix + "~"
+	if toTime != 0 {
+		endKey = auditTimeKey(toTime + 1)
+	}
+	iterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination(startKey, endKey, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	page := &AuditRecordPage{Records: make([]*AuditRecord, 0)}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var record AuditRecord
+		if err := json.Unmarshal(kv.Value, &record); err != nil {
+			return nil, err
+		}
+		if actor != "" && record.Actor != actor {
+			continue
+		}
+		if operation != "" && record.Operation != operation {
+			continue
+		}
+		page.Records = append(page.Records, &record)
+	}
+	page.Bookmark = metadata.GetBookmark()
+	page.FetchedCount = metadata.GetFetchedRecordsCount()
+	log.Printf("分页查询审计记录 - 执行者: %s, 操作: %s, 时间范围: [%d, %d], 返回记录数量: %d", actor, operation, fromTime, toTime, len(page.Records))
+	return page, nil
+}
+
+// ================== 内部方法 ==================
+
+// recordAudit 内部方法：追加一条审计记录，执行者为当前调用者，时间取交易时间戳
+func recordAudit(ctx contractapi.TransactionContextInterface, operation, target string, oldValue, newValue interface{}) error {
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	oldJSON, _ := json.Marshal(oldValue)
+	newJSON, _ := json.Marshal(newValue)
+	record := AuditRecord{
+		TxId:      ctx.GetStub().GetTxID(),
+		Timestamp: txTime.Unix(),
+		Actor:     common.GetCaller(ctx),
+		Operation: operation,
+		Target:    target,
+		OldValue:  string(oldJSON),
+		NewValue:  string(newJSON),
+	}
+	// 交易上下文不支持计数时（非本包的上下文），序号固定为0
+	seq := 0
+	if sequencer, ok := ctx.(interface{ nextAuditSeq() int }); ok {
+		seq = sequencer.nextAuditSeq()
+	}
+	key := fmt.Sprintf("%s:%s:%04d:%s:%s", auditTimeKey(record.Timestamp), record.TxId, seq, operation, target)
+	b, _ := json.Marshal(record)
+	if err := ctx.GetStub().PutState(key, b); err != nil {
+		log.Printf("审计记录存储失败 - 操作: %s, 对象: %s, 错误: %v", operation, target, err)
+		return err
+	}
+	log.Printf("审计记录存储成功 - 操作: %s, 对象: %s", operation, target)
+	return nil
+}
+
+// auditTimeKey 返回指定时间的审计记录存储键前缀，时间补零保证按字典序即时间顺序排列
+func auditTimeKey(timestamp int64) string {
+	return fmt.Sprintf("%s%019d", auditRecordPrefix, timestamp)
+}
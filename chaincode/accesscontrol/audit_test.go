@@ -0,0 +1,33 @@
+package accesscontrol_test
+
+import (
+	"testing"
+	"time"
+
+	"sbp-did-chaincode/accesscontrol"
+)
+
+func TestAuditRecordsSameTargetInBatch(t *testing.T) {
+	p := newPermissionTest(t)
+	admin, user := p.newAccount(), p.newAccount()
+	p.must(p.cc.InitProject(p.as(admin), "sbp", false, false, false, false, "svc", "proj", false))
+
+	// 不同有效期拆分为同一账户的两个条目，每个条目各保留一条审计记录
+	p.must(p.cc.BatchOperateSelectorPermissions(p.as(admin), []accesscontrol.AccountSelector{
+		{Account: user.account, FuncNames: []string{"RegisterDid"}, ValidUntil: p.now.Add(24 * time.Hour).Unix()},
+		{Account: user.account, FuncNames: []string{"DeactivateDid"}},
+	}))
+	page, err := p.cc.QueryAuditRecords(p.as(admin), "", "BatchOperateSelectorPermissions", 0, 0, 10, "")
+	p.must(err)
+	if len(page.Records) != 2 {
+		t.Fatalf("got %d audit records, want 2", len(page.Records))
+	}
+	first, second := page.Records[0], page.Records[1]
+	if first.TxId != second.TxId || first.Target != user.account || second.Target != user.account {
+		t.Fatalf("got records %+v, %+v, want both in one tx for %s", first, second, user.account)
+	}
+	// 按交易内的执行顺序排列，第二条记录的变更前值为第一条记录的变更后值
+	if second.OldValue != first.NewValue {
+		t.Fatalf("got records %+v, %+v, want them chained in execution order", first, second)
+	}
+}
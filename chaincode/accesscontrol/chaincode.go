@@ -23,7 +23,6 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"sort"
 	"strings"
 
 	"sbp-did-chaincode/common"
@@ -36,10 +35,22 @@ import (
 // 使用common.ProjectConfig类型，避免循环导入
 
 // AccountSelector 账户权限结构体
-// 用于批量操作账户权限，包含账户标识和对应的函数权限列表
+// 用于批量操作账户权限，包含账户标识、对应的函数权限列表以及操作模式
 type AccountSelector struct {
-	Account   string   // 链账户SKI（Subject Key Identifier），用于唯一标识账户
-	FuncNames []string // 函数名列表，该账户被授权的函数名称集合
+	Account   string   // 链账户标识，格式：{mspId}:{ski}，ski为证书Subject Key Identifier
+	FuncNames []string // 函数名列表，该账户被授权（或撤销）的函数名称集合
+	IsRevoke  bool     `metadata:",optional"` // 操作模式：false为授权，true为撤销FuncNames中列出的函数权限
+	// 授权有效期（Unix时间戳，秒），仅授权模式生效，作用于FuncNames中的所有函数；不同有效期请拆分为多个条目
+	ValidFrom  int64 `metadata:",optional"` // 生效时间，为0表示立即生效
+	ValidUntil int64 `metadata:",optional"` // 失效时间，为0表示永久有效
+}
+
+// SelectorOperation 单个函数选择器的授权/撤销明细，用于事件通知
+type SelectorOperation struct {
+	FuncName   string `json:"funcName"`             // 函数名
+	Action     string `json:"action"`               // 操作类型：grant/revoke
+	ValidFrom  int64  `json:"validFrom,omitempty"`  // 授权生效时间
+	ValidUntil int64  `json:"validUntil,omitempty"` // 授权失效时间
 }
 
 // PermissionChaincode 权限控制链码结构体
@@ -54,16 +65,21 @@ const (
 	// 格式：permission:projectConfig
 	projectConfigKey = "permission:projectConfig"
 
-	// selectorPermPrefix 账户权限在链上的存储键前缀
-	// 格式：permission:selectorPerm:{account}
+	// selectorPermPrefix 旧版账户权限存储键前缀，现已改为复合键存储（见selectorPermObjectType）
+	// 格式：permission:selectorPerm:{account}，可通过MigrateSelectorPermissionKeys迁移
 	selectorPermPrefix = "permission:selectorPerm:"
+
+	// selectorActionGrant 函数选择器授权操作
+	selectorActionGrant = "grant"
+	// selectorActionRevoke 函数选择器撤销操作
+	selectorActionRevoke = "revoke"
 )
 
 // ================== 项目配置相关 ==================
 
 // InitProject 初始化项目配置
-// 该方法用于首次部署合约时初始化SBP-DID项目的基本配置
-// 只能调用一次，后续修改需要通过其他方法进行
+// 该方法用于首次部署合约时初始化SBP-DID项目（默认项目）的基本配置，其他项目通过CreateProject创建
+// 只能调用一次，后续修改需要通过其他方法进行；确需重新初始化时由现有管理员显式传入reinit=true
 //
 // 参数说明：
 // - ctx: 交易上下文，包含链码存根和调用者信息
@@ -74,24 +90,33 @@ const (
 // - enableWritePermission: 是否启用写权限控制
 // - serviceCode: 服务编码，标识服务实例
 // - projectCode: 项目编码，标识具体项目
+// - reinit: 是否重新初始化已存在的项目配置；重新初始化保留现有管理员、审批阈值及停用状态，附加method集合被清空
 //
 // 返回值：
 // - error: 成功返回nil，失败返回错误信息
 //
-// 权限要求：只有管理员可以调用此方法
+// 权限要求：首次初始化时调用者成为初始管理员；重新初始化只有管理员可以调用，开启多管理员审批后不允许重新初始化
 func (c *PermissionChaincode) InitProject(
 	ctx contractapi.TransactionContextInterface,
 	method string,
 	isPrivate, enableIssuerVerification, enableVcVerification, enableWritePermission bool,
 	serviceCode, projectCode string,
+	reinit bool,
 ) error {
 	// 参数校验：关键参数不能为空
 	if strings.TrimSpace(method) == "" || strings.TrimSpace(serviceCode) == "" || strings.TrimSpace(projectCode) == "" {
 		return errors.New("method, serviceCode, projectCode cannot be empty")
 	}
+	if err := common.ValidateDIDMethodName(method); err != nil {
+		return err
+	}
+	// 通过CreateProject创建的项目在创建时已写入配置
+	if projectCode := common.GetProjectCode(ctx); projectCode != "" {
+		return fmt.Errorf("project %s is created by CreateProject, InitProject only applies to the default project", projectCode)
+	}
 	log.Println("链码InitProject")
 	// 获取调用者身份作为初始管理员
-	log.Printf("初始化项目配置 - method: %s, isPrivate: %t, enableIssuerVerification: %t, enableVcVerification: %t, enableWritePermission: %t, serviceCode: %s, projectCode: %s", method, isPrivate, enableIssuerVerification, enableVcVerification, enableWritePermission, serviceCode, projectCode)
+	log.Printf("初始化项目配置 - method: %s, isPrivate: %t, enableIssuerVerification: %t, enableVcVerification: %t, enableWritePermission: %t, serviceCode: %s, projectCode: %s, reinit: %t", method, isPrivate, enableIssuerVerification, enableVcVerification, enableWritePermission, serviceCode, projectCode, reinit)
 	caller := common.GetCaller(ctx)
 	log.Printf("项目初始化 - 调用者: %s", caller)
 
@@ -108,15 +133,42 @@ func (c *PermissionChaincode) InitProject(
 		Admins:                       []string{caller}, // 调用者成为初始管理员
 	}
 
+	// 防止重复初始化覆盖现有管理员
+	oldCfg, err := c.getProjectConfig(ctx)
+	if err == nil {
+		if !reinit {
+			log.Printf("项目初始化失败 - 项目配置已存在")
+			return errors.New("project is already initialized")
+		}
+		if !common.IsAdmin(ctx, oldCfg.Admins) {
+			log.Printf("权限校验失败 - 调用者: %s, 操作: InitProject(reinit)", caller)
+			return errors.New("only admin can reinitialize project")
+		}
+		if err := checkNotFrozen(ctx, caller); err != nil {
+			return err
+		}
+		if err := checkSingleAdminOperation(oldCfg, "InitProject"); err != nil {
+			return err
+		}
+		log.Printf("权限校验通过 - 调用者: %s, 操作: InitProject(reinit)", caller)
+		cfg.Admins = oldCfg.Admins
+		cfg.ApprovalThreshold = oldCfg.ApprovalThreshold
+		cfg.Paused = oldCfg.Paused
+		cfg.PausedScopes = oldCfg.PausedScopes
+		cfg.Version = oldCfg.Version
+	} else {
+		oldCfg = nil
+	}
+
 	// 序列化配置并存储到链上
-	b, _ := json.Marshal(cfg)
-	if err := ctx.GetStub().PutState(projectConfigKey, b); err != nil {
+	if err := putProjectConfig(ctx, &cfg); err != nil {
 		log.Printf("项目配置存储失败: %v", err)
 		return err
 	}
-	log.Printf("项目配置存储成功 - 配置键: %s", projectConfigKey)
-	//  设置全局权限检查器
-	common.SetGlobalPermissionChecker(c)
+	log.Printf("项目配置存储成功 - 配置键: %s, 版本: %d", projectConfigKey, cfg.Version)
+	if err := recordAudit(ctx, "InitProject", projectCode, oldCfg, cfg); err != nil {
+		return err
+	}
 	// 触发项目初始化事件
 	eventPayload, _ := json.Marshal(&common.ProjectConfig{
 		ServiceCode:                  serviceCode,
@@ -126,9 +178,13 @@ func (c *PermissionChaincode) InitProject(
 		EnableVCTemplateVerification: enableVcVerification,
 		EnableIssuerVerification:     enableIssuerVerification,
 		EnableWritePermission:        enableWritePermission,
-		Paused:                       false,
-		Admins:                       []string{caller},
+		Paused:                       cfg.Paused,
+		Admins:                       cfg.Admins,
+		Version:                      cfg.Version,
 	})
+	if reinit && oldCfg != nil {
+		return common.EmitEvent(ctx, "ProjectReinitialized", eventPayload)
+	}
 	return common.EmitEvent(ctx, "ProjectInitialized", eventPayload)
 }
 
@@ -159,6 +215,9 @@ func (c *PermissionChaincode) ChangePrivateStatus(ctx contractapi.TransactionCon
 		log.Printf("权限校验失败 - 调用者: %s, 操作: ChangePrivateStatus", common.GetCaller(ctx))
 		return errors.New("only admin can change private status")
 	}
+	if err := checkNotFrozen(ctx, common.GetCaller(ctx)); err != nil {
+		return err
+	}
 	log.Printf("权限校验通过 - 调用者: %s, 操作: ChangePrivateStatus", common.GetCaller(ctx))
 
 	// 状态校验：避免重复设置相同状态
@@ -173,12 +232,14 @@ func (c *PermissionChaincode) ChangePrivateStatus(ctx contractapi.TransactionCon
 	log.Printf("项目配置更新 - 私有状态: %t", isPrivate)
 
 	// 序列化并存储到链上
-	b, _ := json.Marshal(cfg)
-	if err := ctx.GetStub().PutState(projectConfigKey, b); err != nil {
+	if err := putProjectConfig(ctx, cfg); err != nil {
 		log.Printf("项目配置更新存储失败: %v", err)
 		return err
 	}
 	log.Printf("项目配置更新存储成功")
+	if err := recordAudit(ctx, "ChangePrivateStatus", "isProjectPrivate", !isPrivate, isPrivate); err != nil {
+		return err
+	}
 
 	// 触发状态变更事件
 	payload, _ := json.Marshal(&common.ProjectConfig{
@@ -190,7 +251,8 @@ func (c *PermissionChaincode) ChangePrivateStatus(ctx contractapi.TransactionCon
 	return common.EmitEvent(ctx, "PrivateStatusChanged", payload)
 }
 
-// ChangeMethod 更改method名称
+// ChangeMethod 更改项目主method名称，新主method若在附加method集合中则从集合移除
+// 开启多管理员审批后需通过CreateConfigProposal发起
 func (c *PermissionChaincode) ChangeMethod(ctx contractapi.TransactionContextInterface, method string) error {
 	log.Printf("开始更改项目method - 新method: %s", method)
 	cfg, err := c.getProjectConfig(ctx)
@@ -202,29 +264,49 @@ func (c *PermissionChaincode) ChangeMethod(ctx contractapi.TransactionContextInt
 		log.Printf("权限校验失败 - 调用者: %s, 操作: ChangeMethod", common.GetCaller(ctx))
 		return errors.New("only admin can change method")
 	}
+	if err := checkNotFrozen(ctx, common.GetCaller(ctx)); err != nil {
+		return err
+	}
 	log.Printf("权限校验通过 - 调用者: %s, 操作: ChangeMethod", common.GetCaller(ctx))
+	if err := checkSingleAdminOperation(cfg, ProposalOpChangeMethod); err != nil {
+		return err
+	}
+
+	eventName, payload, err := c.applyMethod(ctx, cfg, method)
+	if err != nil {
+		return err
+	}
+	return common.EmitEvent(ctx, eventName, payload)
+}
 
+// applyMethod 内部方法：校验并更新项目method，返回待发送的事件
+func (c *PermissionChaincode) applyMethod(ctx contractapi.TransactionContextInterface, cfg *common.ProjectConfig, method string) (string, []byte, error) {
 	if cfg.Paused {
 		log.Printf("项目状态校验失败 - 项目已停用")
-		return errors.New("project is paused")
+		return "", nil, errors.New("project is paused")
 	}
-	if strings.TrimSpace(method) == "" {
-		log.Printf("参数校验失败 - method为空")
-		return errors.New("method cannot be empty")
+	if err := common.ValidateDIDMethodName(method); err != nil {
+		log.Printf("参数校验失败 - %v", err)
+		return "", nil, err
 	}
 	if cfg.Method == method {
 		log.Printf("状态校验失败 - method已相同: %s", method)
-		return errors.New("method is already the same")
+		return "", nil, errors.New("method is already the same")
 	}
 
+	oldMethod := cfg.Method
 	cfg.Method = method
+	// 新主method始终允许使用，从附加method集合中移除
+	cfg.Methods = removeMethod(cfg.Methods, cfg.Method)
 	log.Printf("项目配置更新 - 新method: %s", method)
-	b, _ := json.Marshal(cfg)
-	if err := ctx.GetStub().PutState(projectConfigKey, b); err != nil {
+	if err := putProjectConfig(ctx, cfg); err != nil {
 		log.Printf("项目配置更新存储失败: %v", err)
-		return err
+		return "", nil, err
 	}
 	log.Printf("项目配置更新存储成功")
+	if err := recordAudit(ctx, ProposalOpChangeMethod, "method", oldMethod, method); err != nil {
+		return "", nil, err
+	}
 
 	payload, _ := json.Marshal(&common.ProjectConfig{
 		ServiceCode: cfg.ServiceCode,
@@ -232,7 +314,7 @@ func (c *PermissionChaincode) ChangeMethod(ctx contractapi.TransactionContextInt
 		Method:      method,
 	})
 	log.Printf("触发method变更事件 - 新method: %s", method)
-	return common.EmitEvent(ctx, "MethodChanged", payload)
+	return "MethodChanged", payload, nil
 }
 
 // ChangeEnableVCTemplateVerification 更改VC模板验证开关
@@ -247,6 +329,9 @@ func (c *PermissionChaincode) ChangeEnableVCTemplateVerification(ctx contractapi
 		log.Printf("权限校验失败 - 调用者: %s, 操作: ChangeEnableVCTemplateVerification", common.GetCaller(ctx))
 		return errors.New("only admin can change VC template verification")
 	}
+	if err := checkNotFrozen(ctx, common.GetCaller(ctx)); err != nil {
+		return err
+	}
 	log.Printf("权限校验通过 - 调用者: %s, 操作: ChangeEnableVCTemplateVerification", common.GetCaller(ctx))
 
 	if cfg.Paused {
@@ -260,12 +345,14 @@ func (c *PermissionChaincode) ChangeEnableVCTemplateVerification(ctx contractapi
 
 	cfg.EnableVCTemplateVerification = enableVCTemplateVerification
 	log.Printf("项目配置更新 - VC模板验证开关: %t", enableVCTemplateVerification)
-	b, _ := json.Marshal(cfg)
-	if err := ctx.GetStub().PutState(projectConfigKey, b); err != nil {
+	if err := putProjectConfig(ctx, cfg); err != nil {
 		log.Printf("项目配置更新存储失败: %v", err)
 		return err
 	}
 	log.Printf("项目配置更新存储成功")
+	if err := recordAudit(ctx, "ChangeEnableVCTemplateVerification", "enableVCTemplateVerification", !enableVCTemplateVerification, enableVCTemplateVerification); err != nil {
+		return err
+	}
 
 	payload, _ := json.Marshal(&common.ProjectConfig{
 		ServiceCode:                  cfg.ServiceCode,
@@ -288,6 +375,9 @@ func (c *PermissionChaincode) ChangeEnableIssuerVerification(ctx contractapi.Tra
 		log.Printf("权限校验失败 - 调用者: %s, 操作: ChangeEnableIssuerVerification", common.GetCaller(ctx))
 		return errors.New("only admin can change issuer verification")
 	}
+	if err := checkNotFrozen(ctx, common.GetCaller(ctx)); err != nil {
+		return err
+	}
 	log.Printf("权限校验通过 - 调用者: %s, 操作: ChangeEnableIssuerVerification", common.GetCaller(ctx))
 
 	if cfg.Paused {
@@ -301,12 +391,14 @@ func (c *PermissionChaincode) ChangeEnableIssuerVerification(ctx contractapi.Tra
 
 	cfg.EnableIssuerVerification = enableIssuerVerification
 	log.Printf("项目配置更新 - Issuer验证开关: %t", enableIssuerVerification)
-	b, _ := json.Marshal(cfg)
-	if err := ctx.GetStub().PutState(projectConfigKey, b); err != nil {
+	if err := putProjectConfig(ctx, cfg); err != nil {
 		log.Printf("项目配置更新存储失败: %v", err)
 		return err
 	}
 	log.Printf("项目配置更新存储成功")
+	if err := recordAudit(ctx, "ChangeEnableIssuerVerification", "enableIssuerVerification", !enableIssuerVerification, enableIssuerVerification); err != nil {
+		return err
+	}
 
 	payload, _ := json.Marshal(&common.ProjectConfig{
 		ServiceCode:              cfg.ServiceCode,
@@ -318,6 +410,7 @@ func (c *PermissionChaincode) ChangeEnableIssuerVerification(ctx contractapi.Tra
 }
 
 // ChangeEnableWritePermission 更改写权限状态
+// 开启多管理员审批后需通过CreateConfigProposal发起
 func (c *PermissionChaincode) ChangeEnableWritePermission(ctx contractapi.TransactionContextInterface, enableWritePermission bool) error {
 	log.Printf("开始更改写权限状态 - 新状态: %t", enableWritePermission)
 	cfg, err := c.getProjectConfig(ctx)
@@ -329,25 +422,42 @@ func (c *PermissionChaincode) ChangeEnableWritePermission(ctx contractapi.Transa
 		log.Printf("权限校验失败 - 调用者: %s, 操作: ChangeEnableWritePermission", common.GetCaller(ctx))
 		return errors.New("only admin can change write permission")
 	}
+	if err := checkNotFrozen(ctx, common.GetCaller(ctx)); err != nil {
+		return err
+	}
 	log.Printf("权限校验通过 - 调用者: %s, 操作: ChangeEnableWritePermission", common.GetCaller(ctx))
+	if err := checkSingleAdminOperation(cfg, ProposalOpChangeEnableWritePermission); err != nil {
+		return err
+	}
 
+	eventName, payload, err := c.applyEnableWritePermission(ctx, cfg, enableWritePermission)
+	if err != nil {
+		return err
+	}
+	return common.EmitEvent(ctx, eventName, payload)
+}
+
+// applyEnableWritePermission 内部方法：校验并更新写权限开关，返回待发送的事件
+func (c *PermissionChaincode) applyEnableWritePermission(ctx contractapi.TransactionContextInterface, cfg *common.ProjectConfig, enableWritePermission bool) (string, []byte, error) {
 	if cfg.Paused {
 		log.Printf("项目状态校验失败 - 项目已停用")
-		return errors.New("project is paused")
+		return "", nil, errors.New("project is paused")
 	}
 	if cfg.EnableWritePermission == enableWritePermission {
 		log.Printf("状态校验失败 - 写权限状态已相同: %t", enableWritePermission)
-		return errors.New("write permission status is already the same")
+		return "", nil, errors.New("write permission status is already the same")
 	}
 
 	cfg.EnableWritePermission = enableWritePermission
 	log.Printf("项目配置更新 - 写权限开关: %t", enableWritePermission)
-	b, _ := json.Marshal(cfg)
-	if err := ctx.GetStub().PutState(projectConfigKey, b); err != nil {
+	if err := putProjectConfig(ctx, cfg); err != nil {
 		log.Printf("项目配置更新存储失败: %v", err)
-		return err
+		return "", nil, err
 	}
 	log.Printf("项目配置更新存储成功")
+	if err := recordAudit(ctx, ProposalOpChangeEnableWritePermission, "enableWritePermission", !enableWritePermission, enableWritePermission); err != nil {
+		return "", nil, err
+	}
 
 	payload, _ := json.Marshal(&common.ProjectConfig{
 		ServiceCode:           cfg.ServiceCode,
@@ -355,126 +465,103 @@ func (c *PermissionChaincode) ChangeEnableWritePermission(ctx contractapi.Transa
 		EnableWritePermission: enableWritePermission,
 	})
 	log.Printf("触发写权限状态变更事件 - 新状态: %t", enableWritePermission)
-	return common.EmitEvent(ctx, "EnableWritePermissionChanged", payload)
+	return "EnableWritePermissionChanged", payload, nil
 }
 
 // Pause 项目停用
+// 开启多管理员审批后需通过CreateConfigProposal发起
 func (c *PermissionChaincode) Pause(ctx contractapi.TransactionContextInterface) error {
 	log.Printf("开始停用项目")
-	cfg, err := c.getProjectConfig(ctx)
-	if err != nil {
-		log.Printf("获取项目配置失败: %v", err)
-		return err
-	}
-	if !common.IsAdmin(ctx, cfg.Admins) {
-		log.Printf("权限校验失败 - 调用者: %s, 操作: Pause", common.GetCaller(ctx))
-		return errors.New("only admin can pause project")
-	}
-	log.Printf("权限校验通过 - 调用者: %s, 操作: Pause", common.GetCaller(ctx))
-
-	cfg.Paused = true
-	log.Printf("项目配置更新 - 项目状态: 已停用")
-	b, _ := json.Marshal(cfg)
-	if err := ctx.GetStub().PutState(projectConfigKey, b); err != nil {
-		log.Printf("项目配置更新存储失败: %v", err)
-		return err
-	}
-	log.Printf("项目配置更新存储成功")
-
-	// 触发项目停用事件
-	eventPayload, _ := json.Marshal(&common.ProjectConfig{
-		ServiceCode: cfg.ServiceCode,
-		ProjectCode: cfg.ProjectCode,
-		Paused:      true,
-		Admins:      []string{common.GetCaller(ctx)},
-	})
-	log.Printf("触发项目停用事件")
-	return common.EmitEvent(ctx, "Paused", eventPayload)
+	return c.operatePause(ctx, true)
 }
 
 // Unpause 项目启用
+// 开启多管理员审批后需通过CreateConfigProposal发起
 func (c *PermissionChaincode) Unpause(ctx contractapi.TransactionContextInterface) error {
 	log.Printf("开始启用项目")
+	return c.operatePause(ctx, false)
+}
+
+// operatePause 内部方法：校验管理员身份后停用/启用项目
+func (c *PermissionChaincode) operatePause(ctx contractapi.TransactionContextInterface, paused bool) error {
+	operation, op := "Unpause", ProposalOpUnpause
+	if paused {
+		operation, op = "Pause", ProposalOpPause
+	}
 	cfg, err := c.getProjectConfig(ctx)
 	if err != nil {
 		log.Printf("获取项目配置失败: %v", err)
 		return err
 	}
 	if !common.IsAdmin(ctx, cfg.Admins) {
-		log.Printf("权限校验失败 - 调用者: %s, 操作: Unpause", common.GetCaller(ctx))
+		log.Printf("权限校验失败 - 调用者: %s, 操作: %s", common.GetCaller(ctx), operation)
+		if paused {
+			return errors.New("only admin can pause project")
+		}
 		return errors.New("only admin can unpause project")
 	}
-	log.Printf("权限校验通过 - 调用者: %s, 操作: Unpause", common.GetCaller(ctx))
+	if err := checkNotFrozen(ctx, common.GetCaller(ctx)); err != nil {
+		return err
+	}
+	log.Printf("权限校验通过 - 调用者: %s, 操作: %s", common.GetCaller(ctx), operation)
+	if err := checkSingleAdminOperation(cfg, op); err != nil {
+		return err
+	}
 
-	cfg.Paused = false
-	log.Printf("项目配置更新 - 项目状态: 已启用")
-	b, _ := json.Marshal(cfg)
-	if err := ctx.GetStub().PutState(projectConfigKey, b); err != nil {
-		log.Printf("项目配置更新存储失败: %v", err)
+	eventName, payload, err := c.applyPause(ctx, cfg, paused)
+	if err != nil {
 		return err
 	}
+	return common.EmitEvent(ctx, eventName, payload)
+}
+
+// applyPause 内部方法：更新项目停用状态，返回待发送的事件
+func (c *PermissionChaincode) applyPause(ctx contractapi.TransactionContextInterface, cfg *common.ProjectConfig, paused bool) (string, []byte, error) {
+	operation := ProposalOpUnpause
+	if paused {
+		operation = ProposalOpPause
+	}
+	oldPaused := cfg.Paused
+	cfg.Paused = paused
+	log.Printf("项目配置更新 - 项目已停用: %t", paused)
+	if err := putProjectConfig(ctx, cfg); err != nil {
+		log.Printf("项目配置更新存储失败: %v", err)
+		return "", nil, err
+	}
 	log.Printf("项目配置更新存储成功")
+	if err := recordAudit(ctx, operation, "paused", oldPaused, paused); err != nil {
+		return "", nil, err
+	}
 
-	// 触发项目启用事件
+	// 触发项目停用/启用事件
 	eventPayload, _ := json.Marshal(&common.ProjectConfig{
 		ServiceCode: cfg.ServiceCode,
 		ProjectCode: cfg.ProjectCode,
-		Paused:      false,
+		Paused:      paused,
 		Admins:      []string{common.GetCaller(ctx)},
 	})
+	if paused {
+		log.Printf("触发项目停用事件")
+		return "Paused", eventPayload, nil
+	}
 	log.Printf("触发项目启用事件")
-	return common.EmitEvent(ctx, "Unpaused", eventPayload)
+	return "Unpaused", eventPayload, nil
 }
 
 // TransferAdminRole 转移超级管理员权限
+// 调用者提名newAdmin作为继任者，newAdmin使用自己的证书调用AcceptAdmin后成为管理员，同时移除调用者的管理员身份
+// 仅新增管理员请使用ProposeAdmin；开启多管理员审批后需通过CreateConfigProposal发起
 func (c *PermissionChaincode) TransferAdminRole(ctx contractapi.TransactionContextInterface, newAdmin string) error {
 	log.Printf("开始转移管理员权限 - 新管理员: %s", newAdmin)
-	cfg, err := c.getProjectConfig(ctx)
-	if err != nil {
-		log.Printf("获取项目配置失败: %v", err)
-		return err
-	}
-	if !common.IsAdmin(ctx, cfg.Admins) {
-		log.Printf("权限校验失败 - 调用者: %s, 操作: TransferAdminRole", common.GetCaller(ctx))
-		return errors.New("only admin can transfer admin role")
-	}
-	log.Printf("权限校验通过 - 调用者: %s, 操作: TransferAdminRole", common.GetCaller(ctx))
-
-	if strings.TrimSpace(newAdmin) == "" {
-		log.Printf("参数校验失败 - 新管理员为空")
-		return errors.New("new admin cannot be empty")
-	}
-
-	// 检查新管理员是否已在列表中
-	for _, admin := range cfg.Admins {
-		if admin == newAdmin {
-			log.Printf("状态校验失败 - 新管理员已存在: %s", newAdmin)
-			return errors.New("new admin is already in admin list")
-		}
-	}
-
-	cfg.Admins = append(cfg.Admins, newAdmin)
-	log.Printf("管理员列表更新 - 新增管理员: %s, 当前管理员数量: %d", newAdmin, len(cfg.Admins))
-	b, _ := json.Marshal(cfg)
-	if err := ctx.GetStub().PutState(projectConfigKey, b); err != nil {
-		log.Printf("项目配置更新存储失败: %v", err)
-		return err
-	}
-	log.Printf("项目配置更新存储成功")
-
-	// 触发管理员权限转移事件
-	eventPayload, _ := json.Marshal(map[string]interface{}{
-		"serviceCode": cfg.ServiceCode,
-		"projectCode": cfg.ProjectCode,
-		"oldAdmin":    cfg.Admins,
-		"newAdmin":    newAdmin})
-	log.Printf("触发管理员权限转移事件 - 新管理员: %s", newAdmin)
-	return common.EmitEvent(ctx, "AdminRoleTransfered", eventPayload)
+	return c.proposeAdmin(ctx, newAdmin, true)
 }
 
 // ================== 权限管理相关 ==================
 
 // BatchOperateSelectorPermissions 批量授权/撤销账户权限
+// - IsRevoke为false时，将FuncNames中的函数授权给账户
+// - IsRevoke为true时，仅撤销FuncNames中列出的函数权限，撤销未授权的函数会返回错误
+// - FuncNames为空时，删除该账户的所有权限
 func (c *PermissionChaincode) BatchOperateSelectorPermissions(ctx contractapi.TransactionContextInterface, accountSelectors []AccountSelector) error {
 	log.Printf("开始批量操作账户权限 - 账户数量: %d", len(accountSelectors))
 	cfg, err := c.getProjectConfig(ctx)
@@ -486,6 +573,9 @@ func (c *PermissionChaincode) BatchOperateSelectorPermissions(ctx contractapi.Tr
 		log.Printf("权限校验失败 - 调用者: %s, 操作: BatchOperateSelectorPermissions", common.GetCaller(ctx))
 		return errors.New("only admin can operate selector permissions")
 	}
+	if err := checkNotFrozen(ctx, common.GetCaller(ctx)); err != nil {
+		return err
+	}
 	log.Printf("权限校验通过 - 调用者: %s, 操作: BatchOperateSelectorPermissions", common.GetCaller(ctx))
 
 	if cfg.Paused {
@@ -493,29 +583,38 @@ func (c *PermissionChaincode) BatchOperateSelectorPermissions(ctx contractapi.Tr
 		return errors.New("project is paused")
 	}
 
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	now := txTime.Unix()
+
 	for i, perm := range accountSelectors {
-		log.Printf("处理第%d个账户权限 - 账户: %s, 函数数量: %d", i+1, perm.Account, len(perm.FuncNames))
-		if strings.TrimSpace(perm.Account) == "" {
-			log.Printf("参数校验失败 - 账户为空")
-			return errors.New("account cannot be empty")
+		log.Printf("处理第%d个账户权限 - 账户: %s, 函数数量: %d, 撤销模式: %t", i+1, perm.Account, len(perm.FuncNames), perm.IsRevoke)
+		if err := common.ValidateAccount(perm.Account); err != nil {
+			log.Printf("参数校验失败 - %v", err)
+			return err
 		}
 
-		key := selectorPermPrefix + perm.Account
-		var selectorMap map[string]bool
-		b, err := ctx.GetStub().GetState(key)
-		if err != nil || b == nil {
-			selectorMap = make(map[string]bool)
-			log.Printf("创建新账户权限映射 - 账户: %s", perm.Account)
-		} else {
-			_ = json.Unmarshal(b, &selectorMap)
-			log.Printf("获取现有账户权限映射 - 账户: %s, 现有权限数量: %d", perm.Account, len(selectorMap))
+		selectorMap, err := getSelectorGrants(ctx, perm.Account)
+		if err != nil {
+			log.Printf("获取账户权限映射失败 - 账户: %s, 错误: %v", perm.Account, err)
+			return err
 		}
+		log.Printf("获取现有账户权限映射 - 账户: %s, 现有权限数量: %d", perm.Account, len(selectorMap))
+		oldGrants := copyGrants(selectorMap)
 
 		// 如果FuncNames为空，则删除该账户的所有权限
 		if len(perm.FuncNames) == 0 {
 			// 删除该账户的所有权限
-			ctx.GetStub().DelState(key)
+			if err := putSelectorGrants(ctx, perm.Account, nil); err != nil {
+				log.Printf("删除账户权限失败 - 账户: %s, 错误: %v", perm.Account, err)
+				return err
+			}
 			log.Printf("删除账户所有权限 - 账户: %s", perm.Account)
+			if err := recordAudit(ctx, "BatchOperateSelectorPermissions", perm.Account, oldGrants, nil); err != nil {
+				return err
+			}
 			payload, _ := json.Marshal(map[string]interface{}{
 				"serviceCode": cfg.ServiceCode,
 				"projectCode": cfg.ProjectCode,
@@ -523,31 +622,74 @@ func (c *PermissionChaincode) BatchOperateSelectorPermissions(ctx contractapi.Tr
 				"action":      "deleteAll",
 				"isRevoked":   true})
 			_ = common.EmitEvent(ctx, "SelectorPermissionOperated", payload)
-		} else {
-			// 更新权限
-			for _, funcName := range perm.FuncNames {
-				selectorMap[funcName] = true
+			continue
+		}
+
+		if !perm.IsRevoke {
+			if err := validateGrantBounds(perm.ValidFrom, perm.ValidUntil, now); err != nil {
+				log.Printf("参数校验失败 - 账户: %s, 有效期: [%d, %d], 错误: %v", perm.Account, perm.ValidFrom, perm.ValidUntil, err)
+				return err
 			}
-			b, _ = json.Marshal(selectorMap)
-			ctx.GetStub().PutState(key, b)
-			log.Printf("更新账户权限 - 账户: %s, 新增权限: %v", perm.Account, perm.FuncNames)
+		}
 
-			// 事件通知
-			payload, _ := json.Marshal(map[string]interface{}{
-				"serviceCode": cfg.ServiceCode,
-				"projectCode": cfg.ProjectCode,
-				"account":     perm.Account,
-				"funcNames":   perm.FuncNames,
-				"action":      "update",
-				"isRevoked":   false})
-			_ = common.EmitEvent(ctx, "SelectorPermissionOperated", payload)
+		// 按操作模式逐个授权/撤销函数权限，重复授权会覆盖原有效期
+		operations := make([]SelectorOperation, 0, len(perm.FuncNames))
+		for _, funcName := range perm.FuncNames {
+			if strings.TrimSpace(funcName) == "" {
+				log.Printf("参数校验失败 - 账户: %s, 函数名为空", perm.Account)
+				return errors.New("funcName cannot be empty")
+			}
+			if perm.IsRevoke {
+				if _, ok := selectorMap[funcName]; !ok {
+					log.Printf("参数校验失败 - 账户: %s 未被授权函数: %s", perm.Account, funcName)
+					return fmt.Errorf("unknown selector %s for account %s", funcName, perm.Account)
+				}
+				delete(selectorMap, funcName)
+				operations = append(operations, SelectorOperation{FuncName: funcName, Action: selectorActionRevoke})
+			} else {
+				if err := validateSelector(funcName); err != nil {
+					log.Printf("参数校验失败 - 账户: %s, 错误: %v", perm.Account, err)
+					return err
+				}
+				selectorMap[funcName] = SelectorGrant{ValidFrom: perm.ValidFrom, ValidUntil: perm.ValidUntil}
+				operations = append(operations, SelectorOperation{
+					FuncName:   funcName,
+					Action:     selectorActionGrant,
+					ValidFrom:  perm.ValidFrom,
+					ValidUntil: perm.ValidUntil})
+			}
+		}
+
+		// 撤销后权限为空时直接删除该账户的权限映射
+		if err := putSelectorGrants(ctx, perm.Account, selectorMap); err != nil {
+			log.Printf("账户权限存储失败 - 账户: %s, 错误: %v", perm.Account, err)
+			return err
+		}
+		log.Printf("更新账户权限 - 账户: %s, 撤销模式: %t, 函数: %v, 剩余权限数量: %d", perm.Account, perm.IsRevoke, perm.FuncNames, len(selectorMap))
+		if err := recordAudit(ctx, "BatchOperateSelectorPermissions", perm.Account, oldGrants, selectorMap); err != nil {
+			return err
 		}
+
+		action := "update"
+		if perm.IsRevoke {
+			action = "revoke"
+		}
+		// 事件通知
+		payload, _ := json.Marshal(map[string]interface{}{
+			"serviceCode": cfg.ServiceCode,
+			"projectCode": cfg.ProjectCode,
+			"account":     perm.Account,
+			"funcNames":   perm.FuncNames,
+			"selectors":   operations,
+			"action":      action,
+			"isRevoked":   perm.IsRevoke})
+		_ = common.EmitEvent(ctx, "SelectorPermissionOperated", payload)
 	}
 	log.Printf("批量操作账户权限完成 - 处理账户数量: %d", len(accountSelectors))
 	return nil
 }
 
-// HasSelectorPermission 查询账户是否有某函数权限
+// HasSelectorPermission 查询账户是否有某函数权限（包含直接授权和角色授权，以及所属合约的通配授权）
 func (c *PermissionChaincode) HasSelectorPermission(ctx contractapi.TransactionContextInterface, account, funcName string) (bool, error) {
 	log.Printf("查询账户函数权限 - 账户: %s, 函数: %s", account, funcName)
 	if strings.TrimSpace(account) == "" || strings.TrimSpace(funcName) == "" {
@@ -565,22 +707,20 @@ func (c *PermissionChaincode) HasSelectorPermission(ctx contractapi.TransactionC
 		return false, errors.New("project is paused")
 	}
 
-	key := selectorPermPrefix + account
-	b, err := ctx.GetStub().GetState(key)
-	if err != nil || b == nil {
-		log.Printf("账户权限查询结果 - 账户: %s, 函数: %s, 结果: 无权限", account, funcName)
-		return false, nil
+	// 有效权限 = 直接授权 + 已分配角色中的函数权限
+	selectorMap, err := c.getEffectiveSelectors(ctx, account)
+	if err != nil {
+		log.Printf("账户权限查询失败 - 账户: %s, 错误: %v", account, err)
+		return false, err
 	}
-
-	var selectorMap map[string]bool
-	_ = json.Unmarshal(b, &selectorMap)
-	hasPermission := selectorMap[funcName]
+	hasPermission := selectorGranted(selectorMap, funcName)
 	log.Printf("账户权限查询结果 - 账户: %s, 函数: %s, 结果: %t", account, funcName, hasPermission)
 	return hasPermission, nil
 }
 
-// GetAllSelectorsForUser 查询账户所有函数权限
-func (c *PermissionChaincode) GetAllSelectorsForUser(ctx contractapi.TransactionContextInterface, account string) ([]string, error) {
+// GetAllSelectorsForUser 查询账户直接授权的所有函数权限及其有效期
+// 返回结果包含已过期和尚未生效的授权，Status按交易时间计算
+func (c *PermissionChaincode) GetAllSelectorsForUser(ctx contractapi.TransactionContextInterface, account string) ([]SelectorGrantInfo, error) {
 	if strings.TrimSpace(account) == "" {
 		return nil, errors.New("account cannot be empty")
 	}
@@ -593,20 +733,15 @@ func (c *PermissionChaincode) GetAllSelectorsForUser(ctx contractapi.Transaction
 		return nil, errors.New("project is paused")
 	}
 
-	key := selectorPermPrefix + account
-	b, err := ctx.GetStub().GetState(key)
-	if err != nil || b == nil {
-		return []string{}, nil
+	grants, err := getSelectorGrants(ctx, account)
+	if err != nil {
+		return nil, err
 	}
-
-	var selectorMap map[string]bool
-	_ = json.Unmarshal(b, &selectorMap)
-	selectors := make([]string, 0)
-	for sel := range selectorMap {
-		selectors = append(selectors, sel)
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return nil, err
 	}
-	sort.Strings(selectors) // 排序
-	return selectors, nil
+	return grantInfos(grants, txTime.Unix()), nil
 }
 
 // ================== 查询配置相关 ==================
@@ -624,6 +759,13 @@ func (c *PermissionChaincode) getProjectConfig(ctx contractapi.TransactionContex
 	return &cfg, nil
 }
 
+// putProjectConfig 内部方法：版本号加一后存储项目配置，历史版本可通过GetProjectConfigHistory查询
+func putProjectConfig(ctx contractapi.TransactionContextInterface, cfg *common.ProjectConfig) error {
+	cfg.Version++
+	b, _ := json.Marshal(cfg)
+	return ctx.GetStub().PutState(projectConfigKey, b)
+}
+
 func (c *PermissionChaincode) IsProjectPrivate(ctx contractapi.TransactionContextInterface) (bool, error) {
 	cfg, err := c.getProjectConfig(ctx)
 	if err != nil {
@@ -700,31 +842,27 @@ func (c *PermissionChaincode) IsAdminRole(ctx contractapi.TransactionContextInte
 	if cfg.Paused {
 		return false, errors.New("project is paused")
 	}
-	for _, admin := range cfg.Admins {
-		if admin == account {
-			return true, nil
+	return isAdminAccount(cfg.Admins, account), nil
+}
+
+// isAdminAccount 判断账户是否在管理员列表中（兼容未迁移的旧格式管理员）
+func isAdminAccount(admins []string, account string) bool {
+	for _, admin := range admins {
+		if common.MatchAccount(admin, account) {
+			return true
 		}
 	}
-	return false, nil
+	return false
 }
 
 // ================== 内部校验方法 ==================
 
-// checkMethod 校验某一个did标识符里的method名称是否和项目内一致
+// checkMethod 校验did标识符符合DID语法，且其method为项目主method或已启用的method
 func (c *PermissionChaincode) checkMethod(ctx contractapi.TransactionContextInterface, did string) error {
-	if strings.TrimSpace(did) == "" {
-		return errors.New("did cannot be empty")
-	}
-
-	// 解析DID标识符，格式：did:method:method-specific-id
-	parts := strings.Split(did, ":")
-	if len(parts) != 3 || parts[0] != "did" {
-		return errors.New("invalid did format, expected format: did:method:method-specific-id")
-	}
-
-	didMethod := parts[1]
-	if strings.TrimSpace(didMethod) == "" {
-		return errors.New("did method cannot be empty")
+	// 解析DID标识符，格式：did:method:method-specific-id，method-specific-id允许包含冒号
+	didMethod, _, err := common.ParseDID(did)
+	if err != nil {
+		return err
 	}
 
 	cfg, err := c.getProjectConfig(ctx)
@@ -732,12 +870,8 @@ func (c *PermissionChaincode) checkMethod(ctx contractapi.TransactionContextInte
 		return err
 	}
 
-	// 校验DID的method是否与项目配置中的Method一致
-	if didMethod != cfg.Method {
-		return fmt.Errorf("did method '%s' does not match project method '%s'", didMethod, cfg.Method)
-	}
-
-	return nil
+	// 校验DID的method是否在项目允许的method集合中且已启用
+	return checkMethodAllowed(cfg, didMethod)
 }
 
 // checkWriteFuncSelectorPermission 校验某一个写方法下某一个链账户是否拥有函数选择器权限
@@ -753,6 +887,10 @@ func (c *PermissionChaincode) checkWriteFuncSelectorPermission(ctx contractapi.T
 	if cfg.Paused {
 		return false, errors.New("project is paused")
 	}
+	// 校验函数所属模块在该方向上未被停用
+	if err := checkSelectorNotPaused(cfg, funcName, common.DirectionWrite); err != nil {
+		return false, err
+	}
 
 	// 如果是超级管理员直接返回
 	isAdmin, err := c.IsAdminRole(ctx, account)
@@ -769,7 +907,16 @@ func (c *PermissionChaincode) checkWriteFuncSelectorPermission(ctx contractapi.T
 	}
 
 	// 校验账户是否有该函数权限
-	return c.HasSelectorPermission(ctx, account, funcName)
+	hasPermission, err := c.HasSelectorPermission(ctx, account, funcName)
+	if err != nil || !hasPermission {
+		return hasPermission, err
+	}
+
+	// 校验写操作配额未用完，管理员不受配额限制；配额只在BeforeTransaction中对调用者本人累加，检查方法本身不写入状态
+	if err := c.checkWriteQuota(ctx, account, funcName); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // checkQueryFuncSelectorPermission 校验某一个查方法下某一个链账户是否拥有函数选择器权限
@@ -785,6 +932,10 @@ func (c *PermissionChaincode) checkQueryFuncSelectorPermission(ctx contractapi.T
 	if cfg.Paused {
 		return false, errors.New("project is paused")
 	}
+	// 校验函数所属模块在该方向上未被停用
+	if err := checkSelectorNotPaused(cfg, funcName, common.DirectionRead); err != nil {
+		return false, err
+	}
 
 	// 如果是超级管理员直接返回
 	isAdmin, err := c.IsAdminRole(ctx, account)
@@ -901,42 +1052,81 @@ func CheckPermission(ctx contractapi.TransactionContextInterface, account, funct
 }
 
 // ================== 权限检查接口实现 ==================
+// 所有检查方法首先校验账户未被冻结（不含账户参数的检查方法校验当前调用者），冻结优先于管理员判断
 
 // CheckWriteFuncSelectorPermission 实现PermissionChecker接口的写权限检查方法
 func (c *PermissionChaincode) CheckWriteFuncSelectorPermission(ctx contractapi.TransactionContextInterface, account, funcName string) (bool, error) {
+	if err := checkNotFrozen(ctx, account); err != nil {
+		return false, err
+	}
 	return c.checkWriteFuncSelectorPermission(ctx, account, funcName)
 }
 
 // CheckQueryFuncSelectorPermission 实现PermissionChecker接口的查询权限检查方法
 func (c *PermissionChaincode) CheckQueryFuncSelectorPermission(ctx contractapi.TransactionContextInterface, account, funcName string) (bool, error) {
+	if err := checkNotFrozen(ctx, account); err != nil {
+		return false, err
+	}
 	return c.checkQueryFuncSelectorPermission(ctx, account, funcName)
 }
 
 // CheckMethod 实现PermissionChecker接口的DID方法检查方法
 func (c *PermissionChaincode) CheckMethod(ctx contractapi.TransactionContextInterface, did string) error {
+	if err := checkNotFrozen(ctx, common.GetCaller(ctx)); err != nil {
+		return err
+	}
 	return c.checkMethod(ctx, did)
 }
 
 // CheckIssuerVerificationEnabled 实现PermissionChecker接口的发证方验证检查方法
 func (c *PermissionChaincode) CheckIssuerVerificationEnabled(ctx contractapi.TransactionContextInterface, account string) (bool, error) {
+	if err := checkNotFrozen(ctx, account); err != nil {
+		return false, err
+	}
 	return c.checkIssuerVerificationEnabled(ctx, account)
 }
 
 // CheckVCTemplateVerificationEnabled 实现PermissionChecker接口的VC模板验证检查方法
 func (c *PermissionChaincode) CheckVCTemplateVerificationEnabled(ctx contractapi.TransactionContextInterface, account string) (bool, error) {
+	if err := checkNotFrozen(ctx, account); err != nil {
+		return false, err
+	}
 	return c.checkVCTemplateVerificationEnabled(ctx, account)
 }
 
 // CheckNotPaused 实现PermissionChecker接口的项目状态检查方法
-func (c *PermissionChaincode) CheckNotPaused(ctx contractapi.TransactionContextInterface) error {
-	return c.checkNotPaused(ctx)
+// module为空时仅检查项目是否整体停用，否则同时检查该模块在direction方向上是否停用
+func (c *PermissionChaincode) CheckNotPaused(ctx contractapi.TransactionContextInterface, module, direction string) error {
+	if err := checkNotFrozen(ctx, common.GetCaller(ctx)); err != nil {
+		return err
+	}
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if cfg.Paused {
+		return errors.New("project is paused")
+	}
+	if module == "" {
+		return nil
+	}
+	return checkScopeNotPaused(cfg, module, direction)
 }
 
 // CheckAdminRole 实现PermissionChecker接口的管理员角色检查方法
 func (c *PermissionChaincode) CheckAdminRole(ctx contractapi.TransactionContextInterface, account string) error {
+	if err := checkNotFrozen(ctx, account); err != nil {
+		return err
+	}
 	return c.checkAdminRole(ctx, account)
 }
 
+// GetIgnoredFunctions 实现contractapi.IgnoreContractInterface，排除不能作为交易被外部直接调用的方法
+// ConsumeWriteQuota会写入配额使用计数，只能在BeforeTransaction中对调用者本人调用
+func (c *PermissionChaincode) GetIgnoredFunctions() []string {
+	return []string{"ConsumeWriteQuota"}
+}
+
 // GetProjectConfig 公共方法：获取项目配置，供其他模块调用
 func (c *PermissionChaincode) GetProjectConfig(ctx contractapi.TransactionContextInterface) (*common.ProjectConfig, error) {
 	return c.getProjectConfig(ctx)
@@ -0,0 +1,246 @@
+package accesscontrol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// projectConfigPatch UpdateProjectConfig允许修改的配置项，未出现的字段保持不变
+type projectConfigPatch struct {
+	IsProjectPrivate             *bool   `json:"isProjectPrivate"`
+	EnableIssuerVerification     *bool   `json:"enableIssuerVerification"`
+	EnableVCTemplateVerification *bool   `json:"enableVCTemplateVerification"`
+	EnableWritePermission        *bool   `json:"enableWritePermission"`
+	Method                       *string `json:"method"`
+}
+
+// ProjectConfigVersion 项目配置的一个历史版本
+type ProjectConfigVersion struct {
+	TxId      string               `json:"txId"`      // 写入该版本的交易ID
+	Timestamp int64                `json:"timestamp"` // 写入时间（Unix秒）
+	Config    common.ProjectConfig `json:"config"`    // 该版本的项目配置
+}
+
+// ================== 项目配置批量更新相关 ==================
+
+// UpdateProjectConfig 以JSON补丁的形式一次更新多个配置项，所有配置项校验通过后原子写入并只发送一个事件
+// 参数说明：
+//   - patch: JSON对象，只允许包含以下字段：isProjectPrivate、enableIssuerVerification、
+//     enableVCTemplateVerification、enableWritePermission（bool），method（string）
+//     例如：{"isProjectPrivate":true,"enableWritePermission":true}
+//
+// 开启多管理员审批后，补丁中包含method或enableWritePermission时需通过CreateConfigProposal发起，提案参数为该补丁
+// 权限要求：只有管理员可以调用此方法
+func (c *PermissionChaincode) UpdateProjectConfig(ctx contractapi.TransactionContextInterface, patch string) error {
+	log.Printf("开始批量更新项目配置 - 补丁: %s", patch)
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		log.Printf("获取项目配置失败: %v", err)
+		return err
+	}
+	if !common.IsAdmin(ctx, cfg.Admins) {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: UpdateProjectConfig", common.GetCaller(ctx))
+		return errors.New("only admin can update project config")
+	}
+	if err := checkNotFrozen(ctx, common.GetCaller(ctx)); err != nil {
+		return err
+	}
+	log.Printf("权限校验通过 - 调用者: %s, 操作: UpdateProjectConfig", common.GetCaller(ctx))
+	p, err := parseConfigPatch(patch)
+	if err != nil {
+		log.Printf("参数校验失败 - %v", err)
+		return err
+	}
+	if p.Method != nil || p.EnableWritePermission != nil {
+		if err := checkSingleAdminOperation(cfg, ProposalOpUpdateProjectConfig); err != nil {
+			return err
+		}
+	}
+
+	eventName, payload, err := c.applyConfigPatch(ctx, cfg, p)
+	if err != nil {
+		return err
+	}
+	return common.EmitEvent(ctx, eventName, payload)
+}
+
+// GetProjectConfigView 查询完整的项目配置（只读），项目停用时仍可查询，便于运维排查
+// 权限要求：私有项目中需要管理员或被授权GetProjectConfigView
+func (c *PermissionChaincode) GetProjectConfigView(ctx contractapi.TransactionContextInterface) (*common.ProjectConfig, error) {
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkConfigViewPermission(ctx, cfg, "GetProjectConfigView"); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// GetProjectConfigHistory 按版本从旧到新查询项目配置的所有历史版本，项目停用时仍可查询
+// 权限要求：私有项目中需要管理员或被授权GetProjectConfigHistory
+func (c *PermissionChaincode) GetProjectConfigHistory(ctx contractapi.TransactionContextInterface) ([]*ProjectConfigVersion, error) {
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkConfigViewPermission(ctx, cfg, "GetProjectConfigHistory"); err != nil {
+		return nil, err
+	}
+	return getProjectConfigHistory(ctx)
+}
+
+// GetProjectConfigVersion 查询项目配置的指定历史版本
+// 权限要求：私有项目中需要管理员或被授权GetProjectConfigHistory
+func (c *PermissionChaincode) GetProjectConfigVersion(ctx contractapi.TransactionContextInterface, version int64) (*ProjectConfigVersion, error) {
+	if version <= 0 {
+		return nil, errors.New("version must be greater than 0")
+	}
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkConfigViewPermission(ctx, cfg, "GetProjectConfigHistory"); err != nil {
+		return nil, err
+	}
+	versions, err := getProjectConfigHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 同一交易内多次修改时版本号可能跳跃，取最后写入该版本的记录
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].Config.Version == version {
+			return versions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("project config version %d not found", version)
+}
+
+// ================== 内部方法 ==================
+
+// applyConfigPatch 内部方法：校验并应用配置补丁，返回待发送的事件
+func (c *PermissionChaincode) applyConfigPatch(ctx contractapi.TransactionContextInterface, cfg *common.ProjectConfig, p *projectConfigPatch) (string, []byte, error) {
+	if cfg.Paused {
+		log.Printf("项目状态校验失败 - 项目已停用")
+		return "", nil, errors.New("project is paused")
+	}
+	oldValues := make(map[string]interface{})
+	newValues := make(map[string]interface{})
+	setBool := func(name string, field *bool, value *bool) {
+		if value != nil && *field != *value {
+			oldValues[name], newValues[name] = *field, *value
+			*field = *value
+		}
+	}
+	setBool("isProjectPrivate", &cfg.IsProjectPrivate, p.IsProjectPrivate)
+	setBool("enableIssuerVerification", &cfg.EnableIssuerVerification, p.EnableIssuerVerification)
+	setBool("enableVCTemplateVerification", &cfg.EnableVCTemplateVerification, p.EnableVCTemplateVerification)
+	setBool("enableWritePermission", &cfg.EnableWritePermission, p.EnableWritePermission)
+	if p.Method != nil && *p.Method != cfg.Method {
+		if err := common.ValidateDIDMethodName(*p.Method); err != nil {
+			log.Printf("参数校验失败 - %v", err)
+			return "", nil, err
+		}
+		oldValues["method"], newValues["method"] = cfg.Method, *p.Method
+		cfg.Method = *p.Method
+		// 新主method始终允许使用，从附加method集合中移除
+		cfg.Methods = removeMethod(cfg.Methods, cfg.Method)
+	}
+	if len(newValues) == 0 {
+		log.Printf("状态校验失败 - 补丁中的配置项均与当前配置相同")
+		return "", nil, errors.New("project config is already the same")
+	}
+
+	log.Printf("项目配置更新 - 变更项: %v", newValues)
+	if err := putProjectConfig(ctx, cfg); err != nil {
+		log.Printf("项目配置更新存储失败: %v", err)
+		return "", nil, err
+	}
+	log.Printf("项目配置更新存储成功")
+	if err := recordAudit(ctx, ProposalOpUpdateProjectConfig, "projectConfig", oldValues, newValues); err != nil {
+		return "", nil, err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode": cfg.ServiceCode,
+		"projectCode": cfg.ProjectCode,
+		"oldValues":   oldValues,
+		"newValues":   newValues,
+		"sender":      common.GetCaller(ctx)})
+	return "ProjectConfigUpdated", payload, nil
+}
+
+// checkConfigViewPermission 内部方法：校验调用者可以查看项目配置
+// 项目停用时PermissionChecker的查询权限校验会直接拒绝，此处直接按有效权限判断
+func (c *PermissionChaincode) checkConfigViewPermission(ctx contractapi.TransactionContextInterface, cfg *common.ProjectConfig, funcName string) error {
+	caller := common.GetCaller(ctx)
+	if err := checkNotFrozen(ctx, caller); err != nil {
+		return err
+	}
+	if !cfg.IsProjectPrivate || common.IsAdmin(ctx, cfg.Admins) {
+		return nil
+	}
+	selectors, err := c.getEffectiveSelectors(ctx, caller)
+	if err != nil {
+		return err
+	}
+	if !selectorGranted(selectors, funcName) {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: %s", caller, funcName)
+		return errors.New("no permission to view project config")
+	}
+	return nil
+}
+
+// getProjectConfigHistory 内部方法：通过GetHistoryForKey读取项目配置的所有历史版本，按时间从旧到新排列
+func getProjectConfigHistory(ctx contractapi.TransactionContextInterface) ([]*ProjectConfigVersion, error) {
+	iterator, err := ctx.GetStub().GetHistoryForKey(projectConfigKey)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	versions := make([]*ProjectConfigVersion, 0)
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if modification.IsDelete {
+			continue
+		}
+		version := &ProjectConfigVersion{TxId: modification.TxId}
+		if modification.Timestamp != nil {
+			version.Timestamp = modification.Timestamp.Seconds
+		}
+		if err := json.Unmarshal(modification.Value, &version.Config); err != nil {
+			return nil, err
+		}
+		versions = append([]*ProjectConfigVersion{version}, versions...)
+	}
+	return versions, nil
+}
+
+// parseConfigPatch 解析配置补丁，拒绝未知字段及空补丁
+func parseConfigPatch(patch string) (*projectConfigPatch, error) {
+	decoder := json.NewDecoder(strings.NewReader(patch))
+	decoder.DisallowUnknownFields()
+	var p projectConfigPatch
+	if err := decoder.Decode(&p); err != nil {
+		return nil, fmt.Errorf("invalid config patch: %v", err)
+	}
+	if decoder.More() {
+		return nil, errors.New("invalid config patch: unexpected data after JSON object")
+	}
+	if p.IsProjectPrivate == nil && p.EnableIssuerVerification == nil && p.EnableVCTemplateVerification == nil &&
+		p.EnableWritePermission == nil && p.Method == nil {
+		return nil, errors.New("config patch cannot be empty")
+	}
+	return &p, nil
+}
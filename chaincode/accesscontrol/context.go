@@ -0,0 +1,75 @@
+package accesscontrol
+
+import (
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// TransactionContext 各合约共用的交易上下文，在main.go中通过TransactionContextHandler设置
+// contractapi为每个交易创建新的上下文实例，因此调用者身份和项目配置只在本次交易内缓存
+type TransactionContext struct {
+	contractapi.TransactionContext
+	checker  common.PermissionChecker
+	caller   string
+	config   *common.ProjectConfig
+	auditSeq int // 本次交易已写入的审计记录数量，用于区分同一交易内对同一对象的多条审计记录
+}
+
+var _ common.TransactionContextInterface = (*TransactionContext)(nil)
+
+// NewTransactionContext 创建使用指定权限检查器的交易上下文，checker为nil时使用Permission合约
+// 用于不经过contractapi直接调用合约方法（如单元测试）；contractapi按TransactionContextHandler的类型为每个交易
+// 新建上下文，不会保留这里传入的检查器，经contractapi调度的交易需通过BeforeTransactionWithChecker注入
+func NewTransactionContext(checker common.PermissionChecker) *TransactionContext {
+	return &TransactionContext{checker: checker}
+}
+
+// SetPermissionChecker 设置本次交易使用的权限检查器，checker为nil时使用Permission合约
+func (c *TransactionContext) SetPermissionChecker(checker common.PermissionChecker) {
+	c.checker = checker
+}
+
+// SetStub 设置交易存根，切换项目（存根）后清除已缓存的项目配置
+func (c *TransactionContext) SetStub(stub shim.ChaincodeStubInterface) {
+	c.TransactionContext.SetStub(stub)
+	c.config = nil
+}
+
+// GetPermissionChecker 获取权限检查器，未指定时使用Permission合约
+func (c *TransactionContext) GetPermissionChecker() common.PermissionChecker {
+	if c.checker == nil {
+		c.checker = new(PermissionChaincode)
+	}
+	return c.checker
+}
+
+// GetCaller 获取当前调用者标识，格式：{mspId}:{ski}
+func (c *TransactionContext) GetCaller() string {
+	if c.caller == "" {
+		c.caller = common.GetCaller(c)
+	}
+	return c.caller
+}
+
+// GetProjectConfig 获取当前项目配置，同一交易内只读取一次
+// 返回的配置为只读快照，修改配置需通过Permission合约
+func (c *TransactionContext) GetProjectConfig() (*common.ProjectConfig, error) {
+	if c.config != nil {
+		return c.config, nil
+	}
+	cfg, err := c.GetPermissionChecker().GetProjectConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	c.config = cfg
+	return cfg, nil
+}
+
+// nextAuditSeq 返回本次交易下一条审计记录的序号，从0开始
+func (c *TransactionContext) nextAuditSeq() int {
+	seq := c.auditSeq
+	c.auditSeq++
+	return seq
+}
@@ -0,0 +1,102 @@
+package accesscontrol_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"sbp-did-chaincode/accesscontrol"
+	"sbp-did-chaincode/common"
+	"sbp-did-chaincode/did"
+	"sbp-did-chaincode/internal/testutil"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	testDID      = "did:sbp:alice"
+	testDocument = `{"@context":"https://www.w3.org/ns/did/v1","id":"did:sbp:alice"}`
+)
+
+func TestNewTransactionContextUsesChecker(t *testing.T) {
+	stub, owner := testutil.NewMockStub(t, nil, "Org1MSP")
+	fake := &testutil.FakeChecker{Config: common.ProjectConfig{ProjectCode: "p1"}}
+	ctx := accesscontrol.NewTransactionContext(fake)
+	ctx.SetStub(stub)
+	cc := new(did.DIDChaincode)
+
+	testutil.StartTx(stub, "tx1", time.Unix(1700000000, 0))
+	if err := cc.RegisterDid(ctx, testDID, testDocument); err != nil {
+		t.Fatalf("RegisterDid: %v", err)
+	}
+	stub.MockTransactionEnd("tx1")
+	if !fake.Called("GetProjectConfig") {
+		t.Fatalf("project config was not read from the injected checker, calls: %v", fake.Calls)
+	}
+
+	// 非创建者注销DID时由注入的检查器判断管理员身份
+	admin := testutil.SetCaller(t, stub, "Org2MSP")
+	fake.Config.Admins = []string{admin}
+	ctx = accesscontrol.NewTransactionContext(fake)
+	ctx.SetStub(stub)
+	testutil.StartTx(stub, "tx2", time.Unix(1700000100, 0))
+	if err := cc.DeactivateDid(ctx, testDID); err != nil {
+		t.Fatalf("DeactivateDid by admin: %v", err)
+	}
+	stub.MockTransactionEnd("tx2")
+	if !fake.Called("CheckAdminRole:" + admin) {
+		t.Fatalf("admin role was not checked by the injected checker, calls: %v", fake.Calls)
+	}
+	if fake.Called("CheckAdminRole:" + owner) {
+		t.Fatalf("creator should not be checked, calls: %v", fake.Calls)
+	}
+}
+
+func TestBeforeTransactionWithChecker(t *testing.T) {
+	tests := []struct {
+		name    string
+		denied  map[string]bool
+		wantErr string
+	}{
+		{name: "allowed"},
+		{name: "denied", denied: map[string]bool{"RegisterDid": true}, wantErr: "no permission to call RegisterDid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &testutil.FakeChecker{Denied: tt.denied}
+			didCC := new(did.DIDChaincode)
+			didCC.Name = "did"
+			didCC.TransactionContextHandler = new(accesscontrol.TransactionContext)
+			didCC.BeforeTransaction = accesscontrol.BeforeTransactionWithChecker(didCC.Name, fake)
+			cc, err := contractapi.NewChaincode(didCC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stub, _ := testutil.NewMockStub(t, cc, "Org1MSP")
+
+			resp := stub.MockInvoke("tx1", [][]byte{[]byte("did:RegisterDid"), []byte(testDID), []byte(testDocument)})
+			if !fake.Called("CheckWriteFuncSelectorPermission:RegisterDid") {
+				t.Fatalf("permission was not checked by the injected checker, calls: %v", fake.Calls)
+			}
+			state, err := stub.GetState("did:info:" + testDID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" {
+				if resp.Status == 200 || !strings.Contains(resp.Message, tt.wantErr) {
+					t.Fatalf("got status %d message %q, want error %q", resp.Status, resp.Message, tt.wantErr)
+				}
+				if state != nil || fake.Called("ConsumeWriteQuota:RegisterDid") {
+					t.Fatalf("denied transaction must not write state or consume quota, calls: %v", fake.Calls)
+				}
+				return
+			}
+			if resp.Status != 200 {
+				t.Fatalf("got status %d message %q", resp.Status, resp.Message)
+			}
+			if state == nil || !fake.Called("ConsumeWriteQuota:RegisterDid") || !fake.Called("CheckMethod:"+testDID) {
+				t.Fatalf("expected did stored and quota consumed, calls: %v", fake.Calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package accesscontrol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Delegation 账户间的函数权限委托记录
+// 委托权限在每次校验时与委托人当前的权限取交集，委托人权限被回收或账户被冻结后委托随之失效
+type Delegation struct {
+	Delegator string   `json:"delegator"` // 委托人账户标识
+	Delegate  string   `json:"delegate"`  // 被委托人账户标识
+	FuncNames []string `json:"funcNames"` // 委托的函数选择器列表
+	ExpiresAt int64    `json:"expiresAt"` // 失效时间（Unix秒），为0表示永久有效
+	CreatedAt int64    `json:"createdAt"` // 委托时间（Unix秒，取交易时间戳）
+	TxId      string   `json:"txId"`      // 委托所在交易ID
+}
+
+const (
+	// delegationObjectType 委托记录复合键类型
+	// 复合键属性：{delegator}、{delegate}，值为委托记录
+	delegationObjectType = "permission~delegation"
+
+	// delegateIndexObjectType 被委托人->委托人索引复合键类型
+	// 复合键属性：{delegate}、{delegator}，值为委托记录，用于权限校验时查询账户收到的委托
+	delegateIndexObjectType = "permission~delegate"
+)
+
+// ================== 权限委托相关 ==================
+
+// DelegateSelectors 调用者将自己拥有的部分函数权限委托给其他账户，如发证方操作员委托备用操作员
+// 参数说明：
+// - delegate: 被委托人账户标识，格式：{mspId}:{ski}
+// - funcNames: 委托的函数选择器，必须在调用者当前的直接授权、组织授权或角色权限范围内
+// - expiresAt: 失效时间（Unix秒），为0表示永久有效
+//
+// 对同一被委托人重复委托会覆盖原委托；委托权限不能再次委托
+func (c *PermissionChaincode) DelegateSelectors(ctx contractapi.TransactionContextInterface, delegate string, funcNames []string, expiresAt int64) error {
+	delegator := common.GetCaller(ctx)
+	log.Printf("开始委托函数权限 - 委托人: %s, 被委托人: %s, 函数: %v, 失效时间: %d", delegator, delegate, funcNames, expiresAt)
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		log.Printf("获取项目配置失败: %v", err)
+		return err
+	}
+	if cfg.Paused {
+		log.Printf("项目状态校验失败 - 项目已停用")
+		return errors.New("project is paused")
+	}
+	if err := checkNotFrozen(ctx, delegator); err != nil {
+		return err
+	}
+
+	if err := common.ValidateAccount(delegate); err != nil {
+		log.Printf("参数校验失败 - %v", err)
+		return err
+	}
+	if delegate == delegator {
+		log.Printf("参数校验失败 - 不能委托给自己")
+		return errors.New("cannot delegate to yourself")
+	}
+	funcNames, err = normalizeFuncNames(funcNames)
+	if err != nil {
+		log.Printf("参数校验失败 - %v", err)
+		return err
+	}
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if expiresAt < 0 || (expiresAt != 0 && expiresAt <= txTime.Unix()) {
+		log.Printf("参数校验失败 - 失效时间: %d", expiresAt)
+		return errors.New("expiresAt must be 0 or later than the transaction time")
+	}
+
+	// 委托权限不能超过委托人自身的权限
+	owned, err := c.getGrantedSelectors(ctx, delegator)
+	if err != nil {
+		return err
+	}
+	for _, funcName := range funcNames {
+		if !selectorGranted(owned, funcName) {
+			log.Printf("权限校验失败 - 委托人: %s 未拥有函数权限: %s", delegator, funcName)
+			return fmt.Errorf("cannot delegate %s: delegator does not hold it", funcName)
+		}
+	}
+
+	old, err := getDelegation(ctx, delegator, delegate)
+	if err != nil {
+		return err
+	}
+	delegation := &Delegation{
+		Delegator: delegator,
+		Delegate:  delegate,
+		FuncNames: funcNames,
+		ExpiresAt: expiresAt,
+		CreatedAt: txTime.Unix(),
+		TxId:      ctx.GetStub().GetTxID(),
+	}
+	if err := putDelegation(ctx, delegation); err != nil {
+		log.Printf("委托记录存储失败: %v", err)
+		return err
+	}
+	log.Printf("委托记录存储成功 - 委托人: %s, 被委托人: %s", delegator, delegate)
+	if err := recordAudit(ctx, "DelegateSelectors", delegator+"->"+delegate, old, delegation); err != nil {
+		return err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode": cfg.ServiceCode,
+		"projectCode": cfg.ProjectCode,
+		"delegation":  delegation,
+		"sender":      delegator})
+	return common.EmitEvent(ctx, "SelectorsDelegated", payload)
+}
+
+// RevokeDelegation 撤销委托
+// 权限要求：委托人本人或管理员可以调用此方法
+func (c *PermissionChaincode) RevokeDelegation(ctx contractapi.TransactionContextInterface, delegator, delegate string) error {
+	caller := common.GetCaller(ctx)
+	log.Printf("开始撤销委托 - 委托人: %s, 被委托人: %s, 调用者: %s", delegator, delegate, caller)
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		log.Printf("获取项目配置失败: %v", err)
+		return err
+	}
+	if !common.MatchAccount(caller, delegator) && !common.IsAdmin(ctx, cfg.Admins) {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: RevokeDelegation", caller)
+		return errors.New("only the delegator or admin can call RevokeDelegation")
+	}
+	if err := checkNotFrozen(ctx, caller); err != nil {
+		return err
+	}
+
+	delegation, err := getDelegation(ctx, delegator, delegate)
+	if err != nil {
+		return err
+	}
+	if delegation == nil {
+		log.Printf("撤销委托失败 - 委托不存在")
+		return errors.New("delegation not found")
+	}
+	if err := deleteDelegation(ctx, delegator, delegate); err != nil {
+		log.Printf("委托记录删除失败: %v", err)
+		return err
+	}
+	log.Printf("委托记录删除成功 - 委托人: %s, 被委托人: %s", delegator, delegate)
+	if err := recordAudit(ctx, "RevokeDelegation", delegator+"->"+delegate, delegation, nil); err != nil {
+		return err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode": cfg.ServiceCode,
+		"projectCode": cfg.ProjectCode,
+		"delegation":  delegation,
+		"sender":      caller})
+	return common.EmitEvent(ctx, "DelegationRevoked", payload)
+}
+
+// GetDelegationsByDelegator 查询账户作为委托人发出的所有委托
+func (c *PermissionChaincode) GetDelegationsByDelegator(ctx contractapi.TransactionContextInterface, delegator string) ([]*Delegation, error) {
+	if strings.TrimSpace(delegator) == "" {
+		return nil, errors.New("delegator cannot be empty")
+	}
+	if err := c.checkNotPaused(ctx); err != nil {
+		return nil, err
+	}
+	return listDelegations(ctx, delegationObjectType, delegator)
+}
+
+// GetDelegationsToAccount 查询账户收到的所有委托，包含已失效但未撤销的委托
+func (c *PermissionChaincode) GetDelegationsToAccount(ctx contractapi.TransactionContextInterface, delegate string) ([]*Delegation, error) {
+	if strings.TrimSpace(delegate) == "" {
+		return nil, errors.New("delegate cannot be empty")
+	}
+	if err := c.checkNotPaused(ctx); err != nil {
+		return nil, err
+	}
+	return listDelegations(ctx, delegateIndexObjectType, delegate)
+}
+
+// ================== 内部方法 ==================
+
+// addDelegatedSelectors 内部方法：将账户收到的有效委托权限合并到权限集合
+// 委托未过期、委托人未被冻结，且委托人当前仍拥有的函数才生效
+func (c *PermissionChaincode) addDelegatedSelectors(ctx contractapi.TransactionContextInterface, account string, selectors map[string]bool) error {
+	delegations, err := listDelegations(ctx, delegateIndexObjectType, account)
+	if err != nil {
+		return err
+	}
+	if len(delegations) == 0 {
+		return nil
+	}
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	for _, delegation := range delegations {
+		if delegation.ExpiresAt != 0 && txTime.Unix() >= delegation.ExpiresAt {
+			continue
+		}
+		frozen, err := getFrozenAccount(ctx, delegation.Delegator)
+		if err != nil {
+			return err
+		}
+		if frozen != nil && frozen.Frozen {
+			continue
+		}
+		owned, err := c.getGrantedSelectors(ctx, delegation.Delegator)
+		if err != nil {
+			return err
+		}
+		for _, funcName := range delegation.FuncNames {
+			if selectorGranted(owned, funcName) {
+				selectors[funcName] = true
+			}
+		}
+	}
+	return nil
+}
+
+// getDelegation 内部方法：获取委托记录，不存在时返回nil
+func getDelegation(ctx contractapi.TransactionContextInterface, delegator, delegate string) (*Delegation, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(delegationObjectType, []string{delegator, delegate})
+	if err != nil {
+		return nil, err
+	}
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var delegation Delegation
+	if err := json.Unmarshal(b, &delegation); err != nil {
+		return nil, err
+	}
+	return &delegation, nil
+}
+
+// putDelegation 内部方法：存储委托记录并维护被委托人索引
+func putDelegation(ctx contractapi.TransactionContextInterface, delegation *Delegation) error {
+	stub := ctx.GetStub()
+	key, err := stub.CreateCompositeKey(delegationObjectType, []string{delegation.Delegator, delegation.Delegate})
+	if err != nil {
+		return err
+	}
+	indexKey, err := stub.CreateCompositeKey(delegateIndexObjectType, []string{delegation.Delegate, delegation.Delegator})
+	if err != nil {
+		return err
+	}
+	b, _ := json.Marshal(delegation)
+	if err := stub.PutState(key, b); err != nil {
+		return err
+	}
+	return stub.PutState(indexKey, b)
+}
+
+// deleteDelegation 内部方法：删除委托记录及被委托人索引
+func deleteDelegation(ctx contractapi.TransactionContextInterface, delegator, delegate string) error {
+	stub := ctx.GetStub()
+	key, err := stub.CreateCompositeKey(delegationObjectType, []string{delegator, delegate})
+	if err != nil {
+		return err
+	}
+	indexKey, err := stub.CreateCompositeKey(delegateIndexObjectType, []string{delegate, delegator})
+	if err != nil {
+		return err
+	}
+	if err := stub.DelState(key); err != nil {
+		return err
+	}
+	return stub.DelState(indexKey)
+}
+
+// listDelegations 内部方法：按复合键首个属性查询委托记录
+func listDelegations(ctx contractapi.TransactionContextInterface, objectType, account string) ([]*Delegation, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(objectType, []string{account})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	delegations := make([]*Delegation, 0)
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var delegation Delegation
+		if err := json.Unmarshal(kv.Value, &delegation); err != nil {
+			return nil, err
+		}
+		delegations = append(delegations, &delegation)
+	}
+	return delegations, nil
+}
@@ -0,0 +1,276 @@
+package accesscontrol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// FrozenAccount 账户冻结记录
+// 解冻后记录保留并标记Frozen为false，冻结/解冻的完整过程可通过GetAccountFreezeHistory审计
+type FrozenAccount struct {
+	Account    string `json:"account"`    // 账户标识，格式：{mspId}:{ski}
+	Frozen     bool   `json:"frozen"`     // 是否处于冻结状态
+	Reason     string `json:"reason"`     // 冻结/解冻原因，如证书泄露
+	OperatedBy string `json:"operatedBy"` // 执行冻结/解冻的管理员
+	OperatedAt int64  `json:"operatedAt"` // 执行时间（Unix秒，取交易时间戳）
+	TxId       string `json:"txId"`       // 执行冻结/解冻的交易ID
+}
+
+const (
+	// frozenAccountPrefix 账户冻结记录在链上的存储键前缀
+	// 格式：permission:frozen:{account}
+	frozenAccountPrefix = "permission:frozen:"
+)
+
+// ================== 账户冻结相关 ==================
+
+// FreezeAccount 冻结账户，用于证书泄露等紧急情况
+// 冻结后PermissionChecker的所有检查都会拒绝该账户，包括管理员账户
+// 项目停用期间仍可执行；管理员不能冻结自己
+// 权限要求：只有未被冻结的管理员可以调用此方法；开启多管理员审批后，冻结管理员账户需通过提案执行
+func (c *PermissionChaincode) FreezeAccount(ctx contractapi.TransactionContextInterface, account, reason string) error {
+	log.Printf("开始冻结账户 - 账户: %s, 原因: %s", account, reason)
+	return c.operateFreeze(ctx, account, reason, true)
+}
+
+// UnfreezeAccount 解冻账户
+// 权限要求：只有未被冻结的管理员可以调用此方法；开启多管理员审批后，解冻管理员账户需通过提案执行
+func (c *PermissionChaincode) UnfreezeAccount(ctx contractapi.TransactionContextInterface, account, reason string) error {
+	log.Printf("开始解冻账户 - 账户: %s, 原因: %s", account, reason)
+	return c.operateFreeze(ctx, account, reason, false)
+}
+
+// GetFrozenAccount 查询账户冻结记录，账户从未被冻结时返回错误
+func (c *PermissionChaincode) GetFrozenAccount(ctx contractapi.TransactionContextInterface, account string) (*FrozenAccount, error) {
+	if strings.TrimSpace(account) == "" {
+		return nil, errors.New("account cannot be empty")
+	}
+	record, err := getFrozenAccount(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, errors.New("freeze record not found")
+	}
+	return record, nil
+}
+
+// ListFrozenAccounts 查询当前处于冻结状态的所有账户
+func (c *PermissionChaincode) ListFrozenAccounts(ctx contractapi.TransactionContextInterface) ([]*FrozenAccount, error) {
+	iterator, err := ctx.GetStub().GetStateByRange(frozenAccountPrefix, frozenAccountPrefix+"~")
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	records := make([]*FrozenAccount, 0)
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var record FrozenAccount
+		if err := json.Unmarshal(kv.Value, &record); err != nil {
+			return nil, err
+		}
+		if record.Frozen {
+			records = append(records, &record)
+		}
+	}
+	return records, nil
+}
+
+// GetAccountFreezeHistory 审计查询：按时间顺序返回账户的所有冻结/解冻记录
+func (c *PermissionChaincode) GetAccountFreezeHistory(ctx contractapi.TransactionContextInterface, account string) ([]*FrozenAccount, error) {
+	if strings.TrimSpace(account) == "" {
+		return nil, errors.New("account cannot be empty")
+	}
+	iterator, err := ctx.GetStub().GetHistoryForKey(frozenAccountPrefix + account)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	// 历史记录按从新到旧返回，审计结果按时间顺序排列
+	records := make([]*FrozenAccount, 0)
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if modification.IsDelete {
+			continue
+		}
+		var record FrozenAccount
+		if err := json.Unmarshal(modification.Value, &record); err != nil {
+			return nil, err
+		}
+		records = append([]*FrozenAccount{&record}, records...)
+	}
+	return records, nil
+}
+
+// ================== 内部方法 ==================
+
+// operateFreeze 内部方法：校验管理员身份后冻结/解冻账户
+// 开启多管理员审批后，冻结/解冻管理员账户需通过提案执行
+func (c *PermissionChaincode) operateFreeze(ctx contractapi.TransactionContextInterface, account, reason string, frozen bool) error {
+	operation := ProposalOpUnfreezeAccount
+	if frozen {
+		operation = ProposalOpFreezeAccount
+	}
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		log.Printf("获取项目配置失败: %v", err)
+		return err
+	}
+	caller := common.GetCaller(ctx)
+	if !common.IsAdmin(ctx, cfg.Admins) {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: %s", caller, operation)
+		return fmt.Errorf("only admin can call %s", operation)
+	}
+	// 被冻结的管理员不能冻结/解冻任何账户，包括解冻自己
+	if err := checkNotFrozen(ctx, caller); err != nil {
+		log.Printf("权限校验失败 - 调用者: %s 已被冻结, 操作: %s", caller, operation)
+		return err
+	}
+	log.Printf("权限校验通过 - 调用者: %s, 操作: %s", caller, operation)
+
+	if frozen && account == caller {
+		log.Printf("参数校验失败 - 管理员不能冻结自己")
+		return errors.New("cannot freeze yourself")
+	}
+	// 单个管理员冻结其他管理员即可使其审批失效，多管理员审批下须经提案
+	if isAdminAccount(cfg.Admins, account) {
+		if err := checkSingleAdminOperation(cfg, operation); err != nil {
+			return err
+		}
+	}
+
+	eventName, payload, err := c.applyFreeze(ctx, cfg, account, reason, frozen)
+	if err != nil {
+		return err
+	}
+	return common.EmitEvent(ctx, eventName, payload)
+}
+
+// applyFreeze 内部方法：校验并冻结/解冻账户，返回待发送的事件
+func (c *PermissionChaincode) applyFreeze(ctx contractapi.TransactionContextInterface, cfg *common.ProjectConfig, account, reason string, frozen bool) (string, []byte, error) {
+	if err := common.ValidateAccount(account); err != nil {
+		log.Printf("参数校验失败 - %v", err)
+		return "", nil, err
+	}
+	if strings.TrimSpace(reason) == "" {
+		log.Printf("参数校验失败 - 原因为空")
+		return "", nil, errors.New("reason cannot be empty")
+	}
+
+	record, err := getFrozenAccount(ctx, account)
+	if err != nil {
+		return "", nil, err
+	}
+	isFrozen := record != nil && record.Frozen
+	if frozen && isFrozen {
+		log.Printf("冻结账户失败 - 账户已被冻结: %s", account)
+		return "", nil, errors.New("account is already frozen")
+	}
+	if !frozen && !isFrozen {
+		log.Printf("解冻账户失败 - 账户未被冻结: %s", account)
+		return "", nil, errors.New("account is not frozen")
+	}
+
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	caller := common.GetCaller(ctx)
+	oldRecord := record
+	record = &FrozenAccount{
+		Account:    account,
+		Frozen:     frozen,
+		Reason:     reason,
+		OperatedBy: caller,
+		OperatedAt: txTime.Unix(),
+		TxId:       ctx.GetStub().GetTxID(),
+	}
+	b, _ := json.Marshal(record)
+	if err := ctx.GetStub().PutState(frozenAccountPrefix+account, b); err != nil {
+		log.Printf("账户冻结记录存储失败: %v", err)
+		return "", nil, err
+	}
+	log.Printf("账户冻结记录存储成功 - 账户: %s, 冻结: %t", account, frozen)
+	operation := ProposalOpUnfreezeAccount
+	if frozen {
+		operation = ProposalOpFreezeAccount
+	}
+	if err := recordAudit(ctx, operation, account, oldRecord, record); err != nil {
+		return "", nil, err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode": cfg.ServiceCode,
+		"projectCode": cfg.ProjectCode,
+		"record":      record,
+		"sender":      caller})
+	if frozen {
+		return "AccountFrozen", payload, nil
+	}
+	return "AccountUnfrozen", payload, nil
+}
+
+// freezeProposalValue 冻结/解冻账户提案的参数，JSON格式：{"account":"...","reason":"..."}
+type freezeProposalValue struct {
+	Account string `json:"account"`
+	Reason  string `json:"reason"`
+}
+
+// parseFreezeProposalValue 解析并校验冻结/解冻账户提案的参数
+func parseFreezeProposalValue(value string) (*freezeProposalValue, error) {
+	var v freezeProposalValue
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		return nil, errors.New(`invalid value, expected format: {"account":"...","reason":"..."}`)
+	}
+	if err := common.ValidateAccount(v.Account); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(v.Reason) == "" {
+		return nil, errors.New("reason cannot be empty")
+	}
+	return &v, nil
+}
+
+// getFrozenAccount 内部方法：获取账户冻结记录，不存在时返回nil
+func getFrozenAccount(ctx contractapi.TransactionContextInterface, account string) (*FrozenAccount, error) {
+	b, err := ctx.GetStub().GetState(frozenAccountPrefix + account)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var record FrozenAccount
+	if err := json.Unmarshal(b, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// checkNotFrozen 内部方法：校验账户未被冻结
+func checkNotFrozen(ctx contractapi.TransactionContextInterface, account string) error {
+	record, err := getFrozenAccount(ctx, account)
+	if err != nil {
+		return err
+	}
+	if record != nil && record.Frozen {
+		log.Printf("账户状态校验失败 - 账户: %s 已被冻结, 原因: %s", account, record.Reason)
+		return fmt.Errorf("account %s is frozen: %s", account, record.Reason)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package accesscontrol_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"sbp-did-chaincode/accesscontrol"
+)
+
+func TestFrozenAdmin(t *testing.T) {
+	p := newPermissionTest(t)
+	a, b, c, d, e := p.newAccount(), p.newAccount(), p.newAccount(), p.newAccount(), p.newAccount()
+	p.must(p.cc.InitProject(p.as(a), "sbp", false, false, false, false, "svc", "proj", false))
+	for _, admin := range []testAccount{b, c} {
+		p.must(p.cc.ProposeAdmin(p.as(a), admin.account))
+		p.must(p.cc.AcceptAdmin(p.as(admin)))
+	}
+	p.must(p.cc.ProposeAdmin(p.as(a), d.account))
+	p.must(p.cc.SetApprovalThreshold(p.as(a), 2))
+	deadline := p.now.Add(24 * time.Hour).Unix()
+	proposalID, err := p.cc.CreateConfigProposal(p.as(b), "Pause", "", deadline)
+	p.must(err)
+	freezeB, err := p.cc.CreateConfigProposal(p.as(a), "FreezeAccount", freezeValue(b, "leaked certificate"), deadline)
+	p.must(err)
+	p.must(p.cc.ApproveConfigProposal(p.as(c), freezeB))
+	p.must(p.cc.FreezeAccount(p.as(a), d.account, "leaked certificate"))
+
+	t.Run("frozen approvals are not counted", func(t *testing.T) {
+		p.must(p.cc.ApproveConfigProposal(p.as(c), proposalID))
+		proposal, err := p.cc.GetConfigProposal(p.as(a), proposalID)
+		p.must(err)
+		if proposal.Status != accesscontrol.ProposalStatusPending {
+			t.Fatalf("got proposal status %s, want %s", proposal.Status, accesscontrol.ProposalStatusPending)
+		}
+		paused, err := p.cc.Paused(p.as(a))
+		p.must(err)
+		if paused {
+			t.Fatal("project must not be paused by a proposal approved by a frozen admin")
+		}
+	})
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"ChangePrivateStatus", func() error { return p.cc.ChangePrivateStatus(p.as(b), true) }},
+		{"ChangeEnableWritePermission", func() error { return p.cc.ChangeEnableWritePermission(p.as(b), true) }},
+		{"UpdateProjectConfig", func() error { return p.cc.UpdateProjectConfig(p.as(b), `{"isProjectPrivate":true}`) }},
+		{"CreateConfigProposal", func() error {
+			_, err := p.cc.CreateConfigProposal(p.as(b), "Unpause", "", deadline)
+			return err
+		}},
+		{"ApproveConfigProposal", func() error { return p.cc.ApproveConfigProposal(p.as(b), proposalID) }},
+		{"CreateRole", func() error { return p.cc.CreateRole(p.as(b), "writer", "", []string{"RegisterDid"}) }},
+		{"ProposeAdmin", func() error { return p.cc.ProposeAdmin(p.as(b), e.account) }},
+		{"AcceptAdmin", func() error { return p.cc.AcceptAdmin(p.as(d)) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if err == nil || !strings.Contains(err.Error(), "is frozen") {
+				t.Fatalf("got err %v, want frozen account error", err)
+			}
+		})
+	}
+}
+
+func TestFreezeAdminRequiresProposal(t *testing.T) {
+	p := newPermissionTest(t)
+	a, b, c, user := p.newAccount(), p.newAccount(), p.newAccount(), p.newAccount()
+	p.must(p.cc.InitProject(p.as(a), "sbp", false, false, false, false, "svc", "proj", false))
+	for _, admin := range []testAccount{b, c} {
+		p.must(p.cc.ProposeAdmin(p.as(a), admin.account))
+		p.must(p.cc.AcceptAdmin(p.as(admin)))
+	}
+	p.must(p.cc.SetApprovalThreshold(p.as(a), 2))
+	deadline := p.now.Add(24 * time.Hour).Unix()
+
+	err := p.cc.FreezeAccount(p.as(a), b.account, "leaked certificate")
+	if err == nil || !strings.Contains(err.Error(), "requires approval of 2 admins") {
+		t.Fatalf("got err %v, want approval required error", err)
+	}
+	if _, err := p.cc.GetFrozenAccount(p.as(a), b.account); err == nil {
+		t.Fatal("co-admin must not be frozen by a single admin")
+	}
+	// 非管理员账户仍可由单个管理员直接冻结
+	p.must(p.cc.FreezeAccount(p.as(a), user.account, "leaked certificate"))
+
+	proposalID, err := p.cc.CreateConfigProposal(p.as(a), "FreezeAccount", freezeValue(b, "leaked certificate"), deadline)
+	p.must(err)
+	if record, err := p.cc.GetFrozenAccount(p.as(a), b.account); err == nil && record.Frozen {
+		t.Fatal("co-admin must not be frozen before the proposal reaches the threshold")
+	}
+	p.must(p.cc.ApproveConfigProposal(p.as(c), proposalID))
+	record, err := p.cc.GetFrozenAccount(p.as(a), b.account)
+	p.must(err)
+	if !record.Frozen || record.Reason != "leaked certificate" {
+		t.Fatalf("unexpected freeze record %+v", record)
+	}
+
+	err = p.cc.UnfreezeAccount(p.as(a), b.account, "certificate revoked")
+	if err == nil || !strings.Contains(err.Error(), "requires approval of 2 admins") {
+		t.Fatalf("got err %v, want approval required error", err)
+	}
+	proposalID, err = p.cc.CreateConfigProposal(p.as(a), "UnfreezeAccount", freezeValue(b, "certificate revoked"), deadline)
+	p.must(err)
+	p.must(p.cc.ApproveConfigProposal(p.as(c), proposalID))
+	record, err = p.cc.GetFrozenAccount(p.as(a), b.account)
+	p.must(err)
+	if record.Frozen {
+		t.Fatalf("unexpected freeze record %+v", record)
+	}
+
+	if _, err := p.cc.CreateConfigProposal(p.as(a), "FreezeAccount", b.account, deadline); err == nil {
+		t.Fatal("expected error for a freeze proposal without reason")
+	}
+}
+
+// freezeValue 冻结/解冻账户提案的参数
+func freezeValue(a testAccount, reason string) string {
+	b, _ := json.Marshal(map[string]string{"account": a.account, "reason": reason})
+	return string(b)
+}
@@ -0,0 +1,505 @@
+package accesscontrol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// SelectorGrant 账户单个函数权限的授权记录
+// ValidFrom/ValidUntil为Unix时间戳（秒），为0表示不限制；按交易时间戳判断是否生效
+type SelectorGrant struct {
+	ValidFrom  int64 `json:"validFrom,omitempty"`  // 生效时间，为0表示立即生效
+	ValidUntil int64 `json:"validUntil,omitempty"` // 失效时间，为0表示永久有效
+}
+
+// SelectorGrantInfo 账户函数权限查询结果，包含有效期及当前状态
+type SelectorGrantInfo struct {
+	FuncName   string `json:"funcName"`   // 函数名
+	ValidFrom  int64  `json:"validFrom"`  // 生效时间，为0表示立即生效
+	ValidUntil int64  `json:"validUntil"` // 失效时间，为0表示永久有效
+	Status     string `json:"status"`     // 当前状态：active/pending/expired
+}
+
+// AccountPermissions 账户及其直接授权的函数权限
+type AccountPermissions struct {
+	Account   string              `json:"account"`   // 账户标识
+	Selectors []SelectorGrantInfo `json:"selectors"` // 函数授权列表
+}
+
+// AccountPermissionsPage 账户权限分页查询结果
+type AccountPermissionsPage struct {
+	Accounts     []AccountPermissions `json:"accounts"`     // 当前页账户权限
+	Bookmark     string               `json:"bookmark"`     // 下一页书签，为空表示没有更多数据
+	FetchedCount int32                `json:"fetchedCount"` // 当前页记录数
+}
+
+// SelectorAccount 被授权某函数的账户及授权有效期
+type SelectorAccount struct {
+	Account    string `json:"account"`    // 账户标识
+	ValidFrom  int64  `json:"validFrom"`  // 生效时间，为0表示立即生效
+	ValidUntil int64  `json:"validUntil"` // 失效时间，为0表示永久有效
+	Status     string `json:"status"`     // 当前状态：active/pending/expired
+}
+
+// SelectorAccountsPage 函数授权账户分页查询结果
+type SelectorAccountsPage struct {
+	Accounts     []SelectorAccount `json:"accounts"`     // 当前页账户
+	Bookmark     string            `json:"bookmark"`     // 下一页书签，为空表示没有更多数据
+	FetchedCount int32             `json:"fetchedCount"` // 当前页记录数
+}
+
+const (
+	// maxPageSize 分页查询单页最大记录数
+	maxPageSize = 200
+
+	// selectorPermObjectType 账户权限复合键类型
+	// 复合键属性：{account}，值为该账户的函数授权映射
+	selectorPermObjectType = "permission~selectorPerm"
+
+	// selectorIndexObjectType 函数->账户权限索引复合键类型
+	// 复合键属性：{funcName}、{account}，值为该授权记录，用于按函数查询被授权账户
+	selectorIndexObjectType = "permission~selectorAccount"
+
+	// grantStatusActive 授权在有效期内
+	grantStatusActive = "active"
+	// grantStatusPending 授权尚未到生效时间
+	grantStatusPending = "pending"
+	// grantStatusExpired 授权已过失效时间
+	grantStatusExpired = "expired"
+)
+
+// ================== 账户权限枚举相关 ==================
+
+// ListAccountsWithPermissions 分页查询所有拥有直接授权的账户及其函数权限
+// 参数说明：
+// - pageSize: 单页记录数，取值范围 1~200
+// - bookmark: 上一页返回的书签，首页传空字符串
+//
+// 权限要求：私有项目中需要管理员或被授权ListAccountsWithPermissions
+// 注意：仅统计复合键存储的数据，旧版存储键需先调用MigrateSelectorPermissionKeys迁移
+func (c *PermissionChaincode) ListAccountsWithPermissions(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string) (*AccountPermissionsPage, error) {
+	if err := checkPageSize(pageSize); err != nil {
+		return nil, err
+	}
+	hasPermission, err := c.CheckQueryFuncSelectorPermission(ctx, common.GetCaller(ctx), "ListAccountsWithPermissions")
+	if err != nil {
+		return nil, err
+	}
+	if !hasPermission {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: ListAccountsWithPermissions", common.GetCaller(ctx))
+		return nil, errors.New("no permission to list selector permissions")
+	}
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	iterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(selectorPermObjectType, []string{}, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	page := &AccountPermissionsPage{Accounts: make([]AccountPermissions, 0)}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attrs, err := ctx.GetStub().SplitCompositeKey(kv.Key)
+		if err != nil || len(attrs) != 1 {
+			return nil, fmt.Errorf("invalid selector permission key: %q", kv.Key)
+		}
+		var grants map[string]SelectorGrant
+		if err := json.Unmarshal(kv.Value, &grants); err != nil {
+			return nil, err
+		}
+		page.Accounts = append(page.Accounts, AccountPermissions{
+			Account:   attrs[0],
+			Selectors: grantInfos(grants, txTime.Unix()),
+		})
+	}
+	page.Bookmark = metadata.GetBookmark()
+	page.FetchedCount = metadata.GetFetchedRecordsCount()
+	log.Printf("分页查询账户权限 - 单页记录数: %d, 返回账户数量: %d", pageSize, page.FetchedCount)
+	return page, nil
+}
+
+// ListAccountsForSelector 分页查询被直接授权指定函数的所有账户
+// 通配授权（如 issuer:*）按通配选择器单独登记，需以通配选择器作为funcName查询
+// 参数说明：
+// - funcName: 函数名
+// - pageSize: 单页记录数，取值范围 1~200
+// - bookmark: 上一页返回的书签，首页传空字符串
+//
+// 权限要求：私有项目中需要管理员或被授权ListAccountsForSelector
+func (c *PermissionChaincode) ListAccountsForSelector(ctx contractapi.TransactionContextInterface, funcName string, pageSize int32, bookmark string) (*SelectorAccountsPage, error) {
+	if strings.TrimSpace(funcName) == "" {
+		return nil, errors.New("funcName cannot be empty")
+	}
+	if err := checkPageSize(pageSize); err != nil {
+		return nil, err
+	}
+	hasPermission, err := c.CheckQueryFuncSelectorPermission(ctx, common.GetCaller(ctx), "ListAccountsForSelector")
+	if err != nil {
+		return nil, err
+	}
+	if !hasPermission {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: ListAccountsForSelector", common.GetCaller(ctx))
+		return nil, errors.New("no permission to list selector permissions")
+	}
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	iterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(selectorIndexObjectType, []string{funcName}, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	page := &SelectorAccountsPage{Accounts: make([]SelectorAccount, 0)}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attrs, err := ctx.GetStub().SplitCompositeKey(kv.Key)
+		if err != nil || len(attrs) != 2 {
+			return nil, fmt.Errorf("invalid selector index key: %q", kv.Key)
+		}
+		var grant SelectorGrant
+		if err := json.Unmarshal(kv.Value, &grant); err != nil {
+			return nil, err
+		}
+		page.Accounts = append(page.Accounts, SelectorAccount{
+			Account:    attrs[1],
+			ValidFrom:  grant.ValidFrom,
+			ValidUntil: grant.ValidUntil,
+			Status:     grant.status(txTime.Unix()),
+		})
+	}
+	page.Bookmark = metadata.GetBookmark()
+	page.FetchedCount = metadata.GetFetchedRecordsCount()
+	log.Printf("分页查询函数授权账户 - 函数: %s, 单页记录数: %d, 返回账户数量: %d", funcName, pageSize, page.FetchedCount)
+	return page, nil
+}
+
+// MigrateSelectorPermissionKeys 将旧版 permission:selectorPerm:{account} 存储键迁移为复合键并建立函数->账户索引
+// 新旧存储同时存在同一函数授权时保留复合键中的授权
+// 权限要求：只有管理员可以调用此方法
+func (c *PermissionChaincode) MigrateSelectorPermissionKeys(ctx contractapi.TransactionContextInterface) error {
+	log.Printf("开始迁移账户权限存储键")
+	cfg, err := c.checkAdminOperation(ctx, "MigrateSelectorPermissionKeys")
+	if err != nil {
+		return err
+	}
+	iterator, err := ctx.GetStub().GetStateByRange(selectorPermPrefix, selectorPermPrefix+"~")
+	if err != nil {
+		return err
+	}
+	defer iterator.Close()
+
+	accounts := make([]string, 0)
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return err
+		}
+		account := strings.TrimPrefix(kv.Key, selectorPermPrefix)
+		var legacy map[string]SelectorGrant
+		if err := json.Unmarshal(kv.Value, &legacy); err != nil {
+			log.Printf("旧版账户权限解析失败 - 账户: %s, 错误: %v", account, err)
+			return err
+		}
+		key, err := ctx.GetStub().CreateCompositeKey(selectorPermObjectType, []string{account})
+		if err != nil {
+			return err
+		}
+		b, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return err
+		}
+		merged := legacy
+		if b != nil {
+			var current map[string]SelectorGrant
+			if err := json.Unmarshal(b, &current); err != nil {
+				return err
+			}
+			for funcName, grant := range current {
+				merged[funcName] = grant
+			}
+		}
+		if err := putSelectorGrants(ctx, account, merged); err != nil {
+			log.Printf("账户权限迁移失败 - 账户: %s, 错误: %v", account, err)
+			return err
+		}
+		accounts = append(accounts, account)
+	}
+	log.Printf("账户权限存储键迁移完成 - 迁移账户数量: %d", len(accounts))
+	if err := recordAudit(ctx, "MigrateSelectorPermissionKeys", selectorPermObjectType, nil, accounts); err != nil {
+		return err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode": cfg.ServiceCode,
+		"projectCode": cfg.ProjectCode,
+		"accounts":    accounts,
+		"sender":      common.GetCaller(ctx)})
+	return common.EmitEvent(ctx, "SelectorPermissionKeysMigrated", payload)
+}
+
+// ================== 内部方法 ==================
+
+// checkPageSize 校验分页查询的单页记录数
+func checkPageSize(pageSize int32) error {
+	if pageSize <= 0 || pageSize > maxPageSize {
+		return fmt.Errorf("pageSize must be between 1 and %d", maxPageSize)
+	}
+	return nil
+}
+
+// migrateLegacySelectorGrants 内部方法：将旧格式账户（仅SKI）的直接授权迁移到新格式账户下，返回迁移账户数量
+// 同时处理旧版存储键和复合键中的数据，新格式账户已有的同名授权优先保留
+func migrateLegacySelectorGrants(ctx contractapi.TransactionContextInterface, migrate func(string) string) (int, error) {
+	legacyGrants := make(map[string]map[string]SelectorGrant)
+	collect := func(account string, value []byte) error {
+		if migrate(account) == account {
+			return nil
+		}
+		var grants map[string]SelectorGrant
+		if err := json.Unmarshal(value, &grants); err != nil {
+			return err
+		}
+		if legacyGrants[account] == nil {
+			legacyGrants[account] = make(map[string]SelectorGrant)
+		}
+		for funcName, grant := range grants {
+			legacyGrants[account][funcName] = grant
+		}
+		return nil
+	}
+
+	rangeIterator, err := ctx.GetStub().GetStateByRange(selectorPermPrefix, selectorPermPrefix+"~")
+	if err != nil {
+		return 0, err
+	}
+	defer rangeIterator.Close()
+	for rangeIterator.HasNext() {
+		kv, err := rangeIterator.Next()
+		if err != nil {
+			return 0, err
+		}
+		if err := collect(strings.TrimPrefix(kv.Key, selectorPermPrefix), kv.Value); err != nil {
+			return 0, err
+		}
+	}
+
+	compositeIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(selectorPermObjectType, []string{})
+	if err != nil {
+		return 0, err
+	}
+	defer compositeIterator.Close()
+	for compositeIterator.HasNext() {
+		kv, err := compositeIterator.Next()
+		if err != nil {
+			return 0, err
+		}
+		_, attrs, err := ctx.GetStub().SplitCompositeKey(kv.Key)
+		if err != nil || len(attrs) != 1 {
+			return 0, fmt.Errorf("invalid selector permission key: %q", kv.Key)
+		}
+		if err := collect(attrs[0], kv.Value); err != nil {
+			return 0, err
+		}
+	}
+
+	for account, grants := range legacyGrants {
+		newAccount := migrate(account)
+		merged, err := getSelectorGrants(ctx, newAccount)
+		if err != nil {
+			return 0, err
+		}
+		for funcName, grant := range grants {
+			if _, ok := merged[funcName]; !ok {
+				merged[funcName] = grant
+			}
+		}
+		if err := putSelectorGrants(ctx, newAccount, merged); err != nil {
+			return 0, err
+		}
+		if err := putSelectorGrants(ctx, account, nil); err != nil {
+			return 0, err
+		}
+	}
+	return len(legacyGrants), nil
+}
+
+// UnmarshalJSON 兼容旧格式的权限映射（map[string]bool），旧格式的授权视为永久有效
+func (g *SelectorGrant) UnmarshalJSON(b []byte) error {
+	var legacy bool
+	if err := json.Unmarshal(b, &legacy); err == nil {
+		*g = SelectorGrant{}
+		return nil
+	}
+	type grantAlias SelectorGrant
+	var grant grantAlias
+	if err := json.Unmarshal(b, &grant); err != nil {
+		return err
+	}
+	*g = SelectorGrant(grant)
+	return nil
+}
+
+// status 根据交易时间（Unix秒）判断授权状态
+func (g SelectorGrant) status(now int64) string {
+	if g.ValidFrom != 0 && now < g.ValidFrom {
+		return grantStatusPending
+	}
+	if g.ValidUntil != 0 && now >= g.ValidUntil {
+		return grantStatusExpired
+	}
+	return grantStatusActive
+}
+
+// validateGrantBounds 校验授权有效期参数
+func validateGrantBounds(validFrom, validUntil, now int64) error {
+	if validFrom < 0 || validUntil < 0 {
+		return errors.New("validFrom and validUntil cannot be negative")
+	}
+	if validUntil == 0 {
+		return nil
+	}
+	if validFrom != 0 && validUntil <= validFrom {
+		return errors.New("validUntil must be greater than validFrom")
+	}
+	if validUntil <= now {
+		return errors.New("validUntil must be later than the transaction time")
+	}
+	return nil
+}
+
+// getSelectorGrants 内部方法：获取账户直接授权的函数权限及其有效期
+// 复合键不存在时兼容读取旧版 permission:selectorPerm:{account} 存储键
+func getSelectorGrants(ctx contractapi.TransactionContextInterface, account string) (map[string]SelectorGrant, error) {
+	grants := make(map[string]SelectorGrant)
+	key, err := ctx.GetStub().CreateCompositeKey(selectorPermObjectType, []string{account})
+	if err != nil {
+		return nil, err
+	}
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		if b, err = ctx.GetStub().GetState(selectorPermPrefix + account); err != nil {
+			return nil, err
+		}
+	}
+	if b == nil {
+		return grants, nil
+	}
+	if err := json.Unmarshal(b, &grants); err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// putSelectorGrants 内部方法：存储账户直接授权的函数权限并同步函数->账户索引，为空时删除该账户的权限
+// 存储时同时删除旧版存储键，完成该账户的存储迁移
+func putSelectorGrants(ctx contractapi.TransactionContextInterface, account string, grants map[string]SelectorGrant) error {
+	stub := ctx.GetStub()
+	old, err := getSelectorGrants(ctx, account)
+	if err != nil {
+		return err
+	}
+	for funcName := range old {
+		if _, ok := grants[funcName]; ok {
+			continue
+		}
+		indexKey, err := stub.CreateCompositeKey(selectorIndexObjectType, []string{funcName, account})
+		if err != nil {
+			return err
+		}
+		if err := stub.DelState(indexKey); err != nil {
+			return err
+		}
+	}
+	for funcName, grant := range grants {
+		indexKey, err := stub.CreateCompositeKey(selectorIndexObjectType, []string{funcName, account})
+		if err != nil {
+			return err
+		}
+		b, _ := json.Marshal(grant)
+		if err := stub.PutState(indexKey, b); err != nil {
+			return err
+		}
+	}
+
+	if err := stub.DelState(selectorPermPrefix + account); err != nil {
+		return err
+	}
+	key, err := stub.CreateCompositeKey(selectorPermObjectType, []string{account})
+	if err != nil {
+		return err
+	}
+	if len(grants) == 0 {
+		return stub.DelState(key)
+	}
+	b, _ := json.Marshal(grants)
+	return stub.PutState(key, b)
+}
+
+// copyGrants 复制授权记录映射，用于记录变更前的值
+func copyGrants(grants map[string]SelectorGrant) map[string]SelectorGrant {
+	copied := make(map[string]SelectorGrant, len(grants))
+	for funcName, grant := range grants {
+		copied[funcName] = grant
+	}
+	return copied
+}
+
+// getActiveSelectorGrants 内部方法：获取账户在当前交易时间生效的直接授权函数
+func getActiveSelectorGrants(ctx contractapi.TransactionContextInterface, account string) (map[string]bool, error) {
+	grants, err := getSelectorGrants(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	selectors := make(map[string]bool, len(grants))
+	if len(grants) == 0 {
+		return selectors, nil
+	}
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := txTime.Unix()
+	for funcName, grant := range grants {
+		if grant.status(now) == grantStatusActive {
+			selectors[funcName] = true
+		}
+	}
+	return selectors, nil
+}
+
+// grantInfos 将授权记录转换为按函数名排序的查询结果
+func grantInfos(grants map[string]SelectorGrant, now int64) []SelectorGrantInfo {
+	infos := make([]SelectorGrantInfo, 0, len(grants))
+	for funcName, grant := range grants {
+		infos = append(infos, SelectorGrantInfo{
+			FuncName:   funcName,
+			ValidFrom:  grant.ValidFrom,
+			ValidUntil: grant.ValidUntil,
+			Status:     grant.status(now),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].FuncName < infos[j].FuncName })
+	return infos
+}
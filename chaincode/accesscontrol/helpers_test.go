@@ -0,0 +1,76 @@
+package accesscontrol_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"sbp-did-chaincode/accesscontrol"
+	"sbp-did-chaincode/internal/testutil"
+
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+)
+
+// testAccount 测试账户：证书序列化身份及账户标识
+type testAccount struct {
+	creator []byte
+	account string
+}
+
+// permissionTest 在同一模拟存根上以不同账户依次执行Permission合约交易
+type permissionTest struct {
+	t      *testing.T
+	stub   *shimtest.MockStub
+	ledger *testutil.LedgerStub // 交易使用的存根，支持历史查询和分页查询
+	cc     *accesscontrol.PermissionChaincode
+	now    time.Time
+	txs    int
+}
+
+func newPermissionTest(t *testing.T) *permissionTest {
+	stub, _ := testutil.NewMockStub(t, nil, "Org1MSP")
+	return &permissionTest{
+		t:      t,
+		stub:   stub,
+		ledger: testutil.NewLedgerStub(stub),
+		cc:     new(accesscontrol.PermissionChaincode),
+		now:    time.Unix(1700000000, 0),
+	}
+}
+
+func (p *permissionTest) newAccount() testAccount {
+	return p.newAccountWithAttributes("Org1MSP", nil)
+}
+
+// newAccountWithAttributes 创建mspID下证书包含attrs属性的账户
+func (p *permissionTest) newAccountWithAttributes(mspID string, attrs map[string]string) testAccount {
+	account := testutil.SetCallerWithAttributes(p.t, p.stub, mspID, attrs)
+	return testAccount{creator: p.stub.Creator, account: account}
+}
+
+// as 以指定账户开始一个新交易，交易时间戳为nextTxTime
+func (p *permissionTest) as(a testAccount) *accesscontrol.TransactionContext {
+	return p.at(a, p.nextTxTime())
+}
+
+// at 以指定账户开始一个交易时间戳为t的新交易
+func (p *permissionTest) at(a testAccount, t time.Time) *accesscontrol.TransactionContext {
+	p.txs++
+	p.stub.Creator = a.creator
+	testutil.StartTx(p.stub, "tx"+strconv.Itoa(p.txs), t)
+	ctx := accesscontrol.NewTransactionContext(nil)
+	ctx.SetStub(p.ledger)
+	return ctx
+}
+
+// nextTxTime 下一个交易的时间戳，每个交易比上一个晚一分钟
+func (p *permissionTest) nextTxTime() time.Time {
+	return p.now.Add(time.Duration(p.txs+1) * time.Minute)
+}
+
+func (p *permissionTest) must(err error) {
+	p.t.Helper()
+	if err != nil {
+		p.t.Fatal(err)
+	}
+}
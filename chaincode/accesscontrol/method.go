@@ -0,0 +1,170 @@
+package accesscontrol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ================== 多DID method相关 ==================
+
+// EnableMethod 启用DID method，method不在项目允许集合中时加入集合
+// 用于与主method并行运行新method，或在迁移期间同时接受新旧method
+// 开启多管理员审批后需通过CreateConfigProposal发起
+func (c *PermissionChaincode) EnableMethod(ctx contractapi.TransactionContextInterface, method string) error {
+	log.Printf("开始启用DID method - method: %s", method)
+	return c.operateMethodStatus(ctx, method, true)
+}
+
+// DisableMethod 停用DID method，停用后该method的DID不能再注册或更新；主method不能停用，需先通过ChangeMethod更换
+// 开启多管理员审批后需通过CreateConfigProposal发起
+func (c *PermissionChaincode) DisableMethod(ctx contractapi.TransactionContextInterface, method string) error {
+	log.Printf("开始停用DID method - method: %s", method)
+	return c.operateMethodStatus(ctx, method, false)
+}
+
+// GetMethods 查询项目允许的所有DID method及其启用状态，第一项为主method
+func (c *PermissionChaincode) GetMethods(ctx contractapi.TransactionContextInterface) ([]common.DIDMethod, error) {
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Paused {
+		return nil, errors.New("project is paused")
+	}
+	methods := make([]common.DIDMethod, 0, len(cfg.Methods)+1)
+	methods = append(methods, common.DIDMethod{Name: cfg.Method, Enabled: true})
+	for _, method := range cfg.Methods {
+		if method.Name != cfg.Method {
+			methods = append(methods, method)
+		}
+	}
+	return methods, nil
+}
+
+// ================== 内部方法 ==================
+
+// operateMethodStatus 内部方法：校验管理员身份后启用/停用DID method
+func (c *PermissionChaincode) operateMethodStatus(ctx contractapi.TransactionContextInterface, method string, enabled bool) error {
+	operation, op := "DisableMethod", ProposalOpDisableMethod
+	if enabled {
+		operation, op = "EnableMethod", ProposalOpEnableMethod
+	}
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		log.Printf("获取项目配置失败: %v", err)
+		return err
+	}
+	if !common.IsAdmin(ctx, cfg.Admins) {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: %s", common.GetCaller(ctx), operation)
+		return fmt.Errorf("only admin can call %s", operation)
+	}
+	if err := checkNotFrozen(ctx, common.GetCaller(ctx)); err != nil {
+		return err
+	}
+	log.Printf("权限校验通过 - 调用者: %s, 操作: %s", common.GetCaller(ctx), operation)
+	if err := checkSingleAdminOperation(cfg, op); err != nil {
+		return err
+	}
+
+	eventName, payload, err := c.applyMethodStatus(ctx, cfg, method, enabled)
+	if err != nil {
+		return err
+	}
+	return common.EmitEvent(ctx, eventName, payload)
+}
+
+// applyMethodStatus 内部方法：校验并更新DID method启用状态，返回待发送的事件
+func (c *PermissionChaincode) applyMethodStatus(ctx contractapi.TransactionContextInterface, cfg *common.ProjectConfig, method string, enabled bool) (string, []byte, error) {
+	if cfg.Paused {
+		log.Printf("项目状态校验失败 - 项目已停用")
+		return "", nil, errors.New("project is paused")
+	}
+	if err := common.ValidateDIDMethodName(method); err != nil {
+		log.Printf("参数校验失败 - %v", err)
+		return "", nil, err
+	}
+	if method == cfg.Method {
+		log.Printf("状态校验失败 - %s为主method", method)
+		return "", nil, fmt.Errorf("%s is the primary method and is always enabled, use ChangeMethod to replace it", method)
+	}
+
+	oldMethods := append([]common.DIDMethod(nil), cfg.Methods...)
+	found := false
+	for i := range cfg.Methods {
+		if cfg.Methods[i].Name != method {
+			continue
+		}
+		if cfg.Methods[i].Enabled == enabled {
+			log.Printf("状态校验失败 - method状态已相同: %s, 启用: %t", method, enabled)
+			return "", nil, errors.New("method status is already the same")
+		}
+		cfg.Methods[i].Enabled = enabled
+		found = true
+	}
+	if !found {
+		if !enabled {
+			log.Printf("状态校验失败 - method不在允许集合中: %s", method)
+			return "", nil, fmt.Errorf("method %s is not allowed in project", method)
+		}
+		cfg.Methods = append(cfg.Methods, common.DIDMethod{Name: method, Enabled: true})
+	}
+	log.Printf("项目配置更新 - method: %s, 启用: %t", method, enabled)
+	if err := putProjectConfig(ctx, cfg); err != nil {
+		log.Printf("项目配置更新存储失败: %v", err)
+		return "", nil, err
+	}
+	log.Printf("项目配置更新存储成功")
+	operation := ProposalOpDisableMethod
+	if enabled {
+		operation = ProposalOpEnableMethod
+	}
+	if err := recordAudit(ctx, operation, "methods", oldMethods, cfg.Methods); err != nil {
+		return "", nil, err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode": cfg.ServiceCode,
+		"projectCode": cfg.ProjectCode,
+		"method":      method,
+		"enabled":     enabled,
+		"methods":     cfg.Methods,
+		"sender":      common.GetCaller(ctx)})
+	if enabled {
+		return "MethodEnabled", payload, nil
+	}
+	return "MethodDisabled", payload, nil
+}
+
+// checkMethodAllowed 校验DID method为主method或已启用的method
+func checkMethodAllowed(cfg *common.ProjectConfig, method string) error {
+	if method == cfg.Method {
+		return nil
+	}
+	for _, m := range cfg.Methods {
+		if m.Name != method {
+			continue
+		}
+		if !m.Enabled {
+			return fmt.Errorf("did method '%s' is disabled in project", method)
+		}
+		return nil
+	}
+	return fmt.Errorf("did method '%s' is not allowed in project, primary method is '%s'", method, cfg.Method)
+}
+
+// removeMethod 从method集合中移除指定method
+func removeMethod(methods []common.DIDMethod, name string) []common.DIDMethod {
+	result := make([]common.DIDMethod, 0, len(methods))
+	for _, m := range methods {
+		if m.Name != name {
+			result = append(result, m)
+		}
+	}
+	return result
+}
@@ -0,0 +1,291 @@
+package accesscontrol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// OrgSelector 组织权限结构体
+// 组织级授权对该MSP下的所有成员证书生效
+type OrgSelector struct {
+	MspId     string   // 组织MSP ID，如 Org2MSP
+	FuncNames []string // 函数名列表，为空时删除该组织的所有权限
+	IsRevoke  bool     `metadata:",optional"` // 操作模式：false为授权，true为撤销FuncNames中列出的函数权限
+}
+
+const (
+	// orgSelectorPermPrefix 组织权限在链上的存储键前缀
+	// 格式：permission:orgSelectorPerm:{mspId}
+	orgSelectorPermPrefix = "permission:orgSelectorPerm:"
+)
+
+// ================== 组织权限相关 ==================
+
+// BatchOperateOrgSelectorPermissions 批量授权/撤销组织权限
+// 权限要求：只有管理员可以调用此方法
+func (c *PermissionChaincode) BatchOperateOrgSelectorPermissions(ctx contractapi.TransactionContextInterface, orgSelectors []OrgSelector) error {
+	log.Printf("开始批量操作组织权限 - 组织数量: %d", len(orgSelectors))
+	cfg, err := c.checkAdminOperation(ctx, "BatchOperateOrgSelectorPermissions")
+	if err != nil {
+		return err
+	}
+
+	for i, perm := range orgSelectors {
+		log.Printf("处理第%d个组织权限 - 组织: %s, 函数数量: %d, 撤销模式: %t", i+1, perm.MspId, len(perm.FuncNames), perm.IsRevoke)
+		if strings.TrimSpace(perm.MspId) == "" {
+			log.Printf("参数校验失败 - 组织MSP ID为空")
+			return errors.New("mspId cannot be empty")
+		}
+		key := orgSelectorPermPrefix + perm.MspId
+		selectorMap, err := getStringSet(ctx, key)
+		if err != nil {
+			return err
+		}
+		oldSelectors := sortedKeys(selectorMap)
+
+		action := "update"
+		operations := make([]SelectorOperation, 0, len(perm.FuncNames))
+		if len(perm.FuncNames) == 0 {
+			action = "deleteAll"
+			selectorMap = map[string]bool{}
+		} else {
+			if perm.IsRevoke {
+				action = "revoke"
+			}
+			for _, funcName := range perm.FuncNames {
+				if strings.TrimSpace(funcName) == "" {
+					log.Printf("参数校验失败 - 组织: %s, 函数名为空", perm.MspId)
+					return errors.New("funcName cannot be empty")
+				}
+				if perm.IsRevoke {
+					if !selectorMap[funcName] {
+						log.Printf("参数校验失败 - 组织: %s 未被授权函数: %s", perm.MspId, funcName)
+						return fmt.Errorf("unknown selector %s for org %s", funcName, perm.MspId)
+					}
+					delete(selectorMap, funcName)
+					operations = append(operations, SelectorOperation{FuncName: funcName, Action: selectorActionRevoke})
+				} else {
+					if err := validateSelector(funcName); err != nil {
+						log.Printf("参数校验失败 - 组织: %s, 错误: %v", perm.MspId, err)
+						return err
+					}
+					selectorMap[funcName] = true
+					operations = append(operations, SelectorOperation{FuncName: funcName, Action: selectorActionGrant})
+				}
+			}
+		}
+		if err := putStringSet(ctx, key, selectorMap); err != nil {
+			log.Printf("组织权限存储失败 - 组织: %s, 错误: %v", perm.MspId, err)
+			return err
+		}
+		log.Printf("更新组织权限 - 组织: %s, 操作: %s, 剩余权限数量: %d", perm.MspId, action, len(selectorMap))
+		if err := recordAudit(ctx, "BatchOperateOrgSelectorPermissions", perm.MspId, oldSelectors, sortedKeys(selectorMap)); err != nil {
+			return err
+		}
+
+		payload, _ := json.Marshal(map[string]interface{}{
+			"serviceCode": cfg.ServiceCode,
+			"projectCode": cfg.ProjectCode,
+			"mspId":       perm.MspId,
+			"funcNames":   perm.FuncNames,
+			"selectors":   operations,
+			"action":      action,
+			"isRevoked":   perm.IsRevoke || action == "deleteAll"})
+		_ = common.EmitEvent(ctx, "OrgSelectorPermissionOperated", payload)
+	}
+	log.Printf("批量操作组织权限完成 - 处理组织数量: %d", len(orgSelectors))
+	return nil
+}
+
+// GetAllSelectorsForOrg 查询组织所有函数权限
+func (c *PermissionChaincode) GetAllSelectorsForOrg(ctx contractapi.TransactionContextInterface, mspId string) ([]string, error) {
+	if strings.TrimSpace(mspId) == "" {
+		return nil, errors.New("mspId cannot be empty")
+	}
+	if err := c.checkNotPaused(ctx); err != nil {
+		return nil, err
+	}
+	selectorMap, err := getStringSet(ctx, orgSelectorPermPrefix+mspId)
+	if err != nil {
+		return nil, err
+	}
+	return sortedKeys(selectorMap), nil
+}
+
+// GetCallerAccount 查询当前调用者的账户标识（{mspId}:{ski}），用于授权和管理员提名
+func (c *PermissionChaincode) GetCallerAccount(ctx contractapi.TransactionContextInterface) (string, error) {
+	return common.GetCaller(ctx), nil
+}
+
+// MigrateLegacyAccounts 将仅包含SKI的旧格式账户标识迁移为 {mspId}:{ski} 格式
+// 迁移范围：管理员列表、待接受的管理员提名、账户函数权限、账户角色分配
+// 参数说明：
+// - mspId: 旧数据所属组织的MSP ID
+//
+// 权限要求：只有管理员可以调用此方法
+func (c *PermissionChaincode) MigrateLegacyAccounts(ctx contractapi.TransactionContextInterface, mspId string) error {
+	log.Printf("开始迁移旧格式账户标识 - 组织: %s", mspId)
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		log.Printf("获取项目配置失败: %v", err)
+		return err
+	}
+	if !common.IsAdmin(ctx, cfg.Admins) {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: MigrateLegacyAccounts", common.GetCaller(ctx))
+		return errors.New("only admin can migrate legacy accounts")
+	}
+	if err := checkNotFrozen(ctx, common.GetCaller(ctx)); err != nil {
+		return err
+	}
+	if strings.TrimSpace(mspId) == "" || strings.Contains(mspId, ":") {
+		log.Printf("参数校验失败 - 组织MSP ID无效: %s", mspId)
+		return errors.New("invalid mspId")
+	}
+	migrate := func(account string) string {
+		if account == "" || !common.IsLegacyAccount(account) {
+			return account
+		}
+		return common.FormatAccount(mspId, account)
+	}
+
+	// 1. 管理员列表
+	oldAdmins := cfg.Admins
+	admins := make([]string, 0, len(cfg.Admins))
+	for _, admin := range cfg.Admins {
+		if migrated := migrate(admin); !containsString(admins, migrated) {
+			admins = append(admins, migrated)
+		}
+	}
+	cfg.Admins = admins
+	if err := putProjectConfig(ctx, cfg); err != nil {
+		log.Printf("项目配置更新存储失败: %v", err)
+		return err
+	}
+
+	// 2. 待接受的管理员提名
+	pending, err := c.getPendingAdmins(ctx)
+	if err != nil {
+		return err
+	}
+	migratedPending := make(map[string]PendingAdmin, len(pending))
+	for _, nomination := range pending {
+		nomination.Account = migrate(nomination.Account)
+		nomination.ProposedBy = migrate(nomination.ProposedBy)
+		nomination.ReplaceAdmin = migrate(nomination.ReplaceAdmin)
+		migratedPending[nomination.Account] = nomination
+	}
+	if err := c.putPendingAdmins(ctx, migratedPending); err != nil {
+		return err
+	}
+
+	// 3. 账户函数权限
+	selectorCount, err := migrateLegacySelectorGrants(ctx, migrate)
+	if err != nil {
+		log.Printf("账户函数权限迁移失败: %v", err)
+		return err
+	}
+
+	// 4. 账户角色分配，同时更新角色成员索引
+	roleCount, err := migrateLegacyKeys(ctx, accountRolePrefix, migrate, func(oldAccount, newAccount string, value []byte) error {
+		var roles map[string]bool
+		if err := json.Unmarshal(value, &roles); err != nil {
+			return err
+		}
+		for roleName := range roles {
+			members, err := getStringSet(ctx, roleMemberPrefix+roleName)
+			if err != nil {
+				return err
+			}
+			delete(members, oldAccount)
+			members[newAccount] = true
+			if err := putStringSet(ctx, roleMemberPrefix+roleName, members); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("账户角色分配迁移失败: %v", err)
+		return err
+	}
+	log.Printf("旧格式账户标识迁移完成 - 管理员: %v, 账户权限数量: %d, 账户角色数量: %d", cfg.Admins, selectorCount, roleCount)
+	newValue := map[string]interface{}{"admins": cfg.Admins, "selectorCount": selectorCount, "roleCount": roleCount}
+	if err := recordAudit(ctx, "MigrateLegacyAccounts", mspId, map[string]interface{}{"admins": oldAdmins}, newValue); err != nil {
+		return err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode":   cfg.ServiceCode,
+		"projectCode":   cfg.ProjectCode,
+		"mspId":         mspId,
+		"admins":        cfg.Admins,
+		"selectorCount": selectorCount,
+		"roleCount":     roleCount,
+		"sender":        common.GetCaller(ctx)})
+	return common.EmitEvent(ctx, "LegacyAccountsMigrated", payload)
+}
+
+// ================== 内部方法 ==================
+
+// migrateLegacyKeys 将 {prefix}{旧格式账户} 的映射存储键迁移为 {prefix}{新格式账户}，返回迁移数量
+func migrateLegacyKeys(ctx contractapi.TransactionContextInterface, prefix string, migrate func(string) string, onMigrate func(oldAccount, newAccount string, value []byte) error) (int, error) {
+	iterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+	if err != nil {
+		return 0, err
+	}
+	defer iterator.Close()
+
+	count := 0
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return count, err
+		}
+		account := strings.TrimPrefix(kv.Key, prefix)
+		newAccount := migrate(account)
+		if newAccount == account {
+			continue
+		}
+		// 新旧格式的键同时存在时合并两者的映射，已存在的新格式条目优先
+		merged := make(map[string]json.RawMessage)
+		b, err := ctx.GetStub().GetState(prefix + newAccount)
+		if err != nil {
+			return count, err
+		}
+		if b != nil {
+			if err := json.Unmarshal(b, &merged); err != nil {
+				return count, err
+			}
+		}
+		var legacy map[string]json.RawMessage
+		if err := json.Unmarshal(kv.Value, &legacy); err != nil {
+			return count, err
+		}
+		for item, value := range legacy {
+			if _, ok := merged[item]; !ok {
+				merged[item] = value
+			}
+		}
+		b, _ = json.Marshal(merged)
+		if err := ctx.GetStub().PutState(prefix+newAccount, b); err != nil {
+			return count, err
+		}
+		if err := ctx.GetStub().DelState(kv.Key); err != nil {
+			return count, err
+		}
+		if onMigrate != nil {
+			if err := onMigrate(account, newAccount, kv.Value); err != nil {
+				return count, err
+			}
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,185 @@
+package accesscontrol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	// pauseDirectionAll 同时停用/启用读写两个方向
+	pauseDirectionAll = "all"
+)
+
+// pausableModules 可按模块停用的业务模块
+var pausableModules = map[string]bool{
+	common.ModuleDID:      true,
+	common.ModuleIssuer:   true,
+	common.ModuleTemplate: true,
+	common.ModuleVC:       true,
+}
+
+// ================== 模块停用相关 ==================
+
+// PauseScope 按模块和读写方向停用
+// 参数说明：
+// - module: 业务模块，did/issuer/template/vc
+// - direction: 读写方向，read/write/all
+//
+// 例如停用VC存证：PauseScope("vc", "write")；停用DID注册与更新但保留解析：PauseScope("did", "write")
+// 开启多管理员审批后需通过CreateConfigProposal发起，提案参数格式为 {module}:{direction}
+func (c *PermissionChaincode) PauseScope(ctx contractapi.TransactionContextInterface, module, direction string) error {
+	log.Printf("开始停用模块 - 模块: %s, 方向: %s", module, direction)
+	return c.operatePauseScope(ctx, module, direction, true)
+}
+
+// UnpauseScope 按模块和读写方向启用
+// 开启多管理员审批后需通过CreateConfigProposal发起，提案参数格式为 {module}:{direction}
+func (c *PermissionChaincode) UnpauseScope(ctx contractapi.TransactionContextInterface, module, direction string) error {
+	log.Printf("开始启用模块 - 模块: %s, 方向: %s", module, direction)
+	return c.operatePauseScope(ctx, module, direction, false)
+}
+
+// GetPausedScopes 查询已停用的模块范围，项目整体停用时仍可查询
+func (c *PermissionChaincode) GetPausedScopes(ctx contractapi.TransactionContextInterface) ([]string, error) {
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.PausedScopes == nil {
+		return []string{}, nil
+	}
+	return cfg.PausedScopes, nil
+}
+
+// ================== 内部方法 ==================
+
+// operatePauseScope 内部方法：校验管理员身份后停用/启用模块范围
+func (c *PermissionChaincode) operatePauseScope(ctx contractapi.TransactionContextInterface, module, direction string, paused bool) error {
+	operation, op := "UnpauseScope", ProposalOpUnpauseScope
+	if paused {
+		operation, op = "PauseScope", ProposalOpPauseScope
+	}
+	scopes, err := parsePauseScope(module, direction)
+	if err != nil {
+		log.Printf("参数校验失败 - %v", err)
+		return err
+	}
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		log.Printf("获取项目配置失败: %v", err)
+		return err
+	}
+	if !common.IsAdmin(ctx, cfg.Admins) {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: %s", common.GetCaller(ctx), operation)
+		return fmt.Errorf("only admin can call %s", operation)
+	}
+	if err := checkNotFrozen(ctx, common.GetCaller(ctx)); err != nil {
+		return err
+	}
+	log.Printf("权限校验通过 - 调用者: %s, 操作: %s", common.GetCaller(ctx), operation)
+	if err := checkSingleAdminOperation(cfg, op); err != nil {
+		return err
+	}
+
+	eventName, payload, err := c.applyPauseScope(ctx, cfg, module, direction, scopes, paused)
+	if err != nil {
+		return err
+	}
+	return common.EmitEvent(ctx, eventName, payload)
+}
+
+// applyPauseScope 内部方法：更新模块停用范围，返回待发送的事件
+func (c *PermissionChaincode) applyPauseScope(ctx contractapi.TransactionContextInterface, cfg *common.ProjectConfig, module, direction string, scopes []string, paused bool) (string, []byte, error) {
+	oldScopes := cfg.PausedScopes
+	pausedScopes := make([]string, 0, len(cfg.PausedScopes)+len(scopes))
+	for _, scope := range cfg.PausedScopes {
+		if !containsString(scopes, scope) {
+			pausedScopes = append(pausedScopes, scope)
+		}
+	}
+	if paused {
+		pausedScopes = append(pausedScopes, scopes...)
+	}
+	cfg.PausedScopes = pausedScopes
+	log.Printf("项目配置更新 - 停用范围: %v", pausedScopes)
+	if err := putProjectConfig(ctx, cfg); err != nil {
+		log.Printf("项目配置更新存储失败: %v", err)
+		return "", nil, err
+	}
+	log.Printf("项目配置更新存储成功")
+	operation := ProposalOpUnpauseScope
+	if paused {
+		operation = ProposalOpPauseScope
+	}
+	if err := recordAudit(ctx, operation, "pausedScopes", oldScopes, pausedScopes); err != nil {
+		return "", nil, err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode":  cfg.ServiceCode,
+		"projectCode":  cfg.ProjectCode,
+		"module":       module,
+		"direction":    direction,
+		"scopes":       scopes,
+		"pausedScopes": pausedScopes,
+		"sender":       common.GetCaller(ctx)})
+	if paused {
+		return "ScopePaused", payload, nil
+	}
+	return "ScopeUnpaused", payload, nil
+}
+
+// parsePauseScope 校验模块和读写方向，返回对应的停用范围列表
+func parsePauseScope(module, direction string) ([]string, error) {
+	if !pausableModules[module] {
+		return nil, fmt.Errorf("unknown module %s, expected one of did/issuer/template/vc", module)
+	}
+	switch direction {
+	case common.DirectionRead, common.DirectionWrite:
+		return []string{formatPauseScope(module, direction)}, nil
+	case pauseDirectionAll:
+		return []string{formatPauseScope(module, common.DirectionRead), formatPauseScope(module, common.DirectionWrite)}, nil
+	default:
+		return nil, fmt.Errorf("invalid direction %s, expected read/write/all", direction)
+	}
+}
+
+// parsePauseScopeValue 解析提案参数 {module}:{direction}
+func parsePauseScopeValue(value string) (module, direction string, scopes []string, err error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return "", "", nil, errors.New("invalid scope, expected format: {module}:{direction}")
+	}
+	scopes, err = parsePauseScope(parts[0], parts[1])
+	return parts[0], parts[1], scopes, err
+}
+
+// formatPauseScope 组成停用范围标识 {module}:{direction}
+func formatPauseScope(module, direction string) string {
+	return module + ":" + direction
+}
+
+// checkScopeNotPaused 校验模块在指定读写方向上未被停用
+func checkScopeNotPaused(cfg *common.ProjectConfig, module, direction string) error {
+	if containsString(cfg.PausedScopes, formatPauseScope(module, direction)) {
+		log.Printf("模块状态校验失败 - 模块: %s, 方向: %s 已停用", module, direction)
+		return fmt.Errorf("%s %s is paused", module, direction)
+	}
+	return nil
+}
+
+// checkSelectorNotPaused 根据函数选择器所属模块校验其读写方向未被停用
+func checkSelectorNotPaused(cfg *common.ProjectConfig, funcName, direction string) error {
+	info := lookupSelector(funcName)
+	if info == nil || !pausableModules[info.Module] {
+		return nil
+	}
+	return checkScopeNotPaused(cfg, info.Module, direction)
+}
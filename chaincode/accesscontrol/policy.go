@@ -0,0 +1,209 @@
+package accesscontrol
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// TransactionPolicy 业务合约函数的调用前权限策略
+// 读写类型及所属模块取自Selector在函数选择器登记表中的登记信息
+type TransactionPolicy struct {
+	Contract                    string `json:"contract"`                    // 所属合约：did/issuer/vc
+	Function                    string `json:"function"`                    // 合约函数名
+	Selector                    string `json:"selector"`                    // 权限校验使用的函数选择器，必须已在函数选择器登记表中登记
+	Type                        string `json:"type"`                        // 读写类型：read/write，取自函数选择器登记表
+	RequireIssuerVerification   bool   `json:"requireIssuerVerification"`   // 开启发证方审核后只有管理员可以调用
+	RequireTemplateVerification bool   `json:"requireTemplateVerification"` // 开启VC模板审核后只有管理员可以调用
+	MethodArg                   int    `json:"methodArg"`                   // 需要校验DID method的参数位置（从1开始），0表示不校验
+}
+
+// transactionPolicies 业务合约函数策略表，按合约和函数名索引
+// 合约的每个交易函数都必须登记策略，未登记的函数在BeforeTransaction中直接拒绝
+var transactionPolicies = []TransactionPolicy{
+	// did合约
+	{Contract: "did", Function: "RegisterDid", Selector: "RegisterDid", MethodArg: 1},
+	{Contract: "did", Function: "UpdateDidDocument", Selector: "UpdateDidDocument", MethodArg: 1},
+	{Contract: "did", Function: "UpdateDidDocumentWithSignature", Selector: "UpdateDidDocumentWithSignature", MethodArg: 1},
+	{Contract: "did", Function: "DeactivateDid", Selector: "DeactivateDid"},
+	{Contract: "did", Function: "GetDidInfo", Selector: "GetDidInfo"},
+	{Contract: "did", Function: "ResolveDid", Selector: "ResolveDid"},
+	{Contract: "did", Function: "ResolveDidAt", Selector: "ResolveDidAt"},
+	{Contract: "did", Function: "GetDidHistory", Selector: "GetDidHistory"},
+	{Contract: "did", Function: "GetDidNonce", Selector: "GetDidNonce"},
+	{Contract: "did", Function: "CheckDid", Selector: "CheckDid"},
+
+	// issuer合约
+	{Contract: "issuer", Function: "CheckDid", Selector: "CheckDid"},
+	{Contract: "issuer", Function: "RegisterIssuer", Selector: "RegisterIssuer", RequireIssuerVerification: true, MethodArg: 1},
+	{Contract: "issuer", Function: "UpdateIssuer", Selector: "UpdateIssuer", RequireIssuerVerification: true},
+	{Contract: "issuer", Function: "ChangeIssuerStatus", Selector: "ChangeIssuerStatus", RequireIssuerVerification: true},
+	{Contract: "issuer", Function: "GetIssuerInfo", Selector: "GetIssuerInfo"},
+	{Contract: "issuer", Function: "CheckIssuer", Selector: "CheckIssuer"},
+	{Contract: "issuer", Function: "RegisterVCTemplate", Selector: "RegisterVCTemplate", RequireTemplateVerification: true},
+	{Contract: "issuer", Function: "UpdateVCTemplate", Selector: "UpdateVCTemplate", RequireTemplateVerification: true},
+	{Contract: "issuer", Function: "ChangeVCTemplateStatus", Selector: "ChangeVCTemplateStatus", RequireTemplateVerification: true},
+	{Contract: "issuer", Function: "GetVCTemplateInfo", Selector: "GetVCTemplateInfo"},
+	{Contract: "issuer", Function: "CheckVCTemplate", Selector: "CheckVCTemplate"},
+
+	// vc合约
+	{Contract: "vc", Function: "CheckIssuer", Selector: "CheckIssuer"},
+	{Contract: "vc", Function: "StoreVCHash", Selector: "StoreVCHash"},
+	{Contract: "vc", Function: "RevokedVC", Selector: "RevokedVC"},
+	{Contract: "vc", Function: "GetVCInfo", Selector: "GetVCInfo"},
+	{Contract: "vc", Function: "GetVCRevokedStatus", Selector: "GetVCRevokedStatus"},
+}
+
+func init() {
+	// 策略表引用的函数选择器必须已登记，读写类型以登记表为准
+	for i := range transactionPolicies {
+		info := lookupSelector(transactionPolicies[i].Selector)
+		if info == nil {
+			panic(fmt.Sprintf("selector %s of %s:%s is not registered", transactionPolicies[i].Selector,
+				transactionPolicies[i].Contract, transactionPolicies[i].Function))
+		}
+		transactionPolicies[i].Type = info.Type
+	}
+}
+
+// ================== 交易函数策略相关 ==================
+
+// GetTransactionPolicies 查询业务合约函数的调用前权限策略
+// 参数说明：
+// - contract: 合约名称（did/issuer/vc），为空时返回所有合约的策略
+func (c *PermissionChaincode) GetTransactionPolicies(ctx contractapi.TransactionContextInterface, contract string) ([]TransactionPolicy, error) {
+	policies := make([]TransactionPolicy, 0, len(transactionPolicies))
+	for _, policy := range transactionPolicies {
+		if contract == "" || policy.Contract == contract {
+			policies = append(policies, policy)
+		}
+	}
+	if contract != "" && len(policies) == 0 {
+		return nil, fmt.Errorf("unknown contract %s", contract)
+	}
+	return policies, nil
+}
+
+// BeforeTransaction 返回业务合约的BeforeTransaction：先根据transient字段projectCode选择项目，
+// 再按策略表校验调用者权限，校验全部在函数执行前完成，业务函数中不再重复校验
+func BeforeTransaction(contract string) func(ctx common.TransactionContextInterface) error {
+	return func(ctx common.TransactionContextInterface) error {
+		if err := common.SelectProject(ctx); err != nil {
+			return err
+		}
+		return enforcePolicy(ctx, contract)
+	}
+}
+
+// BeforeTransactionWithChecker 与BeforeTransaction相同，但本次交易使用指定的权限检查器
+// contractapi为每个交易新建上下文，构造上下文时传入的检查器不会保留，需要替换检查器（如测试注入伪造实现）时
+// 通过该函数在交易开始时设置；上下文需提供SetPermissionChecker方法
+func BeforeTransactionWithChecker(contract string, checker common.PermissionChecker) func(ctx common.TransactionContextInterface) error {
+	before := BeforeTransaction(contract)
+	return func(ctx common.TransactionContextInterface) error {
+		setter, ok := ctx.(interface {
+			SetPermissionChecker(checker common.PermissionChecker)
+		})
+		if !ok {
+			return errors.New("transaction context does not support setting the permission checker")
+		}
+		setter.SetPermissionChecker(checker)
+		return before(ctx)
+	}
+}
+
+// ================== 内部方法 ==================
+
+// enforcePolicy 内部方法：按策略表校验本次交易调用的函数
+// 校验顺序：审核开关 -> 函数选择器权限（含项目/模块停用、冻结、写权限开关及写配额余量）-> DID method -> 累加写配额
+func enforcePolicy(ctx common.TransactionContextInterface, contract string) error {
+	fn, params := ctx.GetStub().GetFunctionAndParameters()
+	fn = transactionFunctionName(fn)
+	policy := lookupPolicy(contract, fn)
+	if policy == nil {
+		log.Printf("策略校验失败 - 函数未登记策略: %s:%s", contract, fn)
+		return fmt.Errorf("no transaction policy registered for %s:%s", contract, fn)
+	}
+
+	checker := ctx.GetPermissionChecker()
+	caller := ctx.GetCaller()
+	if policy.Type == selectorTypeWrite {
+		if policy.RequireIssuerVerification {
+			if ok, err := checker.CheckIssuerVerificationEnabled(ctx, caller); err != nil || !ok {
+				log.Printf("发证方审核状态检查失败: %v", err)
+				return fmt.Errorf("failed to check issuer verification status: %v", err)
+			}
+		}
+		if policy.RequireTemplateVerification {
+			if ok, err := checker.CheckVCTemplateVerificationEnabled(ctx, caller); err != nil || !ok {
+				log.Printf("VC模板审核状态检查失败: %v", err)
+				return fmt.Errorf("failed to check vc template verification status: %v", err)
+			}
+		}
+	}
+
+	var hasPermission bool
+	var err error
+	if policy.Type == selectorTypeWrite {
+		hasPermission, err = checker.CheckWriteFuncSelectorPermission(ctx, caller, policy.Selector)
+	} else {
+		hasPermission, err = checker.CheckQueryFuncSelectorPermission(ctx, caller, policy.Selector)
+	}
+	if err != nil {
+		log.Printf("权限检查失败: %v", err)
+		return fmt.Errorf("permission check failed: %v", err)
+	}
+	if !hasPermission {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: %s", caller, fn)
+		return fmt.Errorf("no permission to call %s", fn)
+	}
+	log.Printf("权限校验通过 - 调用者: %s, 操作: %s", caller, fn)
+
+	// 参数为空时交由业务函数做参数校验
+	if policy.MethodArg > 0 && policy.MethodArg <= len(params) && strings.TrimSpace(params[policy.MethodArg-1]) != "" {
+		did := params[policy.MethodArg-1]
+		if err := checker.CheckMethod(ctx, did); err != nil {
+			log.Printf("DID方法校验失败: %v", err)
+			return fmt.Errorf("method validation failed: %v", err)
+		}
+		log.Printf("DID方法校验通过 - DID: %s", did)
+	}
+
+	// 全部校验通过后累加调用者本人的写配额，配额计数与业务写入在同一交易中提交
+	if policy.Type == selectorTypeWrite {
+		if err := checker.ConsumeWriteQuota(ctx, policy.Selector); err != nil {
+			log.Printf("写配额校验失败: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupPolicy 查询合约函数的策略，未登记时返回nil
+func lookupPolicy(contract, fn string) *TransactionPolicy {
+	for i := range transactionPolicies {
+		if transactionPolicies[i].Contract == contract && transactionPolicies[i].Function == fn {
+			return &transactionPolicies[i]
+		}
+	}
+	return nil
+}
+
+// transactionFunctionName 从 {contract}:{function} 形式的调用名中取出函数名，首字母按contractapi规则转为大写
+func transactionFunctionName(name string) string {
+	if idx := strings.LastIndex(name, ":"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	if name == "" {
+		return name
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
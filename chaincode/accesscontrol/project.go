@@ -0,0 +1,325 @@
+package accesscontrol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ProjectInfo 平台登记的项目信息
+type ProjectInfo struct {
+	ProjectCode string `json:"projectCode"` // 项目编码，交易通过transient字段projectCode选择该项目
+	ServiceCode string `json:"serviceCode"` // 服务编码
+	Admin       string `json:"admin"`       // 项目初始管理员
+	CreatedBy   string `json:"createdBy"`   // 创建项目的平台超级管理员
+	CreatedAt   int64  `json:"createdAt"`   // 创建时间（Unix秒，取交易时间戳）
+	TxId        string `json:"txId"`        // 创建项目的交易ID
+}
+
+const (
+	// platformAdminsKey 平台超级管理员列表在链上的存储键（位于默认命名空间）
+	// 格式：platform:superAdmins
+	platformAdminsKey = "platform:superAdmins"
+)
+
+// ================== 多项目（多租户）相关 ==================
+
+// InitPlatform 初始化平台超级管理员，调用者成为第一个平台超级管理员，只能调用一次
+// 权限要求：默认项目必须已通过InitProject初始化，只有默认项目的管理员可以调用
+func (c *PermissionChaincode) InitPlatform(ctx contractapi.TransactionContextInterface) error {
+	if err := common.SetProjectStub(ctx, ""); err != nil {
+		return err
+	}
+	caller := common.GetCaller(ctx)
+	log.Printf("开始初始化平台超级管理员 - 调用者: %s", caller)
+	admins, err := getPlatformAdmins(ctx)
+	if err != nil {
+		return err
+	}
+	if len(admins) > 0 {
+		log.Printf("初始化平台失败 - 平台超级管理员已存在")
+		return errors.New("platform is already initialized")
+	}
+	// 平台超级管理员由默认项目的管理员产生，默认项目未初始化时不允许抢先初始化平台
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		log.Printf("初始化平台失败 - 默认项目未初始化: %v", err)
+		return errors.New("default project must be initialized by InitProject before InitPlatform")
+	}
+	if !common.IsAdmin(ctx, cfg.Admins) {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: InitPlatform", caller)
+		return errors.New("only admin of the default project can call InitPlatform")
+	}
+	if err := checkNotFrozen(ctx, caller); err != nil {
+		return err
+	}
+
+	admins = []string{caller}
+	if err := putPlatformAdmins(ctx, admins); err != nil {
+		log.Printf("平台超级管理员存储失败: %v", err)
+		return err
+	}
+	log.Printf("平台超级管理员存储成功 - 账户: %s", caller)
+	if err := recordAudit(ctx, "InitPlatform", "superAdmins", nil, admins); err != nil {
+		return err
+	}
+	payload, _ := json.Marshal(map[string]interface{}{
+		"superAdmins": admins,
+		"sender":      caller})
+	return common.EmitEvent(ctx, "PlatformInitialized", payload)
+}
+
+// AddPlatformAdmin 添加平台超级管理员
+// 权限要求：只有平台超级管理员可以调用此方法
+func (c *PermissionChaincode) AddPlatformAdmin(ctx contractapi.TransactionContextInterface, account string) error {
+	log.Printf("开始添加平台超级管理员 - 账户: %s", account)
+	return c.operatePlatformAdmin(ctx, account, false)
+}
+
+// RemovePlatformAdmin 移除平台超级管理员，不允许移除最后一个平台超级管理员
+// 权限要求：只有平台超级管理员可以调用此方法
+func (c *PermissionChaincode) RemovePlatformAdmin(ctx contractapi.TransactionContextInterface, account string) error {
+	log.Printf("开始移除平台超级管理员 - 账户: %s", account)
+	return c.operatePlatformAdmin(ctx, account, true)
+}
+
+// GetPlatformAdmins 查询平台超级管理员列表
+func (c *PermissionChaincode) GetPlatformAdmins(ctx contractapi.TransactionContextInterface) ([]string, error) {
+	if err := common.SetProjectStub(ctx, ""); err != nil {
+		return nil, err
+	}
+	return getPlatformAdmins(ctx)
+}
+
+// CreateProject 创建项目，项目拥有独立的管理员、method、开关配置以及did/issuer/vc/permission状态
+// 参数说明：
+// - projectCode: 项目编码，只包含字母、数字、"-"和"_"，创建后交易通过transient字段projectCode选择该项目
+// - serviceCode: 服务编码
+// - method: 项目主method名称
+// - isPrivate/enableIssuerVerification/enableVcVerification/enableWritePermission: 与InitProject相同
+// - admin: 项目初始管理员，格式：{mspId}:{ski}
+//
+// 权限要求：只有平台超级管理员可以调用此方法
+func (c *PermissionChaincode) CreateProject(
+	ctx contractapi.TransactionContextInterface,
+	projectCode, serviceCode, method string,
+	isPrivate, enableIssuerVerification, enableVcVerification, enableWritePermission bool,
+	admin string,
+) error {
+	log.Printf("开始创建项目 - projectCode: %s, serviceCode: %s, method: %s, 管理员: %s", projectCode, serviceCode, method, admin)
+	if err := common.SetProjectStub(ctx, ""); err != nil {
+		return err
+	}
+	caller := common.GetCaller(ctx)
+	if err := checkPlatformAdmin(ctx, "CreateProject"); err != nil {
+		return err
+	}
+	if err := common.ValidateProjectCode(projectCode); err != nil {
+		log.Printf("参数校验失败 - %v", err)
+		return err
+	}
+	if strings.TrimSpace(serviceCode) == "" {
+		log.Printf("参数校验失败 - serviceCode为空")
+		return errors.New("serviceCode cannot be empty")
+	}
+	if err := common.ValidateDIDMethodName(method); err != nil {
+		log.Printf("参数校验失败 - %v", err)
+		return err
+	}
+	if err := common.ValidateAccount(admin); err != nil {
+		log.Printf("参数校验失败 - %v", err)
+		return err
+	}
+	existing, err := ctx.GetStub().GetState(common.PlatformProjectPrefix + projectCode)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		log.Printf("创建项目失败 - 项目已存在: %s", projectCode)
+		return fmt.Errorf("project %s already exists", projectCode)
+	}
+
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	info := ProjectInfo{
+		ProjectCode: projectCode,
+		ServiceCode: serviceCode,
+		Admin:       admin,
+		CreatedBy:   caller,
+		CreatedAt:   txTime.Unix(),
+		TxId:        ctx.GetStub().GetTxID(),
+	}
+	b, _ := json.Marshal(info)
+	if err := ctx.GetStub().PutState(common.PlatformProjectPrefix+projectCode, b); err != nil {
+		log.Printf("项目登记存储失败: %v", err)
+		return err
+	}
+	if err := recordAudit(ctx, "CreateProject", projectCode, nil, info); err != nil {
+		return err
+	}
+
+	// 切换到新项目的命名空间写入项目配置
+	if err := common.SetProjectStub(ctx, projectCode); err != nil {
+		return err
+	}
+	cfg := common.ProjectConfig{
+		EnableVCTemplateVerification: enableVcVerification,
+		EnableIssuerVerification:     enableIssuerVerification,
+		EnableWritePermission:        enableWritePermission,
+		Method:                       method,
+		IsProjectPrivate:             isPrivate,
+		ServiceCode:                  serviceCode,
+		ProjectCode:                  projectCode,
+		Admins:                       []string{admin},
+	}
+	if err := putProjectConfig(ctx, &cfg); err != nil {
+		log.Printf("项目配置存储失败: %v", err)
+		return err
+	}
+	log.Printf("项目创建成功 - projectCode: %s", projectCode)
+	if err := recordAudit(ctx, "CreateProject", projectCode, nil, cfg); err != nil {
+		return err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"project": info,
+		"config":  cfg,
+		"sender":  caller})
+	return common.EmitEvent(ctx, "ProjectCreated", payload)
+}
+
+// ListProjects 查询平台通过CreateProject创建的所有项目（不包含默认项目）
+func (c *PermissionChaincode) ListProjects(ctx contractapi.TransactionContextInterface) ([]ProjectInfo, error) {
+	if err := common.SetProjectStub(ctx, ""); err != nil {
+		return nil, err
+	}
+	iterator, err := ctx.GetStub().GetStateByRange(common.PlatformProjectPrefix, common.PlatformProjectPrefix+"~")
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	projects := make([]ProjectInfo, 0)
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var info ProjectInfo
+		if err := json.Unmarshal(kv.Value, &info); err != nil {
+			return nil, err
+		}
+		projects = append(projects, info)
+	}
+	return projects, nil
+}
+
+// GetCurrentProject 查询本次交易选择的项目编码，默认项目返回空字符串
+func (c *PermissionChaincode) GetCurrentProject(ctx contractapi.TransactionContextInterface) (string, error) {
+	return common.GetProjectCode(ctx), nil
+}
+
+// ================== 内部方法 ==================
+
+// operatePlatformAdmin 内部方法：校验平台超级管理员身份后添加/移除平台超级管理员
+func (c *PermissionChaincode) operatePlatformAdmin(ctx contractapi.TransactionContextInterface, account string, remove bool) error {
+	operation := "AddPlatformAdmin"
+	if remove {
+		operation = "RemovePlatformAdmin"
+	}
+	if err := common.SetProjectStub(ctx, ""); err != nil {
+		return err
+	}
+	if err := checkPlatformAdmin(ctx, operation); err != nil {
+		return err
+	}
+	if err := common.ValidateAccount(account); err != nil {
+		log.Printf("参数校验失败 - %v", err)
+		return err
+	}
+	admins, err := getPlatformAdmins(ctx)
+	if err != nil {
+		return err
+	}
+	oldAdmins := append([]string(nil), admins...)
+	if remove {
+		if !containsString(admins, account) {
+			return errors.New("account is not a platform admin")
+		}
+		if len(admins) == 1 {
+			log.Printf("移除平台超级管理员失败 - 不能移除最后一个平台超级管理员")
+			return errors.New("cannot remove the last platform admin")
+		}
+		admins = removeString(admins, account)
+	} else {
+		if containsString(admins, account) {
+			return errors.New("account is already a platform admin")
+		}
+		admins = append(admins, account)
+	}
+	if err := putPlatformAdmins(ctx, admins); err != nil {
+		log.Printf("平台超级管理员存储失败: %v", err)
+		return err
+	}
+	log.Printf("平台超级管理员存储成功 - 列表: %v", admins)
+	if err := recordAudit(ctx, operation, "superAdmins", oldAdmins, admins); err != nil {
+		return err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"account":     account,
+		"superAdmins": admins,
+		"sender":      common.GetCaller(ctx)})
+	if remove {
+		return common.EmitEvent(ctx, "PlatformAdminRemoved", payload)
+	}
+	return common.EmitEvent(ctx, "PlatformAdminAdded", payload)
+}
+
+// checkPlatformAdmin 内部方法：校验调用者为平台超级管理员且未被冻结
+func checkPlatformAdmin(ctx contractapi.TransactionContextInterface, operation string) error {
+	caller := common.GetCaller(ctx)
+	admins, err := getPlatformAdmins(ctx)
+	if err != nil {
+		return err
+	}
+	if !common.IsAdmin(ctx, admins) {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: %s", caller, operation)
+		return fmt.Errorf("only platform admin can call %s", operation)
+	}
+	if err := checkNotFrozen(ctx, caller); err != nil {
+		return err
+	}
+	log.Printf("权限校验通过 - 调用者: %s, 操作: %s", caller, operation)
+	return nil
+}
+
+// getPlatformAdmins 内部方法：获取平台超级管理员列表，未初始化时返回空列表
+func getPlatformAdmins(ctx contractapi.TransactionContextInterface) ([]string, error) {
+	b, err := common.UnwrapStub(ctx.GetStub()).GetState(platformAdminsKey)
+	if err != nil {
+		return nil, err
+	}
+	admins := make([]string, 0)
+	if b == nil {
+		return admins, nil
+	}
+	if err := json.Unmarshal(b, &admins); err != nil {
+		return nil, err
+	}
+	return admins, nil
+}
+
+// putPlatformAdmins 内部方法：存储平台超级管理员列表
+func putPlatformAdmins(ctx contractapi.TransactionContextInterface, admins []string) error {
+	b, _ := json.Marshal(admins)
+	return common.UnwrapStub(ctx.GetStub()).PutState(platformAdminsKey, b)
+}
@@ -0,0 +1,143 @@
+package accesscontrol_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"sbp-did-chaincode/accesscontrol"
+	"sbp-did-chaincode/common"
+	"sbp-did-chaincode/did"
+)
+
+// in 以指定账户开始一个新交易，并与BeforeTransaction相同通过transient字段projectCode选择项目
+func (p *permissionTest) in(a testAccount, projectCode string) *accesscontrol.TransactionContext {
+	p.t.Helper()
+	ctx, err := p.selectProject(a, projectCode)
+	p.must(err)
+	return ctx
+}
+
+// selectProject 与in相同，返回选择项目的错误
+func (p *permissionTest) selectProject(a testAccount, projectCode string) (*accesscontrol.TransactionContext, error) {
+	ctx := p.as(a)
+	p.stub.TransientMap = map[string][]byte{common.ProjectTransientKey: []byte(projectCode)}
+	defer func() { p.stub.TransientMap = nil }()
+	return ctx, common.SelectProject(ctx)
+}
+
+func TestProjectIsolation(t *testing.T) {
+	p := newPermissionTest(t)
+	platform, p1Admin, p2Admin, user := p.newAccount(), p.newAccount(), p.newAccount(), p.newAccount()
+	p.must(p.cc.InitProject(p.as(platform), "sbp", false, false, false, false, "svc", "default", false))
+	p.must(p.cc.InitPlatform(p.as(platform)))
+
+	t.Run("only platform admin can create project", func(t *testing.T) {
+		for _, caller := range []testAccount{p1Admin, user} {
+			err := p.cc.CreateProject(p.as(caller), "p1", "svc1", "sbp", false, false, false, false, p1Admin.account)
+			if err == nil || !strings.Contains(err.Error(), "only platform admin can call CreateProject") {
+				t.Fatalf("got err %v, want platform admin error", err)
+			}
+		}
+		if _, err := p.selectProject(user, "p1"); err == nil || !strings.Contains(err.Error(), "project p1 not found") {
+			t.Fatalf("got err %v, want project not found", err)
+		}
+	})
+
+	p.must(p.cc.CreateProject(p.as(platform), "p1", "svc1", "sbp", false, false, false, false, p1Admin.account))
+	p.must(p.cc.CreateProject(p.as(platform), "p2", "svc2", "sbp", false, false, false, false, p2Admin.account))
+
+	t.Run("select project", func(t *testing.T) {
+		for _, code := range []string{"", "p1", "p2"} {
+			got, err := p.cc.GetCurrentProject(p.in(user, code))
+			if err != nil || got != code {
+				t.Fatalf("got project %q, %v, want %q", got, err, code)
+			}
+		}
+		if _, err := p.selectProject(user, "p1~x"); err == nil || !strings.Contains(err.Error(), "invalid projectCode") {
+			t.Fatalf("got err %v, want invalid projectCode", err)
+		}
+		// 项目管理员只管理自己的项目
+		err := p.cc.CreateRole(p.in(p1Admin, "p2"), "writer", "", []string{"RegisterDid"})
+		if err == nil || !strings.Contains(err.Error(), "only admin") {
+			t.Fatalf("got err %v, want admin error", err)
+		}
+	})
+
+	t.Run("selector grants", func(t *testing.T) {
+		for code, grant := range map[string]accesscontrol.AccountSelector{
+			"p1": {Account: user.account, FuncNames: []string{"RegisterDid"}},
+			"p2": {Account: user.account, FuncNames: []string{"DeactivateDid"}},
+		} {
+			admin := map[string]testAccount{"p1": p1Admin, "p2": p2Admin}[code]
+			if err := p.cc.BatchOperateSelectorPermissions(p.in(admin, code), []accesscontrol.AccountSelector{grant}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want := map[string]map[string]bool{
+			"":   {"RegisterDid": false, "DeactivateDid": false},
+			"p1": {"RegisterDid": true, "DeactivateDid": false},
+			"p2": {"RegisterDid": false, "DeactivateDid": true},
+		}
+		for code, funcs := range want {
+			for funcName, wantGranted := range funcs {
+				got, err := p.cc.HasSelectorPermission(p.in(user, code), user.account, funcName)
+				if err != nil || got != wantGranted {
+					t.Fatalf("project %q %s: got %t, %v, want %t", code, funcName, got, err, wantGranted)
+				}
+			}
+		}
+	})
+
+	t.Run("did documents", func(t *testing.T) {
+		didCC := new(did.DIDChaincode)
+		documents := map[string]string{
+			"p1": `{"@context":"https://www.w3.org/ns/did/v1","id":"did:sbp:alice","alsoKnownAs":["https://p1.example.com"]}`,
+			"p2": `{"@context":"https://www.w3.org/ns/did/v1","id":"did:sbp:alice","alsoKnownAs":["https://p2.example.com"]}`,
+		}
+		for code, document := range documents {
+			if err := didCC.RegisterDid(p.in(user, code), testDID, document); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, code := range []string{"", "p1", "p2"} {
+			result, err := didCC.ResolveDid(p.in(user, code), testDID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var resolution did.ResolutionResult
+			if err := json.Unmarshal([]byte(result), &resolution); err != nil {
+				t.Fatal(err)
+			}
+			if code == "" {
+				if resolution.DidResolutionMetadata.Error == "" {
+					t.Fatalf("default project resolved tenant did: %s", result)
+				}
+				continue
+			}
+			if string(resolution.DidDocument) != documents[code] {
+				t.Fatalf("project %q got document %s, want %s", code, resolution.DidDocument, documents[code])
+			}
+		}
+	})
+
+	t.Run("project config", func(t *testing.T) {
+		tests := []struct {
+			code, wantProjectCode string
+			wantAdmin             testAccount
+		}{
+			{"", "default", platform},
+			{"p1", "p1", p1Admin},
+			{"p2", "p2", p2Admin},
+		}
+		for _, tt := range tests {
+			cfg, err := p.cc.GetProjectConfig(p.in(user, tt.code))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.ProjectCode != tt.wantProjectCode || len(cfg.Admins) != 1 || cfg.Admins[0] != tt.wantAdmin.account {
+				t.Fatalf("project %q got config %+v", tt.code, cfg)
+			}
+		}
+	})
+}
@@ -0,0 +1,524 @@
+package accesscontrol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ConfigProposal 敏感配置变更提案
+// 开启多管理员审批（ApprovalThreshold > 1）后，敏感配置变更需由管理员发起提案，
+// 其他管理员审批，审批数量达到阈值时自动执行
+type ConfigProposal struct {
+	ProposalId string         `json:"proposalId"` // 提案ID，即发起提案的交易ID
+	Operation  string         `json:"operation"`  // 变更操作，见ProposalOp*常量
+	Value      string         `json:"value"`      // 变更参数，如新的method、true/false、管理员账户标识、阈值
+	Proposer   string         `json:"proposer"`   // 发起提案的管理员账户标识
+	Votes      []ProposalVote `json:"votes"`      // 审批记录，发起人默认审批通过
+	Status     string         `json:"status"`     // 提案状态：pending/executed/cancelled/expired
+	CreatedAt  int64          `json:"createdAt"`  // 发起时间（交易时间戳，Unix秒）
+	Deadline   int64          `json:"deadline"`   // 截止时间（Unix秒），超过后不能再审批
+	ExecutedAt int64          `json:"executedAt"` // 执行时间（Unix秒），未执行为0
+}
+
+// ProposalVote 提案审批记录
+type ProposalVote struct {
+	Admin   string `json:"admin"`   // 审批的管理员账户标识
+	VotedAt int64  `json:"votedAt"` // 审批时间（交易时间戳，Unix秒）
+}
+
+const (
+	// proposalPrefix 配置变更提案在链上的存储键前缀
+	// 格式：permission:proposal:{proposalId}
+	proposalPrefix = "permission:proposal:"
+
+	ProposalOpPause                       = "Pause"
+	ProposalOpUnpause                     = "Unpause"
+	ProposalOpChangeMethod                = "ChangeMethod"
+	ProposalOpChangeEnableWritePermission = "ChangeEnableWritePermission"
+	ProposalOpTransferAdminRole           = "TransferAdminRole"
+	ProposalOpProposeAdmin                = "ProposeAdmin"
+	ProposalOpRemoveAdmin                 = "RemoveAdmin"
+	ProposalOpSetApprovalThreshold        = "SetApprovalThreshold"
+	ProposalOpPauseScope                  = "PauseScope"
+	ProposalOpUnpauseScope                = "UnpauseScope"
+	ProposalOpEnableMethod                = "EnableMethod"
+	ProposalOpDisableMethod               = "DisableMethod"
+	ProposalOpUpdateProjectConfig         = "UpdateProjectConfig"
+	ProposalOpFreezeAccount               = "FreezeAccount"
+	ProposalOpUnfreezeAccount             = "UnfreezeAccount"
+
+	ProposalStatusPending   = "pending"
+	ProposalStatusExecuted  = "executed"
+	ProposalStatusCancelled = "cancelled"
+	ProposalStatusExpired   = "expired"
+)
+
+// proposalOperations 需要多管理员审批的敏感操作
+var proposalOperations = map[string]bool{
+	ProposalOpPause:                       true,
+	ProposalOpUnpause:                     true,
+	ProposalOpChangeMethod:                true,
+	ProposalOpChangeEnableWritePermission: true,
+	ProposalOpTransferAdminRole:           true,
+	ProposalOpProposeAdmin:                true,
+	ProposalOpRemoveAdmin:                 true,
+	ProposalOpSetApprovalThreshold:        true,
+	ProposalOpPauseScope:                  true,
+	ProposalOpUnpauseScope:                true,
+	ProposalOpEnableMethod:                true,
+	ProposalOpDisableMethod:               true,
+	ProposalOpUpdateProjectConfig:         true,
+	ProposalOpFreezeAccount:               true,
+	ProposalOpUnfreezeAccount:             true,
+}
+
+// ================== 多管理员审批相关 ==================
+
+// SetApprovalThreshold 设置敏感配置变更所需的管理员审批数量
+// 阈值必须介于1和当前管理员数量之间；阈值大于1后，修改阈值本身也需要通过提案审批
+// 权限要求：只有管理员可以调用此方法
+func (c *PermissionChaincode) SetApprovalThreshold(ctx contractapi.TransactionContextInterface, threshold int) error {
+	log.Printf("开始设置审批阈值 - 新阈值: %d", threshold)
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		log.Printf("获取项目配置失败: %v", err)
+		return err
+	}
+	if !common.IsAdmin(ctx, cfg.Admins) {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: SetApprovalThreshold", common.GetCaller(ctx))
+		return errors.New("only admin can set approval threshold")
+	}
+	if err := checkNotFrozen(ctx, common.GetCaller(ctx)); err != nil {
+		return err
+	}
+	log.Printf("权限校验通过 - 调用者: %s, 操作: SetApprovalThreshold", common.GetCaller(ctx))
+	if err := checkSingleAdminOperation(cfg, ProposalOpSetApprovalThreshold); err != nil {
+		return err
+	}
+
+	eventName, payload, err := c.applyApprovalThreshold(ctx, cfg, threshold)
+	if err != nil {
+		return err
+	}
+	return common.EmitEvent(ctx, eventName, payload)
+}
+
+// CreateConfigProposal 发起敏感配置变更提案
+// 参数说明：
+// - operation: 变更操作，支持Pause/Unpause/ChangeMethod/ChangeEnableWritePermission/TransferAdminRole/ProposeAdmin/RemoveAdmin/SetApprovalThreshold/FreezeAccount/UnfreezeAccount等，见ProposalOp*常量
+// - value: 变更参数，Pause/Unpause为空，ChangeEnableWritePermission为true/false，SetApprovalThreshold为整数，FreezeAccount/UnfreezeAccount为{"account":"...","reason":"..."}，其余为method或管理员账户标识
+// - deadline: 截止时间（Unix秒），必须晚于当前交易时间
+//
+// 返回值：提案ID。发起人自动计入审批，审批数量已达到阈值时立即执行
+// 权限要求：只有管理员可以调用此方法
+func (c *PermissionChaincode) CreateConfigProposal(ctx contractapi.TransactionContextInterface, operation, value string, deadline int64) (string, error) {
+	caller := common.GetCaller(ctx)
+	log.Printf("开始发起配置变更提案 - 操作: %s, 参数: %s, 截止时间: %d", operation, value, deadline)
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		log.Printf("获取项目配置失败: %v", err)
+		return "", err
+	}
+	if !common.IsAdmin(ctx, cfg.Admins) {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: CreateConfigProposal", caller)
+		return "", errors.New("only admin can create config proposal")
+	}
+	if err := checkNotFrozen(ctx, caller); err != nil {
+		return "", err
+	}
+	log.Printf("权限校验通过 - 调用者: %s, 操作: CreateConfigProposal", caller)
+
+	if !proposalOperations[operation] {
+		log.Printf("参数校验失败 - 不支持的操作: %s", operation)
+		return "", fmt.Errorf("unsupported proposal operation: %s", operation)
+	}
+	if err := validateProposalValue(operation, value); err != nil {
+		log.Printf("参数校验失败 - %v", err)
+		return "", err
+	}
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return "", err
+	}
+	if deadline <= txTime.Unix() {
+		log.Printf("参数校验失败 - 截止时间早于当前交易时间: %d", deadline)
+		return "", errors.New("deadline must be later than current tx timestamp")
+	}
+
+	proposal := &ConfigProposal{
+		ProposalId: ctx.GetStub().GetTxID(),
+		Operation:  operation,
+		Value:      value,
+		Proposer:   caller,
+		Votes:      []ProposalVote{{Admin: caller, VotedAt: txTime.Unix()}},
+		Status:     ProposalStatusPending,
+		CreatedAt:  txTime.Unix(),
+		Deadline:   deadline,
+	}
+	eventName, payload, err := c.tryExecuteProposal(ctx, cfg, proposal, txTime.Unix())
+	if err != nil {
+		return "", err
+	}
+	if err := c.putProposal(ctx, proposal); err != nil {
+		log.Printf("提案存储失败: %v", err)
+		return "", err
+	}
+	log.Printf("提案存储成功 - 提案ID: %s, 状态: %s", proposal.ProposalId, proposal.Status)
+
+	if eventName == "" {
+		eventName = "ConfigProposalCreated"
+		payload, _ = json.Marshal(map[string]interface{}{
+			"serviceCode": cfg.ServiceCode,
+			"projectCode": cfg.ProjectCode,
+			"proposal":    proposal})
+	}
+	return proposal.ProposalId, common.EmitEvent(ctx, eventName, payload)
+}
+
+// ApproveConfigProposal 审批配置变更提案，审批数量达到阈值时自动执行
+// 权限要求：只有管理员可以调用此方法，每个管理员只能审批一次
+func (c *PermissionChaincode) ApproveConfigProposal(ctx contractapi.TransactionContextInterface, proposalId string) error {
+	caller := common.GetCaller(ctx)
+	log.Printf("开始审批配置变更提案 - 提案ID: %s", proposalId)
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		log.Printf("获取项目配置失败: %v", err)
+		return err
+	}
+	if !common.IsAdmin(ctx, cfg.Admins) {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: ApproveConfigProposal", caller)
+		return errors.New("only admin can approve config proposal")
+	}
+	if err := checkNotFrozen(ctx, caller); err != nil {
+		return err
+	}
+	log.Printf("权限校验通过 - 调用者: %s, 操作: ApproveConfigProposal", caller)
+
+	proposal, err := c.getProposal(ctx, proposalId)
+	if err != nil {
+		return err
+	}
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if proposal.Status != ProposalStatusPending {
+		log.Printf("提案状态校验失败 - 提案ID: %s, 状态: %s", proposalId, proposal.Status)
+		return fmt.Errorf("proposal is %s", proposal.Status)
+	}
+	if txTime.Unix() > proposal.Deadline {
+		log.Printf("提案状态校验失败 - 提案已过期: %s", proposalId)
+		return errors.New("proposal is expired")
+	}
+	for _, vote := range proposal.Votes {
+		if vote.Admin == caller {
+			log.Printf("审批失败 - 管理员已审批: %s", caller)
+			return errors.New("admin has already approved this proposal")
+		}
+	}
+	proposal.Votes = append(proposal.Votes, ProposalVote{Admin: caller, VotedAt: txTime.Unix()})
+
+	eventName, payload, err := c.tryExecuteProposal(ctx, cfg, proposal, txTime.Unix())
+	if err != nil {
+		return err
+	}
+	if err := c.putProposal(ctx, proposal); err != nil {
+		log.Printf("提案存储失败: %v", err)
+		return err
+	}
+	log.Printf("提案审批成功 - 提案ID: %s, 审批数量: %d, 状态: %s", proposalId, len(proposal.Votes), proposal.Status)
+
+	if eventName == "" {
+		eventName = "ConfigProposalApproved"
+		payload, _ = json.Marshal(map[string]interface{}{
+			"serviceCode": cfg.ServiceCode,
+			"projectCode": cfg.ProjectCode,
+			"proposal":    proposal,
+			"sender":      caller})
+	}
+	return common.EmitEvent(ctx, eventName, payload)
+}
+
+// CancelConfigProposal 撤销尚未执行的配置变更提案
+// 权限要求：只有提案发起人可以调用此方法
+func (c *PermissionChaincode) CancelConfigProposal(ctx contractapi.TransactionContextInterface, proposalId string) error {
+	caller := common.GetCaller(ctx)
+	log.Printf("开始撤销配置变更提案 - 提案ID: %s", proposalId)
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		log.Printf("获取项目配置失败: %v", err)
+		return err
+	}
+	proposal, err := c.getProposal(ctx, proposalId)
+	if err != nil {
+		return err
+	}
+	if proposal.Proposer != caller || !common.IsAdmin(ctx, cfg.Admins) {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: CancelConfigProposal", caller)
+		return errors.New("only proposer can cancel config proposal")
+	}
+	if err := checkNotFrozen(ctx, caller); err != nil {
+		return err
+	}
+	if proposal.Status != ProposalStatusPending {
+		log.Printf("提案状态校验失败 - 提案ID: %s, 状态: %s", proposalId, proposal.Status)
+		return fmt.Errorf("proposal is %s", proposal.Status)
+	}
+
+	proposal.Status = ProposalStatusCancelled
+	if err := c.putProposal(ctx, proposal); err != nil {
+		log.Printf("提案存储失败: %v", err)
+		return err
+	}
+	log.Printf("提案已撤销 - 提案ID: %s", proposalId)
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode": cfg.ServiceCode,
+		"projectCode": cfg.ProjectCode,
+		"proposal":    proposal})
+	return common.EmitEvent(ctx, "ConfigProposalCancelled", payload)
+}
+
+// GetConfigProposal 查询配置变更提案及审批记录
+func (c *PermissionChaincode) GetConfigProposal(ctx contractapi.TransactionContextInterface, proposalId string) (*ConfigProposal, error) {
+	proposal, err := c.getProposal(ctx, proposalId)
+	if err != nil {
+		return nil, err
+	}
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	markProposalExpired(proposal, txTime.Unix())
+	return proposal, nil
+}
+
+// ListConfigProposals 查询配置变更提案列表，status为空时返回全部提案
+func (c *PermissionChaincode) ListConfigProposals(ctx contractapi.TransactionContextInterface, status string) ([]*ConfigProposal, error) {
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	iterator, err := ctx.GetStub().GetStateByRange(proposalPrefix, proposalPrefix+"~")
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	proposals := make([]*ConfigProposal, 0)
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var proposal ConfigProposal
+		if err := json.Unmarshal(kv.Value, &proposal); err != nil {
+			return nil, err
+		}
+		markProposalExpired(&proposal, txTime.Unix())
+		if status == "" || proposal.Status == status {
+			proposals = append(proposals, &proposal)
+		}
+	}
+	sort.Slice(proposals, func(i, j int) bool { return proposals[i].CreatedAt < proposals[j].CreatedAt })
+	return proposals, nil
+}
+
+// ================== 内部方法 ==================
+
+// checkSingleAdminOperation 开启多管理员审批后，拒绝单个管理员直接执行敏感操作
+func checkSingleAdminOperation(cfg *common.ProjectConfig, operation string) error {
+	if cfg.ApprovalThreshold > 1 {
+		log.Printf("多管理员审批校验失败 - 操作: %s, 审批阈值: %d", operation, cfg.ApprovalThreshold)
+		return fmt.Errorf("%s requires approval of %d admins, please use CreateConfigProposal", operation, cfg.ApprovalThreshold)
+	}
+	return nil
+}
+
+// validateProposalValue 校验提案参数格式
+func validateProposalValue(operation, value string) error {
+	switch operation {
+	case ProposalOpPause, ProposalOpUnpause:
+		return nil
+	case ProposalOpChangeEnableWritePermission:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid value for %s: %s", operation, value)
+		}
+	case ProposalOpSetApprovalThreshold:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("invalid value for %s: %s", operation, value)
+		}
+	case ProposalOpPauseScope, ProposalOpUnpauseScope:
+		if _, _, _, err := parsePauseScopeValue(value); err != nil {
+			return fmt.Errorf("invalid value for %s: %v", operation, err)
+		}
+	case ProposalOpChangeMethod, ProposalOpEnableMethod, ProposalOpDisableMethod:
+		if err := common.ValidateDIDMethodName(value); err != nil {
+			return fmt.Errorf("invalid value for %s: %v", operation, err)
+		}
+	case ProposalOpUpdateProjectConfig:
+		if _, err := parseConfigPatch(value); err != nil {
+			return fmt.Errorf("invalid value for %s: %v", operation, err)
+		}
+	case ProposalOpFreezeAccount, ProposalOpUnfreezeAccount:
+		if _, err := parseFreezeProposalValue(value); err != nil {
+			return fmt.Errorf("invalid value for %s: %v", operation, err)
+		}
+	default:
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("value cannot be empty for %s", operation)
+		}
+	}
+	return nil
+}
+
+// tryExecuteProposal 当前未被冻结的管理员中的审批数量达到阈值时执行提案，返回执行后待发送的事件；未执行时事件名为空
+func (c *PermissionChaincode) tryExecuteProposal(ctx contractapi.TransactionContextInterface, cfg *common.ProjectConfig, proposal *ConfigProposal, now int64) (string, []byte, error) {
+	threshold := cfg.ApprovalThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+	// 已不是管理员或已被冻结的管理员的审批不计入
+	approvals := 0
+	for _, vote := range proposal.Votes {
+		if !isAdminAccount(cfg.Admins, vote.Admin) {
+			continue
+		}
+		frozen, err := getFrozenAccount(ctx, vote.Admin)
+		if err != nil {
+			return "", nil, err
+		}
+		if frozen == nil || !frozen.Frozen {
+			approvals++
+		}
+	}
+	if approvals < threshold {
+		return "", nil, nil
+	}
+
+	log.Printf("提案审批数量达到阈值，开始执行 - 提案ID: %s, 操作: %s, 审批数量: %d, 阈值: %d", proposal.ProposalId, proposal.Operation, approvals, threshold)
+	var (
+		eventName string
+		payload   []byte
+		err       error
+	)
+	switch proposal.Operation {
+	case ProposalOpPause:
+		eventName, payload, err = c.applyPause(ctx, cfg, true)
+	case ProposalOpUnpause:
+		eventName, payload, err = c.applyPause(ctx, cfg, false)
+	case ProposalOpChangeMethod:
+		eventName, payload, err = c.applyMethod(ctx, cfg, proposal.Value)
+	case ProposalOpChangeEnableWritePermission:
+		enable, _ := strconv.ParseBool(proposal.Value)
+		eventName, payload, err = c.applyEnableWritePermission(ctx, cfg, enable)
+	case ProposalOpTransferAdminRole:
+		eventName, payload, err = c.applyAdminNomination(ctx, cfg, proposal.Value, proposal.Proposer, proposal.Proposer)
+	case ProposalOpProposeAdmin:
+		eventName, payload, err = c.applyAdminNomination(ctx, cfg, proposal.Value, proposal.Proposer, "")
+	case ProposalOpRemoveAdmin:
+		eventName, payload, err = c.applyRemoveAdmin(ctx, cfg, proposal.Value)
+	case ProposalOpSetApprovalThreshold:
+		threshold, _ := strconv.Atoi(proposal.Value)
+		eventName, payload, err = c.applyApprovalThreshold(ctx, cfg, threshold)
+	case ProposalOpPauseScope, ProposalOpUnpauseScope:
+		module, direction, scopes, _ := parsePauseScopeValue(proposal.Value)
+		eventName, payload, err = c.applyPauseScope(ctx, cfg, module, direction, scopes, proposal.Operation == ProposalOpPauseScope)
+	case ProposalOpEnableMethod, ProposalOpDisableMethod:
+		eventName, payload, err = c.applyMethodStatus(ctx, cfg, proposal.Value, proposal.Operation == ProposalOpEnableMethod)
+	case ProposalOpUpdateProjectConfig:
+		patch, _ := parseConfigPatch(proposal.Value)
+		eventName, payload, err = c.applyConfigPatch(ctx, cfg, patch)
+	case ProposalOpFreezeAccount, ProposalOpUnfreezeAccount:
+		v, _ := parseFreezeProposalValue(proposal.Value)
+		eventName, payload, err = c.applyFreeze(ctx, cfg, v.Account, v.Reason, proposal.Operation == ProposalOpFreezeAccount)
+	default:
+		err = fmt.Errorf("unsupported proposal operation: %s", proposal.Operation)
+	}
+	if err != nil {
+		log.Printf("提案执行失败 - 提案ID: %s, 错误: %v", proposal.ProposalId, err)
+		return "", nil, fmt.Errorf("failed to execute proposal %s: %v", proposal.ProposalId, err)
+	}
+	proposal.Status = ProposalStatusExecuted
+	proposal.ExecutedAt = now
+
+	// 一个交易只能保留一个事件，执行结果事件中附带提案信息
+	executedPayload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode": cfg.ServiceCode,
+		"projectCode": cfg.ProjectCode,
+		"proposal":    proposal,
+		"eventName":   eventName,
+		"event":       json.RawMessage(payload)})
+	return "ConfigProposalExecuted", executedPayload, nil
+}
+
+// applyApprovalThreshold 内部方法：校验并更新审批阈值，返回待发送的事件
+func (c *PermissionChaincode) applyApprovalThreshold(ctx contractapi.TransactionContextInterface, cfg *common.ProjectConfig, threshold int) (string, []byte, error) {
+	if threshold < 1 || threshold > len(cfg.Admins) {
+		log.Printf("参数校验失败 - 审批阈值: %d, 管理员数量: %d", threshold, len(cfg.Admins))
+		return "", nil, fmt.Errorf("approval threshold must be between 1 and admin count %d", len(cfg.Admins))
+	}
+	if cfg.ApprovalThreshold == threshold {
+		log.Printf("状态校验失败 - 审批阈值已相同: %d", threshold)
+		return "", nil, errors.New("approval threshold is already the same")
+	}
+
+	oldThreshold := cfg.ApprovalThreshold
+	cfg.ApprovalThreshold = threshold
+	if err := putProjectConfig(ctx, cfg); err != nil {
+		log.Printf("项目配置更新存储失败: %v", err)
+		return "", nil, err
+	}
+	log.Printf("项目配置更新 - 审批阈值: %d -> %d", oldThreshold, threshold)
+	if err := recordAudit(ctx, ProposalOpSetApprovalThreshold, "approvalThreshold", oldThreshold, threshold); err != nil {
+		return "", nil, err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode":  cfg.ServiceCode,
+		"projectCode":  cfg.ProjectCode,
+		"oldThreshold": oldThreshold,
+		"newThreshold": threshold})
+	return "ApprovalThresholdChanged", payload, nil
+}
+
+// markProposalExpired 待审批提案超过截止时间后标记为已过期（仅用于查询展示）
+func markProposalExpired(proposal *ConfigProposal, now int64) {
+	if proposal.Status == ProposalStatusPending && now > proposal.Deadline {
+		proposal.Status = ProposalStatusExpired
+	}
+}
+
+// getProposal 内部方法：获取配置变更提案
+func (c *PermissionChaincode) getProposal(ctx contractapi.TransactionContextInterface, proposalId string) (*ConfigProposal, error) {
+	if strings.TrimSpace(proposalId) == "" {
+		return nil, errors.New("proposalId cannot be empty")
+	}
+	b, err := ctx.GetStub().GetState(proposalPrefix + proposalId)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, errors.New("proposal not found")
+	}
+	var proposal ConfigProposal
+	if err := json.Unmarshal(b, &proposal); err != nil {
+		return nil, err
+	}
+	return &proposal, nil
+}
+
+// putProposal 内部方法：存储配置变更提案
+func (c *PermissionChaincode) putProposal(ctx contractapi.TransactionContextInterface, proposal *ConfigProposal) error {
+	b, _ := json.Marshal(proposal)
+	return ctx.GetStub().PutState(proposalPrefix+proposal.ProposalId, b)
+}
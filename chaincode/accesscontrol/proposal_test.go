@@ -0,0 +1,147 @@
+package accesscontrol_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"sbp-did-chaincode/accesscontrol"
+)
+
+// newProposalTest 初始化有a、b、c三个管理员、审批阈值为2的项目
+func newProposalTest(t *testing.T) (p *permissionTest, a, b, c testAccount) {
+	p = newPermissionTest(t)
+	a, b, c = p.newAccount(), p.newAccount(), p.newAccount()
+	p.must(p.cc.InitProject(p.as(a), "sbp", false, false, false, false, "svc", "proj", false))
+	for _, admin := range []testAccount{b, c} {
+		p.must(p.cc.ProposeAdmin(p.as(a), admin.account))
+		p.must(p.cc.AcceptAdmin(p.as(admin)))
+	}
+	p.must(p.cc.SetApprovalThreshold(p.as(a), 2))
+	return p, a, b, c
+}
+
+// wantStatus 校验提案状态及审批数量
+func (p *permissionTest) wantStatus(viewer testAccount, proposalID, status string, votes int) *accesscontrol.ConfigProposal {
+	p.t.Helper()
+	proposal, err := p.cc.GetConfigProposal(p.as(viewer), proposalID)
+	p.must(err)
+	if proposal.Status != status || len(proposal.Votes) != votes {
+		p.t.Fatalf("got proposal status %s with %d votes, want %s with %d votes", proposal.Status, len(proposal.Votes), status, votes)
+	}
+	return proposal
+}
+
+func wantErr(t *testing.T, err error, want string) {
+	t.Helper()
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("got err %v, want %q", err, want)
+	}
+}
+
+func TestProposalExecutesAtThreshold(t *testing.T) {
+	p, a, b, _ := newProposalTest(t)
+	deadline := p.now.Add(24 * time.Hour).Unix()
+
+	wantErr(t, p.cc.Pause(p.as(a)), "requires approval of 2 admins")
+	proposalID, err := p.cc.CreateConfigProposal(p.as(a), "Pause", "", deadline)
+	p.must(err)
+	p.wantStatus(a, proposalID, accesscontrol.ProposalStatusPending, 1)
+	if paused, _ := p.cc.Paused(p.as(a)); paused {
+		t.Fatal("project must not be paused before the proposal reaches the threshold")
+	}
+
+	approvedAt := p.nextTxTime().Unix()
+	p.must(p.cc.ApproveConfigProposal(p.as(b), proposalID))
+	proposal := p.wantStatus(a, proposalID, accesscontrol.ProposalStatusExecuted, 2)
+	if proposal.ExecutedAt != approvedAt {
+		t.Fatalf("got executedAt %d, want %d", proposal.ExecutedAt, approvedAt)
+	}
+	if paused, _ := p.cc.Paused(p.as(a)); !paused {
+		t.Fatal("project must be paused after the proposal is executed")
+	}
+	wantErr(t, p.cc.ApproveConfigProposal(p.as(b), proposalID), "proposal is executed")
+}
+
+func TestProposalDeadline(t *testing.T) {
+	p, a, b, c := newProposalTest(t)
+
+	// 截止时间等于发起交易的时间戳
+	deadline := p.nextTxTime().Unix()
+	_, err := p.cc.CreateConfigProposal(p.as(a), "Pause", "", deadline)
+	wantErr(t, err, "deadline must be later than current tx timestamp")
+
+	// 截止时间为下一个审批交易的时间戳：恰好在截止时间审批仍然有效
+	createdAt := p.nextTxTime()
+	proposalID, err := p.cc.CreateConfigProposal(p.as(a), "ChangeEnableWritePermission", "true", createdAt.Add(2*time.Minute).Unix())
+	p.must(err)
+	p.wantStatus(a, proposalID, accesscontrol.ProposalStatusPending, 1)
+	p.must(p.cc.ApproveConfigProposal(p.as(b), proposalID))
+	p.wantStatus(a, proposalID, accesscontrol.ProposalStatusExecuted, 2)
+
+	createdAt = p.nextTxTime()
+	proposalID, err = p.cc.CreateConfigProposal(p.as(a), "Pause", "", createdAt.Add(30*time.Second).Unix())
+	p.must(err)
+	wantErr(t, p.cc.ApproveConfigProposal(p.as(c), proposalID), "proposal is expired")
+	p.wantStatus(a, proposalID, accesscontrol.ProposalStatusExpired, 1)
+	if paused, _ := p.cc.Paused(p.as(a)); paused {
+		t.Fatal("expired proposal must not be executed")
+	}
+}
+
+func TestProposalDuplicateVote(t *testing.T) {
+	p, a, _, _ := newProposalTest(t)
+	proposalID, err := p.cc.CreateConfigProposal(p.as(a), "Pause", "", p.now.Add(24*time.Hour).Unix())
+	p.must(err)
+	wantErr(t, p.cc.ApproveConfigProposal(p.as(a), proposalID), "admin has already approved this proposal")
+	p.wantStatus(a, proposalID, accesscontrol.ProposalStatusPending, 1)
+}
+
+func TestProposalIgnoresRemovedAdminVotes(t *testing.T) {
+	p, a, b, c := newProposalTest(t)
+	deadline := p.now.Add(24 * time.Hour).Unix()
+	pauseID, err := p.cc.CreateConfigProposal(p.as(b), "Pause", "", deadline)
+	p.must(err)
+
+	removeID, err := p.cc.CreateConfigProposal(p.as(a), "RemoveAdmin", b.account, deadline)
+	p.must(err)
+	p.must(p.cc.ApproveConfigProposal(p.as(c), removeID))
+	p.wantStatus(a, removeID, accesscontrol.ProposalStatusExecuted, 2)
+	wantErr(t, p.cc.ApproveConfigProposal(p.as(b), pauseID), "only admin can approve config proposal")
+
+	// b的审批已不计入：a审批后只有一个有效审批
+	p.must(p.cc.ApproveConfigProposal(p.as(a), pauseID))
+	p.wantStatus(a, pauseID, accesscontrol.ProposalStatusPending, 2)
+	p.must(p.cc.ApproveConfigProposal(p.as(c), pauseID))
+	p.wantStatus(a, pauseID, accesscontrol.ProposalStatusExecuted, 3)
+}
+
+func TestProposalFailedExecutionStaysPending(t *testing.T) {
+	p, a, b, c := newProposalTest(t)
+	d := p.newAccount()
+	deadline := p.now.Add(24 * time.Hour).Unix()
+
+	// 阈值超过管理员数量时执行失败，交易失败且提案保持待审批
+	thresholdID, err := p.cc.CreateConfigProposal(p.as(a), "SetApprovalThreshold", "4", deadline)
+	p.must(err)
+	wantErr(t, p.cc.ApproveConfigProposal(p.as(b), thresholdID), "failed to execute proposal "+thresholdID)
+	p.wantStatus(a, thresholdID, accesscontrol.ProposalStatusPending, 1)
+	cfg, err := p.cc.GetProjectConfig(p.as(a))
+	p.must(err)
+	if cfg.ApprovalThreshold != 2 {
+		t.Fatalf("got approval threshold %d, want 2", cfg.ApprovalThreshold)
+	}
+
+	// 增加第四个管理员后再次审批即可执行
+	nominateID, err := p.cc.CreateConfigProposal(p.as(a), "ProposeAdmin", d.account, deadline)
+	p.must(err)
+	p.must(p.cc.ApproveConfigProposal(p.as(c), nominateID))
+	p.must(p.cc.AcceptAdmin(p.as(d)))
+	p.must(p.cc.ApproveConfigProposal(p.as(b), thresholdID))
+	p.wantStatus(a, thresholdID, accesscontrol.ProposalStatusExecuted, 2)
+	cfg, err = p.cc.GetProjectConfig(p.as(a))
+	p.must(err)
+	if cfg.ApprovalThreshold != 4 {
+		t.Fatalf("got approval threshold %d, want 4", cfg.ApprovalThreshold)
+	}
+}
@@ -0,0 +1,395 @@
+package accesscontrol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// WriteQuota 写操作配额规则
+// 例如：账户每天最多调用RegisterDid 100次，角色成员每小时最多调用StoreVCHash 1000次
+type WriteQuota struct {
+	SubjectType string `json:"subjectType"` // 配额对象类型：account/role
+	Subject     string `json:"subject"`     // 配额对象：账户标识或角色名称
+	FuncName    string `json:"funcName"`    // 受限的写函数选择器
+	Limit       int    `json:"limit"`       // 每个周期内允许的调用次数
+	Period      string `json:"period"`      // 统计周期：hour/day
+}
+
+// QuotaStatus 账户某写函数的配额使用情况
+type QuotaStatus struct {
+	Source      string `json:"source"`      // 配额来源：account或role:{roleName}
+	Limit       int    `json:"limit"`       // 每个周期内允许的调用次数
+	Period      string `json:"period"`      // 统计周期：hour/day
+	Used        int    `json:"used"`        // 当前周期已使用次数
+	Remaining   int    `json:"remaining"`   // 当前周期剩余次数
+	WindowStart int64  `json:"windowStart"` // 当前周期开始时间（Unix秒，UTC对齐）
+	ResetAt     int64  `json:"resetAt"`     // 配额重置时间（Unix秒）
+}
+
+// quotaUsage 配额使用计数，周期变化时重新计数
+type quotaUsage struct {
+	WindowStart int64 `json:"windowStart"`
+	Count       int   `json:"count"`
+}
+
+const (
+	// quotaPrefix 配额规则在链上的存储键前缀
+	// 格式：permission:quota:{subjectType}:{subject}，值为 map[funcName]WriteQuota
+	quotaPrefix = "permission:quota:"
+
+	// quotaUsagePrefix 配额使用计数在链上的存储键前缀
+	// 格式：permission:quotaUsage:{account}:{funcName}:{source}
+	quotaUsagePrefix = "permission:quotaUsage:"
+
+	// quotaSubjectAccount 账户级配额
+	quotaSubjectAccount = "account"
+	// quotaSubjectRole 角色级配额
+	quotaSubjectRole = "role"
+)
+
+// quotaPeriods 支持的统计周期及其秒数
+var quotaPeriods = map[string]int64{
+	"hour": 3600,
+	"day":  86400,
+}
+
+// ================== 写操作配额相关 ==================
+
+// SetWriteQuota 设置账户或角色的写操作配额，同一对象同一函数重复设置会覆盖原配额
+// 配额按交易时间戳统计，周期按UTC对齐（整点/零点重置）
+// 生效规则：账户级配额优先；账户无该函数的配额时，其分配角色上的配额同时生效
+// 管理员不受配额限制
+// 权限要求：只有管理员可以调用此方法
+func (c *PermissionChaincode) SetWriteQuota(ctx contractapi.TransactionContextInterface, quota WriteQuota) error {
+	log.Printf("开始设置写操作配额 - 对象: %s:%s, 函数: %s, 次数: %d, 周期: %s", quota.SubjectType, quota.Subject, quota.FuncName, quota.Limit, quota.Period)
+	cfg, err := c.checkAdminOperation(ctx, "SetWriteQuota")
+	if err != nil {
+		return err
+	}
+	if err := c.validateQuotaSubject(ctx, quota.SubjectType, quota.Subject); err != nil {
+		log.Printf("参数校验失败 - %v", err)
+		return err
+	}
+	info := lookupSelector(quota.FuncName)
+	if info == nil || info.Type != selectorTypeWrite {
+		log.Printf("参数校验失败 - 函数不是已登记的写函数: %s", quota.FuncName)
+		return fmt.Errorf("%s is not a registered write selector", quota.FuncName)
+	}
+	if quota.Limit <= 0 {
+		log.Printf("参数校验失败 - 配额次数: %d", quota.Limit)
+		return errors.New("limit must be greater than 0")
+	}
+	if _, ok := quotaPeriods[quota.Period]; !ok {
+		log.Printf("参数校验失败 - 统计周期: %s", quota.Period)
+		return fmt.Errorf("invalid period %s, expected hour/day", quota.Period)
+	}
+
+	key := quotaPrefix + quota.SubjectType + ":" + quota.Subject
+	quotas, err := getQuotas(ctx, key)
+	if err != nil {
+		return err
+	}
+	var old *WriteQuota
+	if existing, ok := quotas[quota.FuncName]; ok {
+		old = &existing
+	}
+	quotas[quota.FuncName] = quota
+	if err := putQuotas(ctx, key, quotas); err != nil {
+		log.Printf("写操作配额存储失败: %v", err)
+		return err
+	}
+	log.Printf("写操作配额存储成功 - 对象: %s:%s, 函数: %s", quota.SubjectType, quota.Subject, quota.FuncName)
+	if err := recordAudit(ctx, "SetWriteQuota", key+":"+quota.FuncName, old, quota); err != nil {
+		return err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode": cfg.ServiceCode,
+		"projectCode": cfg.ProjectCode,
+		"quota":       quota,
+		"oldQuota":    old,
+		"sender":      common.GetCaller(ctx)})
+	return common.EmitEvent(ctx, "WriteQuotaSet", payload)
+}
+
+// RemoveWriteQuota 删除账户或角色在某写函数上的配额
+// 权限要求：只有管理员可以调用此方法
+func (c *PermissionChaincode) RemoveWriteQuota(ctx contractapi.TransactionContextInterface, subjectType, subject, funcName string) error {
+	log.Printf("开始删除写操作配额 - 对象: %s:%s, 函数: %s", subjectType, subject, funcName)
+	cfg, err := c.checkAdminOperation(ctx, "RemoveWriteQuota")
+	if err != nil {
+		return err
+	}
+	key := quotaPrefix + subjectType + ":" + subject
+	quotas, err := getQuotas(ctx, key)
+	if err != nil {
+		return err
+	}
+	old, ok := quotas[funcName]
+	if !ok {
+		log.Printf("写操作配额删除失败 - 配额不存在: %s:%s, 函数: %s", subjectType, subject, funcName)
+		return errors.New("write quota not found")
+	}
+	delete(quotas, funcName)
+	if err := putQuotas(ctx, key, quotas); err != nil {
+		log.Printf("写操作配额删除失败: %v", err)
+		return err
+	}
+	log.Printf("写操作配额删除成功 - 对象: %s:%s, 函数: %s", subjectType, subject, funcName)
+	if err := recordAudit(ctx, "RemoveWriteQuota", key+":"+funcName, old, nil); err != nil {
+		return err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode": cfg.ServiceCode,
+		"projectCode": cfg.ProjectCode,
+		"quota":       old,
+		"sender":      common.GetCaller(ctx)})
+	return common.EmitEvent(ctx, "WriteQuotaRemoved", payload)
+}
+
+// GetWriteQuotas 查询账户或角色上配置的所有写操作配额
+func (c *PermissionChaincode) GetWriteQuotas(ctx contractapi.TransactionContextInterface, subjectType, subject string) ([]WriteQuota, error) {
+	if subjectType != quotaSubjectAccount && subjectType != quotaSubjectRole {
+		return nil, fmt.Errorf("invalid subjectType %s, expected account/role", subjectType)
+	}
+	if strings.TrimSpace(subject) == "" {
+		return nil, errors.New("subject cannot be empty")
+	}
+	if err := c.checkNotPaused(ctx); err != nil {
+		return nil, err
+	}
+	quotas, err := getQuotas(ctx, quotaPrefix+subjectType+":"+subject)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]WriteQuota, 0, len(quotas))
+	for _, quota := range quotas {
+		result = append(result, quota)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].FuncName < result[j].FuncName })
+	return result, nil
+}
+
+// GetRemainingQuota 查询账户在某写函数上的剩余配额，按交易时间计算当前周期
+// 返回空列表表示该账户在该函数上不受配额限制
+func (c *PermissionChaincode) GetRemainingQuota(ctx contractapi.TransactionContextInterface, account, funcName string) ([]QuotaStatus, error) {
+	if strings.TrimSpace(account) == "" || strings.TrimSpace(funcName) == "" {
+		return nil, errors.New("account and funcName cannot be empty")
+	}
+	if err := c.checkNotPaused(ctx); err != nil {
+		return nil, err
+	}
+	statuses, _, err := c.getQuotaStatuses(ctx, account, funcName)
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// ConsumeWriteQuota 实现PermissionChecker接口：累加当前调用者在写函数上的配额使用次数，超出配额时返回错误
+// 只对调用者本人计数，由BeforeTransaction在策略校验通过后调用；管理员不受配额限制
+// 该方法会写入状态，通过GetIgnoredFunctions从合约交易中排除，不能被外部直接调用
+func (c *PermissionChaincode) ConsumeWriteQuota(ctx contractapi.TransactionContextInterface, funcName string) error {
+	caller := common.GetCaller(ctx)
+	isAdmin, err := c.IsAdminRole(ctx, caller)
+	if err != nil {
+		return err
+	}
+	if isAdmin {
+		return nil
+	}
+	return c.consumeWriteQuota(ctx, caller, funcName)
+}
+
+// ================== 内部方法 ==================
+
+// checkWriteQuota 内部方法：校验账户在当前周期内未超出配额，不累加使用次数
+func (c *PermissionChaincode) checkWriteQuota(ctx contractapi.TransactionContextInterface, account, funcName string) error {
+	statuses, _, err := c.getQuotaStatuses(ctx, account, funcName)
+	if err != nil {
+		return err
+	}
+	return quotaExceeded(account, funcName, statuses)
+}
+
+// consumeWriteQuota 内部方法：校验账户在当前周期内未超出配额并累加使用次数
+// 同一账户在同一区块内的并发调用会因读写冲突而失效，配额不会被超额使用
+func (c *PermissionChaincode) consumeWriteQuota(ctx contractapi.TransactionContextInterface, account, funcName string) error {
+	statuses, keys, err := c.getQuotaStatuses(ctx, account, funcName)
+	if err != nil {
+		return err
+	}
+	if err := quotaExceeded(account, funcName, statuses); err != nil {
+		return err
+	}
+	for i, status := range statuses {
+		b, _ := json.Marshal(quotaUsage{WindowStart: status.WindowStart, Count: status.Used + 1})
+		if err := ctx.GetStub().PutState(keys[i], b); err != nil {
+			log.Printf("配额使用计数存储失败: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// quotaExceeded 任一生效配额在当前周期内已用完时返回错误
+func quotaExceeded(account, funcName string, statuses []QuotaStatus) error {
+	for _, status := range statuses {
+		if status.Remaining <= 0 {
+			log.Printf("配额校验失败 - 账户: %s, 函数: %s, 来源: %s, 配额: %d/%s", account, funcName, status.Source, status.Limit, status.Period)
+			return fmt.Errorf("write quota exceeded for %s: %d per %s (%s), resets at %d", funcName, status.Limit, status.Period, status.Source, status.ResetAt)
+		}
+	}
+	return nil
+}
+
+// getQuotaStatuses 内部方法：计算账户在某写函数上生效的配额及使用情况，同时返回对应的使用计数存储键
+func (c *PermissionChaincode) getQuotaStatuses(ctx contractapi.TransactionContextInterface, account, funcName string) ([]QuotaStatus, []string, error) {
+	type sourcedQuota struct {
+		source string
+		quota  WriteQuota
+	}
+	applicable := make([]sourcedQuota, 0)
+
+	accountQuotas, err := getQuotas(ctx, quotaPrefix+quotaSubjectAccount+":"+account)
+	if err != nil {
+		return nil, nil, err
+	}
+	if quota, ok := accountQuotas[funcName]; ok {
+		applicable = append(applicable, sourcedQuota{source: quotaSubjectAccount, quota: quota})
+	} else {
+		roles, err := getStringSet(ctx, accountRolePrefix+account)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, roleName := range sortedKeys(roles) {
+			roleQuotas, err := getQuotas(ctx, quotaPrefix+quotaSubjectRole+":"+roleName)
+			if err != nil {
+				return nil, nil, err
+			}
+			if quota, ok := roleQuotas[funcName]; ok {
+				applicable = append(applicable, sourcedQuota{source: quotaSubjectRole + ":" + roleName, quota: quota})
+			}
+		}
+	}
+	statuses := make([]QuotaStatus, 0, len(applicable))
+	keys := make([]string, 0, len(applicable))
+	if len(applicable) == 0 {
+		return statuses, keys, nil
+	}
+
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := txTime.Unix()
+	for _, item := range applicable {
+		seconds := quotaPeriods[item.quota.Period]
+		windowStart := now - now%seconds
+		key := quotaUsagePrefix + account + ":" + funcName + ":" + item.source
+		b, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		used := 0
+		if b != nil {
+			var usage quotaUsage
+			if err := json.Unmarshal(b, &usage); err != nil {
+				return nil, nil, err
+			}
+			// 周期变化后重新计数
+			if usage.WindowStart == windowStart {
+				used = usage.Count
+			}
+		}
+		remaining := item.quota.Limit - used
+		if remaining < 0 {
+			remaining = 0
+		}
+		statuses = append(statuses, QuotaStatus{
+			Source:      item.source,
+			Limit:       item.quota.Limit,
+			Period:      item.quota.Period,
+			Used:        used,
+			Remaining:   remaining,
+			WindowStart: windowStart,
+			ResetAt:     windowStart + seconds,
+		})
+		keys = append(keys, key)
+	}
+	return statuses, keys, nil
+}
+
+// validateQuotaSubject 内部方法：校验配额对象类型及对象存在
+func (c *PermissionChaincode) validateQuotaSubject(ctx contractapi.TransactionContextInterface, subjectType, subject string) error {
+	switch subjectType {
+	case quotaSubjectAccount:
+		return common.ValidateAccount(subject)
+	case quotaSubjectRole:
+		role, err := c.getRole(ctx, subject)
+		if err != nil {
+			return err
+		}
+		if role == nil {
+			return fmt.Errorf("role %s not found", subject)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid subjectType %s, expected account/role", subjectType)
+	}
+}
+
+// getQuotas 内部方法：获取配额规则映射，不存在时返回空映射
+func getQuotas(ctx contractapi.TransactionContextInterface, key string) (map[string]WriteQuota, error) {
+	quotas := make(map[string]WriteQuota)
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return quotas, nil
+	}
+	if err := json.Unmarshal(b, &quotas); err != nil {
+		return nil, err
+	}
+	return quotas, nil
+}
+
+// putQuotas 内部方法：存储配额规则映射，为空时删除该键
+func putQuotas(ctx contractapi.TransactionContextInterface, key string, quotas map[string]WriteQuota) error {
+	if len(quotas) == 0 {
+		return ctx.GetStub().DelState(key)
+	}
+	b, _ := json.Marshal(quotas)
+	return ctx.GetStub().PutState(key, b)
+}
+
+// deleteRoleQuotas 内部方法：删除角色上的配额规则及其成员按该角色统计的使用计数，避免同名角色重建后继承原配额
+func deleteRoleQuotas(ctx contractapi.TransactionContextInterface, roleName string, members map[string]bool) error {
+	key := quotaPrefix + quotaSubjectRole + ":" + roleName
+	quotas, err := getQuotas(ctx, key)
+	if err != nil {
+		return err
+	}
+	if len(quotas) == 0 {
+		return nil
+	}
+	source := quotaSubjectRole + ":" + roleName
+	for account := range members {
+		for funcName := range quotas {
+			if err := ctx.GetStub().DelState(quotaUsagePrefix + account + ":" + funcName + ":" + source); err != nil {
+				return err
+			}
+		}
+	}
+	return ctx.GetStub().DelState(key)
+}
@@ -0,0 +1,143 @@
+package accesscontrol_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"sbp-did-chaincode/accesscontrol"
+)
+
+// hourStart 整点对齐的时间，作为配额周期的起点
+var hourStart = time.Unix(1700000000-1700000000%3600, 0)
+
+// newQuotaTest 初始化项目，返回管理员和普通账户
+func newQuotaTest(t *testing.T) (p *permissionTest, admin, user testAccount) {
+	p = newPermissionTest(t)
+	admin, user = p.newAccount(), p.newAccount()
+	p.must(p.cc.InitProject(p.as(admin), "sbp", false, false, false, false, "svc", "proj", false))
+	return p, admin, user
+}
+
+// remaining 查询账户在t时刻的配额使用情况
+func (p *permissionTest) remaining(a testAccount, t time.Time, funcName string) []accesscontrol.QuotaStatus {
+	p.t.Helper()
+	statuses, err := p.cc.GetRemainingQuota(p.at(a, t), a.account, funcName)
+	p.must(err)
+	return statuses
+}
+
+func TestWriteQuotaWindow(t *testing.T) {
+	p, admin, user := newQuotaTest(t)
+	p.must(p.cc.SetWriteQuota(p.as(admin), accesscontrol.WriteQuota{
+		SubjectType: "account", Subject: user.account, FuncName: "RegisterDid", Limit: 2, Period: "hour",
+	}))
+
+	// 周期内前两次调用通过，第三次被拒绝
+	for _, offset := range []time.Duration{10 * time.Minute, 20 * time.Minute} {
+		p.must(p.cc.ConsumeWriteQuota(p.at(user, hourStart.Add(offset)), "RegisterDid"))
+	}
+	lastSecond := hourStart.Add(time.Hour - time.Second)
+	err := p.cc.ConsumeWriteQuota(p.at(user, lastSecond), "RegisterDid")
+	resetAt := hourStart.Add(time.Hour).Unix()
+	wantErr(t, err, "write quota exceeded for RegisterDid: 2 per hour (account), resets at "+strconv.FormatInt(resetAt, 10))
+	want := accesscontrol.QuotaStatus{
+		Source: "account", Limit: 2, Period: "hour", Used: 2, Remaining: 0, WindowStart: hourStart.Unix(), ResetAt: resetAt,
+	}
+	if statuses := p.remaining(user, lastSecond, "RegisterDid"); len(statuses) != 1 || statuses[0] != want {
+		t.Fatalf("got statuses %+v, want %+v", statuses, want)
+	}
+
+	// 到达ResetAt时进入新周期，重新计数
+	p.must(p.cc.ConsumeWriteQuota(p.at(user, time.Unix(resetAt, 0)), "RegisterDid"))
+	want = accesscontrol.QuotaStatus{
+		Source: "account", Limit: 2, Period: "hour", Used: 1, Remaining: 1, WindowStart: resetAt, ResetAt: resetAt + 3600,
+	}
+	if statuses := p.remaining(user, time.Unix(resetAt, 0), "RegisterDid"); len(statuses) != 1 || statuses[0] != want {
+		t.Fatalf("got statuses %+v, want %+v", statuses, want)
+	}
+
+	// 其他函数及管理员不受限制
+	if statuses := p.remaining(user, lastSecond, "UpdateDidDocument"); len(statuses) != 0 {
+		t.Fatalf("got statuses %+v for a function without quota", statuses)
+	}
+	for i := 0; i < 3; i++ {
+		p.must(p.cc.ConsumeWriteQuota(p.at(admin, lastSecond), "RegisterDid"))
+	}
+}
+
+func TestWriteQuotaDayWindowAlignment(t *testing.T) {
+	p, admin, user := newQuotaTest(t)
+	p.must(p.cc.SetWriteQuota(p.as(admin), accesscontrol.WriteQuota{
+		SubjectType: "account", Subject: user.account, FuncName: "RegisterDid", Limit: 1, Period: "day",
+	}))
+	// 周期按UTC零点对齐，与调用时刻无关
+	at := time.Date(2023, 11, 14, 23, 59, 59, 0, time.UTC)
+	midnight := time.Date(2023, 11, 14, 0, 0, 0, 0, time.UTC).Unix()
+	statuses := p.remaining(user, at, "RegisterDid")
+	if len(statuses) != 1 || statuses[0].WindowStart != midnight || statuses[0].ResetAt != midnight+86400 {
+		t.Fatalf("got statuses %+v, want window [%d, %d)", statuses, midnight, midnight+86400)
+	}
+	p.must(p.cc.ConsumeWriteQuota(p.at(user, at), "RegisterDid"))
+	wantErr(t, p.cc.ConsumeWriteQuota(p.at(user, at), "RegisterDid"), "write quota exceeded")
+	p.must(p.cc.ConsumeWriteQuota(p.at(user, time.Unix(midnight+86400, 0)), "RegisterDid"))
+}
+
+func TestWriteQuotaAccountOverridesRole(t *testing.T) {
+	p, admin, user := newQuotaTest(t)
+	member := p.newAccount()
+	p.must(p.cc.CreateRole(p.as(admin), "writer", "", []string{"RegisterDid"}))
+	for _, a := range []testAccount{user, member} {
+		p.must(p.cc.AssignRoles(p.as(admin), a.account, []string{"writer"}))
+	}
+	p.must(p.cc.SetWriteQuota(p.as(admin), accesscontrol.WriteQuota{
+		SubjectType: "role", Subject: "writer", FuncName: "RegisterDid", Limit: 1, Period: "hour",
+	}))
+	p.must(p.cc.SetWriteQuota(p.as(admin), accesscontrol.WriteQuota{
+		SubjectType: "account", Subject: user.account, FuncName: "RegisterDid", Limit: 3, Period: "hour",
+	}))
+
+	at := hourStart.Add(time.Minute)
+	for i := 0; i < 3; i++ {
+		p.must(p.cc.ConsumeWriteQuota(p.at(user, at), "RegisterDid"))
+	}
+	wantErr(t, p.cc.ConsumeWriteQuota(p.at(user, at), "RegisterDid"), "(account)")
+	if statuses := p.remaining(user, at, "RegisterDid"); len(statuses) != 1 || statuses[0].Source != "account" || statuses[0].Used != 3 {
+		t.Fatalf("got statuses %+v, want only the account quota", statuses)
+	}
+
+	// 没有账户级配额的成员按角色配额计数
+	p.must(p.cc.ConsumeWriteQuota(p.at(member, at), "RegisterDid"))
+	wantErr(t, p.cc.ConsumeWriteQuota(p.at(member, at), "RegisterDid"), "(role:writer)")
+	if statuses := p.remaining(member, at, "RegisterDid"); len(statuses) != 1 || statuses[0].Source != "role:writer" || statuses[0].Used != 1 {
+		t.Fatalf("got statuses %+v, want the role quota", statuses)
+	}
+}
+
+func TestDeleteRoleRemovesQuotas(t *testing.T) {
+	p, admin, member := newQuotaTest(t)
+	roleQuota := accesscontrol.WriteQuota{SubjectType: "role", Subject: "writer", FuncName: "RegisterDid", Limit: 1, Period: "hour"}
+	p.must(p.cc.CreateRole(p.as(admin), "writer", "", []string{"RegisterDid"}))
+	p.must(p.cc.AssignRoles(p.as(admin), member.account, []string{"writer"}))
+	p.must(p.cc.SetWriteQuota(p.as(admin), roleQuota))
+	at := hourStart.Add(time.Minute)
+	p.must(p.cc.ConsumeWriteQuota(p.at(member, at), "RegisterDid"))
+
+	p.must(p.cc.DeleteRole(p.as(admin), "writer"))
+	if quotas, err := p.cc.GetWriteQuotas(p.as(admin), "role", "writer"); err != nil || len(quotas) != 0 {
+		t.Fatalf("got quotas %+v, %v after DeleteRole", quotas, err)
+	}
+
+	// 同名角色重建后不继承原配额，原使用计数也已删除
+	p.must(p.cc.CreateRole(p.as(admin), "writer", "", []string{"RegisterDid"}))
+	p.must(p.cc.AssignRoles(p.as(admin), member.account, []string{"writer"}))
+	if statuses := p.remaining(member, at, "RegisterDid"); len(statuses) != 0 {
+		t.Fatalf("got statuses %+v, recreated role must not inherit quotas", statuses)
+	}
+	p.must(p.cc.SetWriteQuota(p.as(admin), roleQuota))
+	statuses := p.remaining(member, at, "RegisterDid")
+	if len(statuses) != 1 || statuses[0].Used != 0 {
+		t.Fatalf("got statuses %+v, want usage reset after DeleteRole", statuses)
+	}
+	p.must(p.cc.ConsumeWriteQuota(p.at(member, at), "RegisterDid"))
+}
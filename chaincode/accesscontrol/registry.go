@@ -0,0 +1,139 @@
+package accesscontrol
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// SelectorInfo 可授权的函数选择器登记信息
+type SelectorInfo struct {
+	Selector string `json:"selector"` // 函数选择器，即被权限校验的函数名
+	Contract string `json:"contract"` // 所属合约：did/issuer/vc/permission
+	Module   string `json:"module"`   // 所属业务模块：did/issuer/template/vc/permission，用于按模块停用
+	Type     string `json:"type"`     // 选择器类型：read/write
+}
+
+const (
+	// selectorTypeRead 查询类函数选择器，仅在私有项目中需要授权
+	selectorTypeRead = "read"
+	// selectorTypeWrite 写入类函数选择器，开启写权限控制后需要授权
+	selectorTypeWrite = "write"
+
+	// selectorWildcardSuffix 合约通配选择器后缀，如 issuer:* 表示授权issuer合约的所有函数
+	selectorWildcardSuffix = ":*"
+)
+
+// selectorRegistry 可授权函数选择器登记表
+// 仅登记会调用CheckWriteFuncSelectorPermission/CheckQueryFuncSelectorPermission进行权限校验的函数
+// did/issuer/vc合约的函数通过策略表（policy.go）引用这里的选择器，新增函数时必须同步登记，否则无法对其授权
+var selectorRegistry = []SelectorInfo{
+	// did合约
+	{Selector: "RegisterDid", Contract: "did", Module: common.ModuleDID, Type: selectorTypeWrite},
+	{Selector: "UpdateDidDocument", Contract: "did", Module: common.ModuleDID, Type: selectorTypeWrite},
+	{Selector: "UpdateDidDocumentWithSignature", Contract: "did", Module: common.ModuleDID, Type: selectorTypeWrite},
+	{Selector: "DeactivateDid", Contract: "did", Module: common.ModuleDID, Type: selectorTypeWrite},
+	{Selector: "GetDidInfo", Contract: "did", Module: common.ModuleDID, Type: selectorTypeRead},
+	{Selector: "ResolveDid", Contract: "did", Module: common.ModuleDID, Type: selectorTypeRead},
+	{Selector: "ResolveDidAt", Contract: "did", Module: common.ModuleDID, Type: selectorTypeRead},
+	{Selector: "GetDidHistory", Contract: "did", Module: common.ModuleDID, Type: selectorTypeRead},
+	{Selector: "GetDidNonce", Contract: "did", Module: common.ModuleDID, Type: selectorTypeRead},
+	{Selector: "CheckDid", Contract: "did", Module: common.ModuleDID, Type: selectorTypeRead},
+
+	// issuer合约
+	{Selector: "RegisterIssuer", Contract: "issuer", Module: common.ModuleIssuer, Type: selectorTypeWrite},
+	{Selector: "UpdateIssuer", Contract: "issuer", Module: common.ModuleIssuer, Type: selectorTypeWrite},
+	{Selector: "ChangeIssuerStatus", Contract: "issuer", Module: common.ModuleIssuer, Type: selectorTypeWrite},
+	{Selector: "GetIssuerInfo", Contract: "issuer", Module: common.ModuleIssuer, Type: selectorTypeRead},
+	{Selector: "CheckIssuer", Contract: "issuer", Module: common.ModuleIssuer, Type: selectorTypeRead},
+	{Selector: "RegisterVCTemplate", Contract: "issuer", Module: common.ModuleTemplate, Type: selectorTypeWrite},
+	{Selector: "UpdateVCTemplate", Contract: "issuer", Module: common.ModuleTemplate, Type: selectorTypeWrite},
+	{Selector: "ChangeVCTemplateStatus", Contract: "issuer", Module: common.ModuleTemplate, Type: selectorTypeWrite},
+	{Selector: "GetVCTemplateInfo", Contract: "issuer", Module: common.ModuleTemplate, Type: selectorTypeRead},
+	{Selector: "CheckVCTemplate", Contract: "issuer", Module: common.ModuleTemplate, Type: selectorTypeRead},
+
+	// vc合约
+	{Selector: "StoreVCHash", Contract: "vc", Module: common.ModuleVC, Type: selectorTypeWrite},
+	{Selector: "RevokedVC", Contract: "vc", Module: common.ModuleVC, Type: selectorTypeWrite},
+	{Selector: "GetVCInfo", Contract: "vc", Module: common.ModuleVC, Type: selectorTypeRead},
+	{Selector: "GetVCRevokedStatus", Contract: "vc", Module: common.ModuleVC, Type: selectorTypeRead},
+
+	// permission合约
+	{Selector: "ListAccountsWithPermissions", Contract: "permission", Module: "permission", Type: selectorTypeRead},
+	{Selector: "ListAccountsForSelector", Contract: "permission", Module: "permission", Type: selectorTypeRead},
+	{Selector: "QueryAuditRecords", Contract: "permission", Module: "permission", Type: selectorTypeRead},
+	{Selector: "GetProjectConfigView", Contract: "permission", Module: "permission", Type: selectorTypeRead},
+	{Selector: "GetProjectConfigHistory", Contract: "permission", Module: "permission", Type: selectorTypeRead},
+}
+
+// ================== 函数选择器登记表相关 ==================
+
+// GetSelectorRegistry 查询可授权的函数选择器登记表
+// 参数说明：
+// - contract: 合约名称（did/issuer/vc/permission），为空时返回所有合约的函数选择器
+func (c *PermissionChaincode) GetSelectorRegistry(ctx contractapi.TransactionContextInterface, contract string) ([]SelectorInfo, error) {
+	if contract != "" && !isRegisteredContract(contract) {
+		return nil, fmt.Errorf("unknown contract %s", contract)
+	}
+	selectors := make([]SelectorInfo, 0, len(selectorRegistry))
+	for _, info := range selectorRegistry {
+		if contract == "" || info.Contract == contract {
+			selectors = append(selectors, info)
+		}
+	}
+	return selectors, nil
+}
+
+// ================== 内部方法 ==================
+
+// lookupSelector 查询函数选择器登记信息，未登记时返回nil
+func lookupSelector(selector string) *SelectorInfo {
+	for i := range selectorRegistry {
+		if selectorRegistry[i].Selector == selector {
+			return &selectorRegistry[i]
+		}
+	}
+	return nil
+}
+
+// isRegisteredContract 判断合约是否在登记表中
+func isRegisteredContract(contract string) bool {
+	for _, info := range selectorRegistry {
+		if info.Contract == contract {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSelector 校验授权的函数选择器已登记，或为已登记合约的通配选择器（如 issuer:*）
+func validateSelector(selector string) error {
+	if strings.TrimSpace(selector) == "" {
+		return errors.New("funcName cannot be empty")
+	}
+	if strings.HasSuffix(selector, selectorWildcardSuffix) {
+		if contract := strings.TrimSuffix(selector, selectorWildcardSuffix); isRegisteredContract(contract) {
+			return nil
+		}
+		return fmt.Errorf("unknown contract in wildcard selector %s", selector)
+	}
+	if lookupSelector(selector) == nil {
+		return fmt.Errorf("unknown selector %s", selector)
+	}
+	return nil
+}
+
+// selectorGranted 判断权限集合是否包含该函数选择器，或包含其所属合约的通配选择器
+func selectorGranted(selectors map[string]bool, selector string) bool {
+	if selectors[selector] {
+		return true
+	}
+	if info := lookupSelector(selector); info != nil {
+		return selectors[info.Contract+selectorWildcardSuffix]
+	}
+	return false
+}
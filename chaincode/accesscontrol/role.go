@@ -0,0 +1,492 @@
+package accesscontrol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// RoleInfo 角色信息结构体
+// 角色是一组函数选择器的集合，账户被分配角色后即拥有角色内的全部函数权限
+type RoleInfo struct {
+	Name        string   `json:"name"`        // 角色名称，如 did-operator、issuer-manager、vc-auditor
+	Description string   `json:"description"` // 角色描述
+	FuncNames   []string `json:"funcNames"`   // 角色包含的函数名列表
+}
+
+const (
+	// roleInfoPrefix 角色信息在链上的存储键前缀
+	// 格式：permission:role:{roleName}
+	roleInfoPrefix = "permission:role:"
+
+	// accountRolePrefix 账户已分配角色在链上的存储键前缀
+	// 格式：permission:accountRole:{account}
+	accountRolePrefix = "permission:accountRole:"
+
+	// roleMemberPrefix 角色下已分配账户在链上的存储键前缀，用于删除角色时级联解除分配
+	// 格式：permission:roleMember:{roleName}
+	roleMemberPrefix = "permission:roleMember:"
+)
+
+// ================== 角色管理相关 ==================
+
+// CreateRole 创建角色
+// 权限要求：只有管理员可以调用此方法
+func (c *PermissionChaincode) CreateRole(ctx contractapi.TransactionContextInterface, roleName, description string, funcNames []string) error {
+	log.Printf("开始创建角色 - 角色: %s, 函数数量: %d", roleName, len(funcNames))
+	cfg, err := c.checkAdminOperation(ctx, "CreateRole")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(roleName) == "" {
+		log.Printf("参数校验失败 - 角色名称为空")
+		return errors.New("roleName cannot be empty")
+	}
+	selectors, err := normalizeFuncNames(funcNames)
+	if err != nil {
+		log.Printf("参数校验失败 - %v", err)
+		return err
+	}
+
+	role, err := c.getRole(ctx, roleName)
+	if err != nil {
+		return err
+	}
+	if role != nil {
+		log.Printf("角色创建失败 - 角色已存在: %s", roleName)
+		return errors.New("role already exists")
+	}
+
+	role = &RoleInfo{
+		Name:        roleName,
+		Description: description,
+		FuncNames:   selectors,
+	}
+	if err := c.putRole(ctx, role); err != nil {
+		log.Printf("角色信息存储失败: %v", err)
+		return err
+	}
+	log.Printf("角色信息存储成功 - 角色: %s, 函数: %v", roleName, selectors)
+	if err := recordAudit(ctx, "CreateRole", roleName, nil, role); err != nil {
+		return err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode": cfg.ServiceCode,
+		"projectCode": cfg.ProjectCode,
+		"role":        role,
+		"sender":      common.GetCaller(ctx)})
+	return common.EmitEvent(ctx, "RoleCreated", payload)
+}
+
+// UpdateRole 更新角色的描述和函数列表，已分配该角色的账户权限随之变化
+// 权限要求：只有管理员可以调用此方法
+func (c *PermissionChaincode) UpdateRole(ctx contractapi.TransactionContextInterface, roleName, description string, funcNames []string) error {
+	log.Printf("开始更新角色 - 角色: %s, 函数数量: %d", roleName, len(funcNames))
+	cfg, err := c.checkAdminOperation(ctx, "UpdateRole")
+	if err != nil {
+		return err
+	}
+	selectors, err := normalizeFuncNames(funcNames)
+	if err != nil {
+		log.Printf("参数校验失败 - %v", err)
+		return err
+	}
+
+	role, err := c.getRole(ctx, roleName)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		log.Printf("角色更新失败 - 角色不存在: %s", roleName)
+		return errors.New("role not found")
+	}
+
+	oldRole := *role
+	oldFuncNames := role.FuncNames
+	role.Description = description
+	role.FuncNames = selectors
+	if err := c.putRole(ctx, role); err != nil {
+		log.Printf("角色信息更新失败: %v", err)
+		return err
+	}
+	log.Printf("角色信息更新成功 - 角色: %s, 原函数: %v, 新函数: %v", roleName, oldFuncNames, selectors)
+	if err := recordAudit(ctx, "UpdateRole", roleName, oldRole, role); err != nil {
+		return err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode":  cfg.ServiceCode,
+		"projectCode":  cfg.ProjectCode,
+		"role":         role,
+		"oldFuncNames": oldFuncNames,
+		"sender":       common.GetCaller(ctx)})
+	return common.EmitEvent(ctx, "RoleUpdated", payload)
+}
+
+// DeleteRole 删除角色，并级联解除所有账户对该角色的分配
+// 权限要求：只有管理员可以调用此方法
+func (c *PermissionChaincode) DeleteRole(ctx contractapi.TransactionContextInterface, roleName string) error {
+	log.Printf("开始删除角色 - 角色: %s", roleName)
+	cfg, err := c.checkAdminOperation(ctx, "DeleteRole")
+	if err != nil {
+		return err
+	}
+
+	role, err := c.getRole(ctx, roleName)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		log.Printf("角色删除失败 - 角色不存在: %s", roleName)
+		return errors.New("role not found")
+	}
+
+	members, err := getStringSet(ctx, roleMemberPrefix+roleName)
+	if err != nil {
+		return err
+	}
+	for account := range members {
+		roles, err := getStringSet(ctx, accountRolePrefix+account)
+		if err != nil {
+			return err
+		}
+		delete(roles, roleName)
+		if err := putStringSet(ctx, accountRolePrefix+account, roles); err != nil {
+			log.Printf("解除账户角色分配失败 - 账户: %s, 错误: %v", account, err)
+			return err
+		}
+	}
+	if err := ctx.GetStub().DelState(roleMemberPrefix + roleName); err != nil {
+		return err
+	}
+	// 删除角色上的配额规则及成员的使用计数，避免同名角色重建后继承原配额
+	if err := deleteRoleQuotas(ctx, roleName, members); err != nil {
+		log.Printf("角色配额删除失败: %v", err)
+		return err
+	}
+	// 同时从证书属性策略中移除该角色，避免同名角色重建后被自动授予
+	if err := c.removeRoleFromAttributePolicies(ctx, roleName); err != nil {
+		log.Printf("证书属性策略更新失败: %v", err)
+		return err
+	}
+	if err := ctx.GetStub().DelState(roleInfoPrefix + roleName); err != nil {
+		log.Printf("角色信息删除失败: %v", err)
+		return err
+	}
+	log.Printf("角色删除成功 - 角色: %s, 解除分配账户数量: %d", roleName, len(members))
+	oldValue := map[string]interface{}{"role": role, "accounts": sortedKeys(members)}
+	if err := recordAudit(ctx, "DeleteRole", roleName, oldValue, nil); err != nil {
+		return err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode": cfg.ServiceCode,
+		"projectCode": cfg.ProjectCode,
+		"roleName":    roleName,
+		"accounts":    sortedKeys(members),
+		"sender":      common.GetCaller(ctx)})
+	return common.EmitEvent(ctx, "RoleDeleted", payload)
+}
+
+// AssignRoles 为账户分配角色
+// 权限要求：只有管理员可以调用此方法
+func (c *PermissionChaincode) AssignRoles(ctx contractapi.TransactionContextInterface, account string, roleNames []string) error {
+	return c.operateAccountRoles(ctx, account, roleNames, false)
+}
+
+// UnassignRoles 解除账户的角色分配
+// 权限要求：只有管理员可以调用此方法
+func (c *PermissionChaincode) UnassignRoles(ctx contractapi.TransactionContextInterface, account string, roleNames []string) error {
+	return c.operateAccountRoles(ctx, account, roleNames, true)
+}
+
+// GetRole 查询角色信息
+func (c *PermissionChaincode) GetRole(ctx contractapi.TransactionContextInterface, roleName string) (*RoleInfo, error) {
+	if strings.TrimSpace(roleName) == "" {
+		return nil, errors.New("roleName cannot be empty")
+	}
+	if err := c.checkNotPaused(ctx); err != nil {
+		return nil, err
+	}
+	role, err := c.getRole(ctx, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, errors.New("role not found")
+	}
+	return role, nil
+}
+
+// GetRolesForUser 查询账户已分配的角色列表
+func (c *PermissionChaincode) GetRolesForUser(ctx contractapi.TransactionContextInterface, account string) ([]string, error) {
+	if strings.TrimSpace(account) == "" {
+		return nil, errors.New("account cannot be empty")
+	}
+	if err := c.checkNotPaused(ctx); err != nil {
+		return nil, err
+	}
+	roles, err := getStringSet(ctx, accountRolePrefix+account)
+	if err != nil {
+		return nil, err
+	}
+	return sortedKeys(roles), nil
+}
+
+// GetEffectiveSelectorsForUser 查询账户的有效函数权限（直接授权 + 组织授权 + 角色授权 + 委托权限）
+// 查询调用者本人时还包含证书属性策略授予的权限
+func (c *PermissionChaincode) GetEffectiveSelectorsForUser(ctx contractapi.TransactionContextInterface, account string) ([]string, error) {
+	if strings.TrimSpace(account) == "" {
+		return nil, errors.New("account cannot be empty")
+	}
+	if err := c.checkNotPaused(ctx); err != nil {
+		return nil, err
+	}
+	selectors, err := c.getEffectiveSelectors(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	return sortedKeys(selectors), nil
+}
+
+// ================== 内部方法 ==================
+
+// operateAccountRoles 分配/解除账户角色
+func (c *PermissionChaincode) operateAccountRoles(ctx contractapi.TransactionContextInterface, account string, roleNames []string, isRevoke bool) error {
+	operation := "AssignRoles"
+	if isRevoke {
+		operation = "UnassignRoles"
+	}
+	log.Printf("开始操作账户角色 - 账户: %s, 角色: %v, 操作: %s", account, roleNames, operation)
+	cfg, err := c.checkAdminOperation(ctx, operation)
+	if err != nil {
+		return err
+	}
+	if err := common.ValidateAccount(account); err != nil {
+		log.Printf("参数校验失败 - %v", err)
+		return err
+	}
+	if len(roleNames) == 0 {
+		log.Printf("参数校验失败 - 角色列表为空")
+		return errors.New("roleNames cannot be empty")
+	}
+
+	roles, err := getStringSet(ctx, accountRolePrefix+account)
+	if err != nil {
+		return err
+	}
+	oldRoles := sortedKeys(roles)
+	for _, roleName := range roleNames {
+		if isRevoke {
+			if !roles[roleName] {
+				log.Printf("参数校验失败 - 账户: %s 未分配角色: %s", account, roleName)
+				return fmt.Errorf("role %s is not assigned to account %s", roleName, account)
+			}
+			delete(roles, roleName)
+		} else {
+			role, err := c.getRole(ctx, roleName)
+			if err != nil {
+				return err
+			}
+			if role == nil {
+				log.Printf("参数校验失败 - 角色不存在: %s", roleName)
+				return fmt.Errorf("role %s not found", roleName)
+			}
+			roles[roleName] = true
+		}
+
+		memberKey := roleMemberPrefix + roleName
+		members, err := getStringSet(ctx, memberKey)
+		if err != nil {
+			return err
+		}
+		if isRevoke {
+			delete(members, account)
+		} else {
+			members[account] = true
+		}
+		if err := putStringSet(ctx, memberKey, members); err != nil {
+			log.Printf("角色成员存储失败 - 角色: %s, 错误: %v", roleName, err)
+			return err
+		}
+	}
+	if err := putStringSet(ctx, accountRolePrefix+account, roles); err != nil {
+		log.Printf("账户角色存储失败 - 账户: %s, 错误: %v", account, err)
+		return err
+	}
+	log.Printf("账户角色操作成功 - 账户: %s, 当前角色: %v", account, sortedKeys(roles))
+	if err := recordAudit(ctx, operation, account, oldRoles, sortedKeys(roles)); err != nil {
+		return err
+	}
+
+	eventName := "RoleAssigned"
+	if isRevoke {
+		eventName = "RoleUnassigned"
+	}
+	payload, _ := json.Marshal(map[string]interface{}{
+		"serviceCode": cfg.ServiceCode,
+		"projectCode": cfg.ProjectCode,
+		"account":     account,
+		"roleNames":   roleNames,
+		"isRevoked":   isRevoke,
+		"sender":      common.GetCaller(ctx)})
+	return common.EmitEvent(ctx, eventName, payload)
+}
+
+// getRole 内部方法：获取角色信息，角色不存在时返回nil
+func (c *PermissionChaincode) getRole(ctx contractapi.TransactionContextInterface, roleName string) (*RoleInfo, error) {
+	b, err := ctx.GetStub().GetState(roleInfoPrefix + roleName)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var role RoleInfo
+	if err := json.Unmarshal(b, &role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// putRole 内部方法：存储角色信息
+func (c *PermissionChaincode) putRole(ctx contractapi.TransactionContextInterface, role *RoleInfo) error {
+	b, _ := json.Marshal(role)
+	return ctx.GetStub().PutState(roleInfoPrefix+role.Name, b)
+}
+
+// getEffectiveSelectors 内部方法：合并账户直接授权、组织授权、角色授权、证书属性策略以及委托授予的函数权限
+func (c *PermissionChaincode) getEffectiveSelectors(ctx contractapi.TransactionContextInterface, account string) (map[string]bool, error) {
+	selectors, err := c.getGrantedSelectors(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	// 证书属性策略仅能作用于当前调用者
+	if account == common.GetCaller(ctx) {
+		if err := c.addAttributeSelectors(ctx, selectors); err != nil {
+			return nil, err
+		}
+	}
+	// 其他账户委托的函数权限，不超过委托人当前自身的权限
+	if err := c.addDelegatedSelectors(ctx, account, selectors); err != nil {
+		return nil, err
+	}
+	return selectors, nil
+}
+
+// getGrantedSelectors 内部方法：计算账户在链上记录的权限（直接授权 + 组织级授权 + 角色权限）
+// 不包含证书属性策略和委托权限，作为委托权限的上限
+func (c *PermissionChaincode) getGrantedSelectors(ctx contractapi.TransactionContextInterface, account string) (map[string]bool, error) {
+	// 直接授权仅统计在当前交易时间有效期内的函数
+	selectors, err := getActiveSelectorGrants(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	// 组织级授权对该MSP下的所有成员证书生效
+	if mspID, _ := common.ParseAccount(account); mspID != "" {
+		orgSelectors, err := getStringSet(ctx, orgSelectorPermPrefix+mspID)
+		if err != nil {
+			return nil, err
+		}
+		for funcName := range orgSelectors {
+			selectors[funcName] = true
+		}
+	}
+	roles, err := getStringSet(ctx, accountRolePrefix+account)
+	if err != nil {
+		return nil, err
+	}
+	for roleName := range roles {
+		role, err := c.getRole(ctx, roleName)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			continue
+		}
+		for _, funcName := range role.FuncNames {
+			selectors[funcName] = true
+		}
+	}
+	return selectors, nil
+}
+
+// checkAdminOperation 内部方法：校验调用者为未被冻结的管理员且项目未停用，返回项目配置
+func (c *PermissionChaincode) checkAdminOperation(ctx contractapi.TransactionContextInterface, operation string) (*common.ProjectConfig, error) {
+	cfg, err := c.getProjectConfig(ctx)
+	if err != nil {
+		log.Printf("获取项目配置失败: %v", err)
+		return nil, err
+	}
+	if !common.IsAdmin(ctx, cfg.Admins) {
+		log.Printf("权限校验失败 - 调用者: %s, 操作: %s", common.GetCaller(ctx), operation)
+		return nil, fmt.Errorf("only admin can call %s", operation)
+	}
+	if err := checkNotFrozen(ctx, common.GetCaller(ctx)); err != nil {
+		return nil, err
+	}
+	log.Printf("权限校验通过 - 调用者: %s, 操作: %s", common.GetCaller(ctx), operation)
+	if cfg.Paused {
+		log.Printf("项目状态校验失败 - 项目已停用")
+		return nil, errors.New("project is paused")
+	}
+	return cfg, nil
+}
+
+// normalizeFuncNames 校验函数名列表均为已登记的函数选择器并去重排序
+func normalizeFuncNames(funcNames []string) ([]string, error) {
+	if len(funcNames) == 0 {
+		return nil, errors.New("funcNames cannot be empty")
+	}
+	set := make(map[string]bool, len(funcNames))
+	for _, funcName := range funcNames {
+		if err := validateSelector(funcName); err != nil {
+			return nil, err
+		}
+		set[funcName] = true
+	}
+	return sortedKeys(set), nil
+}
+
+// getStringSet 读取以JSON map[string]bool形式存储的集合，不存在时返回空集合
+func getStringSet(ctx contractapi.TransactionContextInterface, key string) (map[string]bool, error) {
+	set := make(map[string]bool)
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return set, nil
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// putStringSet 存储集合，集合为空时删除该键
+func putStringSet(ctx contractapi.TransactionContextInterface, key string, set map[string]bool) error {
+	if len(set) == 0 {
+		return ctx.GetStub().DelState(key)
+	}
+	b, _ := json.Marshal(set)
+	return ctx.GetStub().PutState(key, b)
+}
+
+// sortedKeys 返回集合中的键并排序
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
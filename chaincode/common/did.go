@@ -0,0 +1,84 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// didScheme DID标识符的固定前缀
+const didScheme = "did:"
+
+// ParseDID 按W3C DID Core语法校验DID标识符，返回method名称和method-specific-id
+//
+//	did                = "did:" method-name ":" method-specific-id
+//	method-name        = 1*method-char
+//	method-char        = %x61-7A / DIGIT
+//	method-specific-id = *( *idchar ":" ) 1*idchar
+//	idchar             = ALPHA / DIGIT / "." / "-" / "_" / pct-encoded
+//
+// method-specific-id允许包含冒号（如 did:bsn:org1:abc），但不能以冒号结尾；不接受带路径、查询或片段的DID URL
+func ParseDID(did string) (method, methodSpecificID string, err error) {
+	if strings.TrimSpace(did) == "" {
+		return "", "", errors.New("did cannot be empty")
+	}
+	if !strings.HasPrefix(did, didScheme) {
+		return "", "", fmt.Errorf("invalid did %s, expected format: did:method:method-specific-id", did)
+	}
+	rest := did[len(didScheme):]
+	idx := strings.Index(rest, ":")
+	if idx < 0 {
+		return "", "", fmt.Errorf("invalid did %s, expected format: did:method:method-specific-id", did)
+	}
+	method, methodSpecificID = rest[:idx], rest[idx+1:]
+	if err := ValidateDIDMethodName(method); err != nil {
+		return "", "", err
+	}
+	if err := validateMethodSpecificID(methodSpecificID); err != nil {
+		return "", "", fmt.Errorf("invalid did %s: %v", did, err)
+	}
+	return method, methodSpecificID, nil
+}
+
+// ValidateDIDMethodName 校验DID method名称只包含小写字母和数字
+func ValidateDIDMethodName(method string) error {
+	if method == "" {
+		return errors.New("did method cannot be empty")
+	}
+	for _, ch := range method {
+		if !(ch >= 'a' && ch <= 'z') && !(ch >= '0' && ch <= '9') {
+			return fmt.Errorf("invalid did method %s, only lowercase letters and digits are allowed", method)
+		}
+	}
+	return nil
+}
+
+// validateMethodSpecificID 校验method-specific-id，最后一段不能为空
+func validateMethodSpecificID(id string) error {
+	if id == "" {
+		return errors.New("method-specific-id cannot be empty")
+	}
+	if strings.HasSuffix(id, ":") {
+		return errors.New("method-specific-id cannot end with ':'")
+	}
+	for i := 0; i < len(id); i++ {
+		ch := id[i]
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '.' || ch == '-' || ch == '_' || ch == ':':
+		case ch == '%':
+			if i+2 >= len(id) || !isHexDigit(id[i+1]) || !isHexDigit(id[i+2]) {
+				return fmt.Errorf("invalid percent-encoding at position %d", i)
+			}
+			i += 2
+		default:
+			return fmt.Errorf("invalid character %q in method-specific-id", ch)
+		}
+	}
+	return nil
+}
+
+// isHexDigit 判断字符是否为十六进制数字
+func isHexDigit(ch byte) bool {
+	return (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// accountSeparator 账户标识中MSP ID与证书SKI之间的分隔符
+// 账户标识格式：{mspId}:{ski}，例如 Org1MSP:3a5f...
+const accountSeparator = ":"
+
+// FormatAccount 由MSP ID和证书SKI组成账户标识
+func FormatAccount(mspID, ski string) string {
+	return mspID + accountSeparator + ski
+}
+
+// ParseAccount 解析账户标识，返回MSP ID和证书SKI
+// 旧格式（仅SKI）的账户标识返回的MSP ID为空
+func ParseAccount(account string) (mspID, ski string) {
+	idx := strings.LastIndex(account, accountSeparator)
+	if idx < 0 {
+		return "", account
+	}
+	return account[:idx], account[idx+1:]
+}
+
+// IsLegacyAccount 判断账户标识是否为不包含MSP ID的旧格式
+func IsLegacyAccount(account string) bool {
+	mspID, _ := ParseAccount(account)
+	return mspID == ""
+}
+
+// ValidateAccount 校验账户标识是否为 {mspId}:{ski} 格式
+func ValidateAccount(account string) error {
+	if strings.TrimSpace(account) == "" {
+		return errors.New("account cannot be empty")
+	}
+	mspID, ski := ParseAccount(account)
+	if strings.TrimSpace(mspID) == "" || strings.TrimSpace(ski) == "" {
+		return fmt.Errorf("invalid account %s, expected format: {mspId}%s{ski}", account, accountSeparator)
+	}
+	return nil
+}
+
+// MatchAccount 判断链上存储的账户标识是否为当前调用者
+// 旧格式（仅SKI）的存储值在迁移完成前按SKI匹配，保证历史数据可用
+func MatchAccount(stored, caller string) bool {
+	if stored == caller {
+		return true
+	}
+	if IsLegacyAccount(stored) {
+		_, ski := ParseAccount(caller)
+		return stored == ski
+	}
+	return false
+}
+
+// GetCallerMSPID 获取当前调用者所属组织的MSP ID
+func GetCallerMSPID(ctx contractapi.TransactionContextInterface) string {
+	mspID, err := cid.GetMSPID(ctx.GetStub())
+	if err != nil {
+		panic(fmt.Errorf("failed to get MSP ID: %v", err))
+	}
+	return mspID
+}
+
+// GetCallerAttribute 获取当前调用者证书中的Fabric CA属性值
+func GetCallerAttribute(ctx contractapi.TransactionContextInterface, attrName string) (string, bool, error) {
+	value, found, err := cid.GetAttributeValue(ctx.GetStub(), attrName)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get attribute %s: %v", attrName, err)
+	}
+	return value, found, nil
+}
@@ -7,23 +7,50 @@ import (
 // ProjectConfig 项目配置结构体定义
 // 避免循环导入，直接定义结构体
 type ProjectConfig struct {
-	EnableVCTemplateVerification bool     `json:"enableVCTemplateVerification"` // 是否启用VC模板验证，启用后只有管理员可以管理模板
-	EnableIssuerVerification     bool     `json:"enableIssuerVerification"`     // 是否启用Issuer验证，启用后只有管理员可以管理发证方
-	EnableWritePermission        bool     `json:"enableWritePermission"`        // 是否开启合约写权限，控制普通用户是否可以进行写操作
-	Method                       string   `json:"method"`                       // 项目method名称，用于DID标识符的method部分验证
-	Paused                       bool     `json:"paused"`                       // 项目是否停用，停用后所有操作都会被拒绝
-	IsProjectPrivate             bool     `json:"isProjectPrivate"`             // 项目是否私有，私有项目需要权限验证才能访问
-	ServiceCode                  string   `json:"serviceCode"`                  // 服务编码，用于标识不同的服务实例
-	ProjectCode                  string   `json:"projectCode"`                  // 项目编码，用于标识具体的项目
-	Admins                       []string `json:"admins"`                       // 管理员账户地址SKI列表，具有最高权限
+	EnableVCTemplateVerification bool        `json:"enableVCTemplateVerification"` // 是否启用VC模板验证，启用后只有管理员可以管理模板
+	EnableIssuerVerification     bool        `json:"enableIssuerVerification"`     // 是否启用Issuer验证，启用后只有管理员可以管理发证方
+	EnableWritePermission        bool        `json:"enableWritePermission"`        // 是否开启合约写权限，控制普通用户是否可以进行写操作
+	Method                       string      `json:"method"`                       // 项目主method名称，始终允许使用
+	Methods                      []DIDMethod `json:"methods,omitempty"`            // 主method之外允许的method集合，可单独启用/停用，用于并行运行或迁移到新method
+	Paused                       bool        `json:"paused"`                       // 项目是否停用，停用后所有操作都会被拒绝
+	IsProjectPrivate             bool        `json:"isProjectPrivate"`             // 项目是否私有，私有项目需要权限验证才能访问
+	ServiceCode                  string      `json:"serviceCode"`                  // 服务编码，用于标识不同的服务实例
+	ProjectCode                  string      `json:"projectCode"`                  // 项目编码，用于标识具体的项目
+	Admins                       []string    `json:"admins"`                       // 管理员账户标识列表（{mspId}:{ski}），具有最高权限
+	ApprovalThreshold            int         `json:"approvalThreshold"`            // 敏感配置变更所需的管理员审批数量，小于等于1表示单个管理员即可执行
+	PausedScopes                 []string    `json:"pausedScopes,omitempty"`       // 按模块和读写方向停用的范围，格式：{module}:{read|write}，如 vc:write
+	Version                      int64       `json:"version"`                      // 配置版本号，每次修改加一，历史版本可通过GetHistoryForKey查询
 }
 
+// DIDMethod 项目允许的DID method及其启用状态
+type DIDMethod struct {
+	Name    string `json:"name"`    // method名称，只包含小写字母和数字
+	Enabled bool   `json:"enabled"` // 是否启用，停用后该method的DID不能再注册或更新
+}
+
+// 可按模块停用的业务模块
+const (
+	ModuleDID      = "did"      // DID注册、更新与查询
+	ModuleIssuer   = "issuer"   // 发证方管理与查询
+	ModuleTemplate = "template" // VC模板管理与查询
+	ModuleVC       = "vc"       // VC存证、撤销与查询
+)
+
+// 停用范围的读写方向
+const (
+	DirectionRead  = "read"  // 查询操作
+	DirectionWrite = "write" // 写入操作
+)
+
 // PermissionChecker 权限检查接口
 // 定义Permission模块需要实现的方法，供其他模块调用
 type PermissionChecker interface {
 	// 写权限检查
 	CheckWriteFuncSelectorPermission(ctx contractapi.TransactionContextInterface, account, funcName string) (bool, error)
 
+	// 累加当前调用者在写函数上的配额使用次数，超出配额时返回错误
+	ConsumeWriteQuota(ctx contractapi.TransactionContextInterface, funcName string) error
+
 	// 查询权限检查
 	CheckQueryFuncSelectorPermission(ctx contractapi.TransactionContextInterface, account, funcName string) (bool, error)
 
@@ -36,8 +63,8 @@ type PermissionChecker interface {
 	// VC模板验证检查
 	CheckVCTemplateVerificationEnabled(ctx contractapi.TransactionContextInterface, account string) (bool, error)
 
-	// 项目状态检查
-	CheckNotPaused(ctx contractapi.TransactionContextInterface) error
+	// 项目状态检查，module为空时仅检查项目是否整体停用，否则同时检查该模块在direction方向上是否停用
+	CheckNotPaused(ctx contractapi.TransactionContextInterface, module, direction string) error
 
 	// 管理员角色检查
 	CheckAdminRole(ctx contractapi.TransactionContextInterface, account string) error
@@ -46,16 +73,18 @@ type PermissionChecker interface {
 	GetProjectConfig(ctx contractapi.TransactionContextInterface) (*ProjectConfig, error)
 }
 
-// GlobalPermissionChecker 全局权限检查器实例
-// 在main.go中初始化，供所有模块使用
-var GlobalPermissionChecker PermissionChecker
+// TransactionContextInterface 各业务合约使用的交易上下文接口
+// 在contractapi交易上下文的基础上提供权限检查器、调用者身份及本次交易内缓存的项目配置，
+// 各模块只通过该接口获取依赖，测试时可实现该接口注入伪造的权限检查器
+type TransactionContextInterface interface {
+	contractapi.TransactionContextInterface
 
-// SetGlobalPermissionChecker 设置全局权限检查器
-func SetGlobalPermissionChecker(checker PermissionChecker) {
-	GlobalPermissionChecker = checker
-}
+	// 获取权限检查器
+	GetPermissionChecker() PermissionChecker
+
+	// 获取当前调用者标识，格式：{mspId}:{ski}
+	GetCaller() string
 
-// GetGlobalPermissionChecker 获取全局权限检查器
-func GetGlobalPermissionChecker() PermissionChecker {
-	return GlobalPermissionChecker
+	// 获取当前项目配置，同一交易内只读取一次，切换项目后重新读取
+	GetProjectConfig() (*ProjectConfig, error)
 }
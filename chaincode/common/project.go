@@ -0,0 +1,206 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+const (
+	// ProjectTransientKey 交易transient数据中选择项目的字段名，不传或为空时使用默认项目
+	ProjectTransientKey = "projectCode"
+
+	// projectNamespace 项目状态隔离前缀
+	// 普通键格式：project~{projectCode}~{key}；复合键的objectType格式：project~{projectCode}~{objectType}
+	projectNamespace = "project~"
+
+	// compositeKeyNamespace 复合键的起始字符
+	compositeKeyNamespace = "\x00"
+
+	// PlatformProjectPrefix 平台已创建项目的登记键前缀（位于默认命名空间）
+	// 格式：platform:project:{projectCode}
+	PlatformProjectPrefix = "platform:project:"
+)
+
+// ProjectStub 按项目编码隔离状态的链码存根
+// 普通键在读写时自动加上项目前缀，复合键在创建时将项目前缀加入objectType，
+// 因此各合约无需感知项目即可访问当前项目的did/issuer/vc/permission状态；默认项目不使用该存根，保持原有存储键
+type ProjectStub struct {
+	shim.ChaincodeStubInterface
+	projectCode string
+}
+
+// NewProjectStub 创建按项目编码隔离状态的链码存根
+func NewProjectStub(stub shim.ChaincodeStubInterface, projectCode string) *ProjectStub {
+	return &ProjectStub{ChaincodeStubInterface: UnwrapStub(stub), projectCode: projectCode}
+}
+
+// UnwrapStub 返回未按项目隔离的原始存根，用于访问平台级（默认命名空间）状态
+func UnwrapStub(stub shim.ChaincodeStubInterface) shim.ChaincodeStubInterface {
+	if scoped, ok := stub.(*ProjectStub); ok {
+		return scoped.ChaincodeStubInterface
+	}
+	return stub
+}
+
+// GetProjectCode 返回当前交易选择的项目编码，默认项目返回空字符串
+func GetProjectCode(ctx contractapi.TransactionContextInterface) string {
+	if scoped, ok := ctx.GetStub().(*ProjectStub); ok {
+		return scoped.projectCode
+	}
+	return ""
+}
+
+// ValidateProjectCode 校验项目编码只包含字母、数字、"-"和"_"
+func ValidateProjectCode(projectCode string) error {
+	if projectCode == "" {
+		return errors.New("projectCode cannot be empty")
+	}
+	for _, ch := range projectCode {
+		if !(ch >= 'a' && ch <= 'z') && !(ch >= 'A' && ch <= 'Z') && !(ch >= '0' && ch <= '9') && ch != '-' && ch != '_' {
+			return fmt.Errorf("invalid projectCode %s, only letters, digits, '-' and '_' are allowed", projectCode)
+		}
+	}
+	return nil
+}
+
+// SelectProject 各合约的BeforeTransaction：根据transient数据中的projectCode选择本次交易操作的项目
+// 项目必须已通过CreateProject登记；未选择项目时使用默认项目（部署时通过InitProject初始化的项目）
+func SelectProject(ctx contractapi.TransactionContextInterface) error {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("failed to get transient: %v", err)
+	}
+	projectCode := strings.TrimSpace(string(transient[ProjectTransientKey]))
+	if projectCode == "" {
+		return nil
+	}
+	if err := ValidateProjectCode(projectCode); err != nil {
+		return err
+	}
+	b, err := UnwrapStub(ctx.GetStub()).GetState(PlatformProjectPrefix + projectCode)
+	if err != nil {
+		return err
+	}
+	if b == nil {
+		return fmt.Errorf("project %s not found", projectCode)
+	}
+	return SetProjectStub(ctx, projectCode)
+}
+
+// SetProjectStub 将交易上下文的存根切换为指定项目，projectCode为空时切换回默认项目
+func SetProjectStub(ctx contractapi.TransactionContextInterface, projectCode string) error {
+	setter, ok := ctx.(interface {
+		SetStub(stub shim.ChaincodeStubInterface)
+	})
+	if !ok {
+		return errors.New("transaction context does not support project selection")
+	}
+	if projectCode == "" {
+		setter.SetStub(UnwrapStub(ctx.GetStub()))
+		return nil
+	}
+	setter.SetStub(NewProjectStub(ctx.GetStub(), projectCode))
+	return nil
+}
+
+// ================== 状态读写 ==================
+
+func (s *ProjectStub) key(key string) string {
+	// 复合键已在创建时隔离，不再加前缀
+	if strings.HasPrefix(key, compositeKeyNamespace) {
+		return key
+	}
+	return projectNamespace + s.projectCode + "~" + key
+}
+
+// endKey 范围查询的结束键，为空时表示查询到项目命名空间的末尾，而不是整个账本的末尾
+func (s *ProjectStub) endKey(key string) string {
+	if key == "" {
+		return s.key(string(utf8.MaxRune))
+	}
+	return s.key(key)
+}
+
+func (s *ProjectStub) objectType(objectType string) string {
+	return projectNamespace + s.projectCode + "~" + objectType
+}
+
+func (s *ProjectStub) GetState(key string) ([]byte, error) {
+	return s.ChaincodeStubInterface.GetState(s.key(key))
+}
+
+func (s *ProjectStub) PutState(key string, value []byte) error {
+	return s.ChaincodeStubInterface.PutState(s.key(key), value)
+}
+
+func (s *ProjectStub) DelState(key string) error {
+	return s.ChaincodeStubInterface.DelState(s.key(key))
+}
+
+func (s *ProjectStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return s.ChaincodeStubInterface.GetHistoryForKey(s.key(key))
+}
+
+func (s *ProjectStub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	iterator, err := s.ChaincodeStubInterface.GetStateByRange(s.key(startKey), s.endKey(endKey))
+	if err != nil {
+		return nil, err
+	}
+	return &projectIterator{StateQueryIteratorInterface: iterator, prefix: s.key("")}, nil
+}
+
+func (s *ProjectStub) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	if bookmark != "" {
+		bookmark = s.key(bookmark)
+	}
+	iterator, metadata, err := s.ChaincodeStubInterface.GetStateByRangeWithPagination(s.key(startKey), s.endKey(endKey), pageSize, bookmark)
+	if err != nil {
+		return nil, nil, err
+	}
+	if metadata != nil {
+		metadata.Bookmark = strings.TrimPrefix(metadata.Bookmark, s.key(""))
+	}
+	return &projectIterator{StateQueryIteratorInterface: iterator, prefix: s.key("")}, metadata, nil
+}
+
+func (s *ProjectStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return s.ChaincodeStubInterface.CreateCompositeKey(s.objectType(objectType), attributes)
+}
+
+func (s *ProjectStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	objectType, attributes, err := s.ChaincodeStubInterface.SplitCompositeKey(compositeKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return strings.TrimPrefix(objectType, s.objectType("")), attributes, nil
+}
+
+func (s *ProjectStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	return s.ChaincodeStubInterface.GetStateByPartialCompositeKey(s.objectType(objectType), keys)
+}
+
+func (s *ProjectStub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	return s.ChaincodeStubInterface.GetStateByPartialCompositeKeyWithPagination(s.objectType(objectType), keys, pageSize, bookmark)
+}
+
+// projectIterator 去掉范围查询结果中普通键的项目前缀
+type projectIterator struct {
+	shim.StateQueryIteratorInterface
+	prefix string
+}
+
+func (it *projectIterator) Next() (*queryresult.KV, error) {
+	kv, err := it.StateQueryIteratorInterface.Next()
+	if err != nil {
+		return nil, err
+	}
+	kv.Key = strings.TrimPrefix(kv.Key, it.prefix)
+	return kv, nil
+}
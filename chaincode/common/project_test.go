@@ -0,0 +1,161 @@
+package common_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"sbp-did-chaincode/common"
+	"sbp-did-chaincode/internal/testutil"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// rangeKeys 读取范围查询返回的所有键
+func rangeKeys(iterator shim.StateQueryIteratorInterface, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+	keys := make([]string, 0)
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, kv.Key)
+	}
+	return keys, nil
+}
+
+func TestProjectStubIsolation(t *testing.T) {
+	mock, _ := testutil.NewMockStub(t, nil, "Org1MSP")
+	ledger := testutil.NewLedgerStub(mock)
+	testutil.StartTx(mock, "tx1", time.Unix(1700000000, 0))
+	p1, p2 := common.NewProjectStub(ledger, "p1"), common.NewProjectStub(ledger, "p2")
+	stubs := map[string]shim.ChaincodeStubInterface{"default": ledger, "p1": p1, "p2": p2}
+
+	put := func(stub shim.ChaincodeStubInterface, key, value string) {
+		t.Helper()
+		if err := stub.PutState(key, []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for code, stub := range stubs {
+		put(stub, "did:info:did:sbp:alice", "doc-"+code)
+		for _, key := range []string{"audit:1", "audit:2", "audit:3"} {
+			put(stub, key, code)
+		}
+		compositeKey, err := stub.CreateCompositeKey("permission~selectorPerm", []string{"Org1MSP:aa", "RegisterDid"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		put(stub, compositeKey, code)
+	}
+
+	t.Run("plain keys", func(t *testing.T) {
+		for code, stub := range stubs {
+			got, err := stub.GetState("did:info:did:sbp:alice")
+			if err != nil || string(got) != "doc-"+code {
+				t.Fatalf("project %q got %s, %v", code, got, err)
+			}
+		}
+		got, err := mock.GetState("project~p1~did:info:did:sbp:alice")
+		if err != nil || string(got) != "doc-p1" {
+			t.Fatalf("tenant key is not stored under the project prefix, got %s, %v", got, err)
+		}
+	})
+
+	t.Run("composite keys", func(t *testing.T) {
+		for code, stub := range stubs {
+			iterator, err := stub.GetStateByPartialCompositeKey("permission~selectorPerm", []string{"Org1MSP:aa"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var values []string
+			for iterator.HasNext() {
+				kv, err := iterator.Next()
+				if err != nil {
+					t.Fatal(err)
+				}
+				objectType, attributes, err := stub.SplitCompositeKey(kv.Key)
+				if err != nil || objectType != "permission~selectorPerm" || !reflect.DeepEqual(attributes, []string{"Org1MSP:aa", "RegisterDid"}) {
+					t.Fatalf("project %q split %q into %q %v, %v", code, kv.Key, objectType, attributes, err)
+				}
+				values = append(values, string(kv.Value))
+			}
+			iterator.Close()
+			if !reflect.DeepEqual(values, []string{code}) {
+				t.Fatalf("project %q got values %v", code, values)
+			}
+		}
+	})
+
+	t.Run("range", func(t *testing.T) {
+		want := []string{"audit:1", "audit:2", "audit:3"}
+		for _, code := range []string{"p1", "p2"} {
+			if keys, err := rangeKeys(stubs[code].GetStateByRange("audit:", "audit:~")); err != nil || !reflect.DeepEqual(keys, want) {
+				t.Fatalf("project %q got keys %v, %v, want %v", code, keys, err, want)
+			}
+			// 起止键为空时只查询到项目命名空间内的全部普通键
+			want := []string{"audit:1", "audit:2", "audit:3", "did:info:did:sbp:alice"}
+			if keys, err := rangeKeys(stubs[code].GetStateByRange("", "")); err != nil || !reflect.DeepEqual(keys, want) {
+				t.Fatalf("project %q got keys %v, %v, want %v", code, keys, err, want)
+			}
+		}
+		if keys, err := rangeKeys(ledger.GetStateByRange("audit:", "audit:~")); err != nil || !reflect.DeepEqual(keys, want) {
+			t.Fatalf("default project got keys %v, %v, want %v", keys, err, want)
+		}
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		iterator, metadata, err := p1.GetStateByRangeWithPagination("audit:", "audit:~", 2, "")
+		if keys, err := rangeKeys(iterator, err); err != nil || !reflect.DeepEqual(keys, []string{"audit:1", "audit:2"}) {
+			t.Fatalf("got first page %v, %v", keys, err)
+		}
+		if metadata.Bookmark != "audit:3" {
+			t.Fatalf("got bookmark %q, want it without the project prefix", metadata.Bookmark)
+		}
+		iterator, metadata, err = p1.GetStateByRangeWithPagination("audit:", "audit:~", 2, metadata.Bookmark)
+		if keys, err := rangeKeys(iterator, err); err != nil || !reflect.DeepEqual(keys, []string{"audit:3"}) || metadata.Bookmark != "" {
+			t.Fatalf("got second page %v, %v, bookmark %q", keys, err, metadata.Bookmark)
+		}
+		// 其他项目的bookmark只能定位到本项目的键
+		iterator, _, err = p2.GetStateByRangeWithPagination("audit:", "audit:~", 2, "audit:3")
+		keys, err := rangeKeys(iterator, err)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := make([]string, 0)
+		for _, key := range keys {
+			value, _ := p2.GetState(key)
+			values = append(values, string(value))
+		}
+		if !reflect.DeepEqual(values, []string{"p2"}) {
+			t.Fatalf("got values %v from project p2", values)
+		}
+	})
+
+	t.Run("history", func(t *testing.T) {
+		testutil.StartTx(mock, "tx2", time.Unix(1700000100, 0))
+		put(p1, "did:info:did:sbp:alice", "doc-p1-v2")
+		for code, want := range map[string][]string{"default": {"doc-default"}, "p1": {"doc-p1-v2", "doc-p1"}, "p2": {"doc-p2"}} {
+			iterator, err := stubs[code].GetHistoryForKey("did:info:did:sbp:alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			values := make([]string, 0)
+			for iterator.HasNext() {
+				modification, err := iterator.Next()
+				if err != nil {
+					t.Fatal(err)
+				}
+				values = append(values, string(modification.Value))
+			}
+			iterator.Close()
+			if !reflect.DeepEqual(values, want) {
+				t.Fatalf("project %q got history %v, want %v", code, values, want)
+			}
+		}
+	})
+}
@@ -3,6 +3,7 @@ package common
 import (
 	"encoding/hex"
 	"fmt"
+	"time"
 
 	"github.com/duke-git/lancet/v2/slice"
 	//"github.com/ethereum/go-ethereum/common"
@@ -14,7 +15,9 @@ import (
 // 校验是否为管理员
 func IsAdmin(ctx contractapi.TransactionContextInterface, Admins []string) bool {
 	caller := GetCaller(ctx)
-	return slice.Contain(Admins, caller)
+	return slice.ContainBy(Admins, func(admin string) bool {
+		return MatchAccount(admin, caller)
+	})
 }
 
 // 事件封装工具
@@ -22,14 +25,22 @@ func EmitEvent(ctx contractapi.TransactionContextInterface, eventName string, pa
 	return ctx.GetStub().SetEvent(eventName, payload)
 }
 
-// GetCaller 获取当前调用者标识（如MSPID或证书ID）
+// GetCaller 获取当前调用者标识，格式：{mspId}:{ski}
 func GetCaller(ctx contractapi.TransactionContextInterface) string {
-	// TODO: 实现获取调用者身份标识
 	ski, err := GetMsgSenderSKI(ctx.GetStub())
 	if err != nil {
 		panic(err)
 	}
-	return ski
+	return FormatAccount(GetCallerMSPID(ctx), ski)
+}
+
+// GetTxTime 获取当前交易的时间戳（由客户端提案携带，所有背书节点一致）
+func GetTxTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get tx timestamp: %v", err)
+	}
+	return ts.AsTime(), nil
 }
 
 func GetMsgSenderSKI(stub shim.ChaincodeStubInterface) (string, error) {
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 
 	"sbp-did-chaincode/common"
@@ -14,8 +15,15 @@ import (
 
 // DID信息结构体
 type DidInfo struct {
-	DidDocument string `json:"didDocument"` // DID文档
-	Account     string `json:"sender"`      // 注册账户
+	DidDocument   string `json:"didDocument"`             // DID文档，注销后保留注销前的最后版本
+	Account       string `json:"sender"`                  // 注册账户
+	Created       int64  `json:"created,omitempty"`       // 注册时间（Unix秒，取交易时间戳）
+	Updated       int64  `json:"updated,omitempty"`       // 最后一次更新（含注销）时间（Unix秒，取交易时间戳）
+	VersionId     int64  `json:"versionId,omitempty"`     // 文档版本号，注册时为1，每次更新或注销加一
+	Nonce         int64  `json:"nonce,omitempty"`         // 最后一次签名更新（UpdateDidDocumentWithSignature）使用的nonce，每次签名更新加一
+	Deactivated   bool   `json:"deactivated"`             // 是否已注销，注销后的记录作为墓碑永久保留，DID不能再更新或重新注册
+	DeactivatedAt int64  `json:"deactivatedAt,omitempty"` // 注销时间（Unix秒，取交易时间戳）
+	DeactivatedBy string `json:"deactivatedBy,omitempty"` // 执行注销的账户（DID创建者或管理员）
 }
 
 // DIDChaincode 结构体
@@ -25,85 +33,26 @@ type DIDChaincode struct {
 
 const didInfoPrefix = "did:info:"
 
-// ================== 内部合约调用辅助方法 ==================
-
-// getProjectConfig 获取项目配置信息
-func (c *DIDChaincode) getProjectConfig(ctx contractapi.TransactionContextInterface) (*common.ProjectConfig, error) {
-	permissionChecker := common.GetGlobalPermissionChecker()
-	if permissionChecker == nil {
-		return nil, fmt.Errorf("global permission checker not initialized")
-	}
-
-	return permissionChecker.GetProjectConfig(ctx)
-}
-
-// checkWriteFuncSelectorPermission 调用Permission合约的权限检查方法
-func (c *DIDChaincode) checkWriteFuncSelectorPermission(ctx contractapi.TransactionContextInterface, caller, funcName string) (bool, error) {
-	// 通过全局权限检查器调用Permission合约的方法
-	permissionChecker := common.GetGlobalPermissionChecker()
-	if permissionChecker == nil {
-		return false, fmt.Errorf("global permission checker not initialized")
-	}
-
-	return permissionChecker.CheckWriteFuncSelectorPermission(ctx, caller, funcName)
-}
-
-// checkMethod 调用Permission合约的DID方法检查
-func (c *DIDChaincode) checkMethod(ctx contractapi.TransactionContextInterface, did string) error {
-	// 通过全局权限检查器调用Permission合约的方法
-	permissionChecker := common.GetGlobalPermissionChecker()
-	if permissionChecker == nil {
-		return fmt.Errorf("global permission checker not initialized")
-	}
-
-	return permissionChecker.CheckMethod(ctx, did)
-}
-
-// checkQueryFuncSelectorPermission 调用Permission合约的查询权限检查方法
-func (c *DIDChaincode) checkQueryFuncSelectorPermission(ctx contractapi.TransactionContextInterface, funcName string) (bool, error) {
-	// 获取调用者账户
-	caller := common.GetCaller(ctx)
-
-	// 通过全局权限检查器调用Permission合约的方法
-	permissionChecker := common.GetGlobalPermissionChecker()
-	if permissionChecker == nil {
-		return false, fmt.Errorf("global permission checker not initialized")
-	}
-
-	return permissionChecker.CheckQueryFuncSelectorPermission(ctx, caller, funcName)
-}
-
 // ================== 主要业务方法 ==================
+// 调用者权限、项目/模块停用状态及DID method校验由BeforeTransaction按策略表统一完成
 
 // RegisterDid 注册DID
-func (c *DIDChaincode) RegisterDid(ctx contractapi.TransactionContextInterface, did, didDocument string) error {
+func (c *DIDChaincode) RegisterDid(ctx common.TransactionContextInterface, did, didDocument string) error {
 	log.Printf("开始注册DID - DID: %s", did)
 	if strings.TrimSpace(did) == "" || strings.TrimSpace(didDocument) == "" {
 		log.Printf("参数校验失败 - DID或DID文档为空")
 		return errors.New("did and didDocument cannot be empty")
 	}
 	// 获取调用者账户
-	caller := common.GetCaller(ctx)
+	caller := ctx.GetCaller()
 	log.Printf("DID注册 - 调用者: %s", caller)
 
-	// 调用Permission合约的checkWriteFuncSelectorPermission方法
-	hasPermission, err := c.checkWriteFuncSelectorPermission(ctx, caller, "RegisterDid")
-	if err != nil {
-		log.Printf("权限检查失败: %v", err)
-		return fmt.Errorf("permission check failed: %v", err)
-	}
-	if !hasPermission {
-		log.Printf("权限校验失败 - 调用者: %s, 操作: RegisterDid", caller)
-		return errors.New("no permission to register DID")
-	}
-	log.Printf("权限校验通过 - 调用者: %s, 操作: RegisterDid", caller)
-
-	// 调用Permission合约的checkMethod方法
-	if err := c.checkMethod(ctx, did); err != nil {
-		log.Printf("DID方法校验失败: %v", err)
-		return fmt.Errorf("method validation failed: %v", err)
+	// 按W3C DID Core数据模型校验DID文档
+	if _, err := ParseDocument(did, didDocument); err != nil {
+		log.Printf("DID文档校验失败: %v", err)
+		return err
 	}
-	log.Printf("DID方法校验通过 - DID: %s", did)
+	log.Printf("DID文档校验通过 - DID: %s", did)
 
 	key := didInfoPrefix + did
 	b, err := ctx.GetStub().GetState(key)
@@ -112,13 +61,25 @@ func (c *DIDChaincode) RegisterDid(ctx contractapi.TransactionContextInterface,
 		return err
 	}
 	if b != nil {
+		var existing DidInfo
+		if err := json.Unmarshal(b, &existing); err == nil && existing.Deactivated {
+			log.Printf("DID注册失败 - DID已注销: %s", did)
+			return errors.New("did has been deactivated and cannot be registered again")
+		}
 		log.Printf("DID注册失败 - DID已存在: %s", did)
 		return errors.New("did already exists")
 	}
 
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return err
+	}
 	info := DidInfo{
 		DidDocument: didDocument,
 		Account:     caller,
+		Created:     txTime.Unix(),
+		Updated:     txTime.Unix(),
+		VersionId:   1,
 	}
 	b, _ = json.Marshal(info)
 	if err := ctx.GetStub().PutState(key, b); err != nil {
@@ -128,7 +89,7 @@ func (c *DIDChaincode) RegisterDid(ctx contractapi.TransactionContextInterface,
 	log.Printf("DID信息存储成功 - DID: %s, 账户: %s", did, caller)
 
 	// 获取项目配置信息，用于事件通知
-	cfg, err := c.getProjectConfig(ctx)
+	cfg, err := ctx.GetProjectConfig()
 	if err != nil {
 		log.Printf("获取项目配置失败: %v", err)
 		// 如果获取配置失败，仍然发送事件，但不包含项目信息
@@ -149,98 +110,139 @@ func (c *DIDChaincode) RegisterDid(ctx contractapi.TransactionContextInterface,
 }
 
 // UpdateDidDocument 更新DID文档
-func (c *DIDChaincode) UpdateDidDocument(ctx contractapi.TransactionContextInterface, did, didDocument string) error {
+// 权限要求：DID创建者（DidInfo中记录的注册账户）
+func (c *DIDChaincode) UpdateDidDocument(ctx common.TransactionContextInterface, did, didDocument string) error {
 	log.Printf("开始更新DID文档 - DID: %s", did)
-	if strings.TrimSpace(did) == "" || strings.TrimSpace(didDocument) == "" {
-		log.Printf("参数校验失败 - DID或DID文档为空")
-		return errors.New("did and didDocument cannot be empty")
-	}
-	// 获取调用者账户
-	caller := common.GetCaller(ctx)
+	caller := ctx.GetCaller()
 	log.Printf("DID文档更新 - 调用者: %s", caller)
+	return updateDidDocument(ctx, did, didDocument, func(info *DidInfo) (map[string]interface{}, error) {
+		if !common.MatchAccount(info.Account, caller) {
+			log.Printf("权限校验失败 - 只有创建者可以更新DID: %s, 创建者: %s, 调用者: %s", did, info.Account, caller)
+			return nil, errors.New("only creator can update did")
+		}
+		log.Printf("权限校验通过 - 调用者是DID创建者")
+		return nil, nil
+	})
+}
 
-	// 调用Permission合约的checkWriteFuncSelectorPermission方法
-	hasPermission, err := c.checkWriteFuncSelectorPermission(ctx, caller, "UpdateDidDocument")
-	if err != nil {
-		log.Printf("权限检查失败: %v", err)
-		return fmt.Errorf("permission check failed: %v", err)
+// UpdateDidDocumentWithSignature 由DID自身的密钥签名授权更新DID文档，不要求调用者是DID创建者，
+// 用于Fabric证书轮换等注册账户不可用的场景
+// 参数说明：
+// - verificationMethod: 签名使用的验证方法ID（DID URL，可为 #key-1 形式），必须出现在当前DID文档的authentication或capabilityInvocation中
+// - nonce: 该DID的签名更新序号，必须为GetDidNonce返回值加一，防止签名重放
+// - signature: 对UpdateSigningInput生成的消息的base64url签名，支持Ed25519（EdDSA）和secp256k1（ES256K，r||s）的publicKeyJwk
+func (c *DIDChaincode) UpdateDidDocumentWithSignature(ctx common.TransactionContextInterface, did, didDocument, verificationMethod, nonce, signature string) error {
+	log.Printf("开始签名更新DID文档 - DID: %s, 验证方法: %s, nonce: %s", did, verificationMethod, nonce)
+	if strings.TrimSpace(verificationMethod) == "" || strings.TrimSpace(signature) == "" {
+		log.Printf("参数校验失败 - 验证方法或签名为空")
+		return errors.New("verificationMethod and signature cannot be empty")
 	}
-	if !hasPermission {
-		log.Printf("权限校验失败 - 调用者: %s, 操作: UpdateDidDocument", caller)
-		return errors.New("no permission to update DID")
+	n, err := strconv.ParseInt(nonce, 10, 64)
+	if err != nil {
+		log.Printf("参数校验失败 - nonce非法: %s", nonce)
+		return fmt.Errorf("invalid nonce %s", nonce)
 	}
-	log.Printf("权限校验通过 - 调用者: %s, 操作: UpdateDidDocument", caller)
+	return updateDidDocument(ctx, did, didDocument, func(info *DidInfo) (map[string]interface{}, error) {
+		if n != info.Nonce+1 {
+			log.Printf("签名校验失败 - nonce不匹配, 期望: %d, 实际: %d", info.Nonce+1, n)
+			return nil, fmt.Errorf("invalid nonce %d, expected %d", n, info.Nonce+1)
+		}
+		// 签名密钥取自更新前的当前DID文档
+		current, err := ParseDocument(did, info.DidDocument)
+		if err != nil {
+			log.Printf("当前DID文档解析失败: %v", err)
+			return nil, err
+		}
+		method, err := findSigningMethod(current, verificationMethod)
+		if err != nil {
+			log.Printf("签名校验失败 - %v", err)
+			return nil, err
+		}
+		message := UpdateSigningInput(ctx.GetStub().GetChannelID(), common.GetProjectCode(ctx), did, n, didDocument)
+		if err := verifyMethodSignature(method, message, signature); err != nil {
+			log.Printf("签名校验失败 - 验证方法: %s, 错误: %v", method.ID, err)
+			return nil, err
+		}
+		log.Printf("签名校验通过 - 验证方法: %s", method.ID)
+		info.Nonce = n
+		return map[string]interface{}{"verificationMethod": method.ID, "nonce": n}, nil
+	})
+}
 
-	// 调用Permission合约的checkMethod方法
-	if err := c.checkMethod(ctx, did); err != nil {
-		log.Printf("DID方法校验失败: %v", err)
-		return fmt.Errorf("method validation failed: %v", err)
+// DeactivateDid 注销DID，注销后保留墓碑记录：DID不能再更新或重新注册，查询时报告已注销，以该DID注册的发证方视为已停用
+// 权限要求：DID创建者或管理员
+func (c *DIDChaincode) DeactivateDid(ctx common.TransactionContextInterface, did string) error {
+	log.Printf("开始注销DID - DID: %s", did)
+	if strings.TrimSpace(did) == "" {
+		log.Printf("参数校验失败 - DID为空")
+		return errors.New("did cannot be empty")
 	}
-	log.Printf("DID方法校验通过 - DID: %s", did)
+	caller := ctx.GetCaller()
+	log.Printf("DID注销 - 调用者: %s", caller)
 
 	key := didInfoPrefix + did
 	b, err := ctx.GetStub().GetState(key)
 	if err != nil || b == nil {
-		log.Printf("DID文档更新失败 - DID不存在: %s", did)
+		log.Printf("DID注销失败 - DID不存在: %s", did)
 		return errors.New("did not found")
 	}
 	var info DidInfo
-	_ = json.Unmarshal(b, &info)
-	if info.Account != common.GetCaller(ctx) {
-		log.Printf("权限校验失败 - 只有创建者可以更新DID: %s, 创建者: %s, 调用者: %s", did, info.Account, common.GetCaller(ctx))
-		return errors.New("only creator can update did")
+	if err := json.Unmarshal(b, &info); err != nil {
+		return err
+	}
+	if info.Deactivated {
+		log.Printf("DID注销失败 - DID已注销: %s", did)
+		return errors.New("did is already deactivated")
+	}
+	if !common.MatchAccount(info.Account, caller) {
+		if err := ctx.GetPermissionChecker().CheckAdminRole(ctx, caller); err != nil {
+			log.Printf("权限校验失败 - 只有创建者或管理员可以注销DID: %s, 创建者: %s, 调用者: %s", did, info.Account, caller)
+			return errors.New("only creator or admin can deactivate did")
+		}
 	}
-	log.Printf("权限校验通过 - 调用者是DID创建者")
+	log.Printf("权限校验通过 - 调用者: %s, 操作: DeactivateDid", caller)
 
-	info.DidDocument = didDocument
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	info.Deactivated = true
+	info.DeactivatedAt = txTime.Unix()
+	info.DeactivatedBy = caller
+	info.Updated = txTime.Unix()
+	info.VersionId++
 	b, _ = json.Marshal(info)
 	if err := ctx.GetStub().PutState(key, b); err != nil {
-		log.Printf("DID文档更新存储失败: %v", err)
+		log.Printf("DID注销存储失败: %v", err)
 		return err
 	}
-	log.Printf("DID文档更新存储成功 - DID: %s", did)
+	log.Printf("DID注销存储成功 - DID: %s", did)
 
-	// 获取项目配置信息，用于事件通知
-	cfg, err := c.getProjectConfig(ctx)
+	cfg, err := ctx.GetProjectConfig()
 	if err != nil {
-		log.Printf("获取项目配置失败: %v", err)
-		// 如果获取配置失败，仍然发送事件，但不包含项目信息
-		return common.EmitEvent(ctx, "DidDocumentUpdated", b)
+		return err
 	}
-
-	// 构建包含项目信息的事件数据
 	eventData := map[string]interface{}{
-		"serviceCode": cfg.ServiceCode,
-		"projectCode": cfg.ProjectCode,
-		"did":         did,
-		"didDocument": info,
-		"sender":      common.GetCaller(ctx),
+		"serviceCode":   cfg.ServiceCode,
+		"projectCode":   cfg.ProjectCode,
+		"did":           did,
+		"deactivated":   true,
+		"deactivatedAt": info.DeactivatedAt,
+		"sender":        caller,
 	}
 	eventPayload, _ := json.Marshal(eventData)
-	log.Printf("触发DID文档更新事件 - DID: %s", did)
-	return common.EmitEvent(ctx, "DidDocumentUpdated", eventPayload)
+	log.Printf("触发DID注销事件 - DID: %s", did)
+	return common.EmitEvent(ctx, "DidDeactivated", eventPayload)
 }
 
-// GetDidInfo 查询DID文档
-func (c *DIDChaincode) GetDidInfo(ctx contractapi.TransactionContextInterface, did string) (string, error) {
+// GetDidInfo 查询DID文档，DID已注销时返回错误
+func (c *DIDChaincode) GetDidInfo(ctx common.TransactionContextInterface, did string) (string, error) {
 	log.Printf("开始查询DID信息 - DID: %s", did)
 	if strings.TrimSpace(did) == "" {
 		log.Printf("参数校验失败 - DID为空")
 		return "", errors.New("did cannot be empty")
 	}
 
-	// 调用Permission合约的checkQueryFuncSelectorPermission方法
-	hasPermission, err := c.checkQueryFuncSelectorPermission(ctx, "GetDidInfo")
-	if err != nil {
-		log.Printf("权限检查失败: %v", err)
-		return "", fmt.Errorf("permission check failed: %v", err)
-	}
-	if !hasPermission {
-		log.Printf("权限校验失败 - 调用者: %s, 操作: GetDidInfo", common.GetCaller(ctx))
-		return "", errors.New("no permission to query DID")
-	}
-	log.Printf("权限校验通过 - 调用者: %s, 操作: GetDidInfo", common.GetCaller(ctx))
-
 	key := didInfoPrefix + did
 	b, err := ctx.GetStub().GetState(key)
 	if err != nil || b == nil {
@@ -249,12 +251,16 @@ func (c *DIDChaincode) GetDidInfo(ctx contractapi.TransactionContextInterface, d
 	}
 	var info DidInfo
 	_ = json.Unmarshal(b, &info)
+	if info.Deactivated {
+		log.Printf("DID信息查询结果 - DID已注销: %s", did)
+		return "", fmt.Errorf("did %s is deactivated", did)
+	}
 	log.Printf("DID信息查询成功 - DID: %s, 账户: %s", did, info.Account)
 	return info.DidDocument, nil
 }
 
-// CheckDid 校验DID是否存在
-func (c *DIDChaincode) CheckDid(ctx contractapi.TransactionContextInterface, did string) (bool, error) {
+// CheckDid 校验DID是否存在且未注销
+func (c *DIDChaincode) CheckDid(ctx common.TransactionContextInterface, did string) (bool, error) {
 	log.Printf("开始校验DID是否存在 - DID: %s", did)
 	if strings.TrimSpace(did) == "" {
 		log.Printf("参数校验失败 - DID为空")
@@ -266,6 +272,110 @@ func (c *DIDChaincode) CheckDid(ctx contractapi.TransactionContextInterface, did
 		log.Printf("DID校验结果 - DID不存在: %s", did)
 		return false, nil
 	}
+	var info DidInfo
+	if err := json.Unmarshal(b, &info); err == nil && info.Deactivated {
+		log.Printf("DID校验结果 - DID已注销: %s", did)
+		return false, nil
+	}
 	log.Printf("DID校验结果 - DID存在: %s", did)
 	return true, nil
 }
+
+// GetDidNonce 查询DID最后一次签名更新使用的nonce，从未签名更新时为0，下一次签名更新须使用该值加一
+func (c *DIDChaincode) GetDidNonce(ctx common.TransactionContextInterface, did string) (int64, error) {
+	log.Printf("开始查询DID签名更新nonce - DID: %s", did)
+	if strings.TrimSpace(did) == "" {
+		log.Printf("参数校验失败 - DID为空")
+		return 0, errors.New("did cannot be empty")
+	}
+	info, err := getDidInfo(ctx, did)
+	if err != nil {
+		return 0, err
+	}
+	if info == nil {
+		log.Printf("DID签名更新nonce查询失败 - DID不存在: %s", did)
+		return 0, errors.New("did not found")
+	}
+	log.Printf("DID签名更新nonce查询成功 - DID: %s, nonce: %d", did, info.Nonce)
+	return info.Nonce, nil
+}
+
+// IsDidDeactivated 查询DID是否已注销，DID不存在时返回false，供其他模块调用
+func IsDidDeactivated(ctx contractapi.TransactionContextInterface, did string) (bool, error) {
+	info, err := getDidInfo(ctx, did)
+	if err != nil || info == nil {
+		return false, err
+	}
+	return info.Deactivated, nil
+}
+
+// ================== 内部方法 ==================
+
+// updateDidDocument 内部方法：校验并更新DID文档，authorize校验调用者是否有权更新，返回需要附加到事件中的字段
+func updateDidDocument(ctx common.TransactionContextInterface, did, didDocument string, authorize func(info *DidInfo) (map[string]interface{}, error)) error {
+	if strings.TrimSpace(did) == "" || strings.TrimSpace(didDocument) == "" {
+		log.Printf("参数校验失败 - DID或DID文档为空")
+		return errors.New("did and didDocument cannot be empty")
+	}
+
+	// 按W3C DID Core数据模型校验DID文档
+	if _, err := ParseDocument(did, didDocument); err != nil {
+		log.Printf("DID文档校验失败: %v", err)
+		return err
+	}
+	log.Printf("DID文档校验通过 - DID: %s", did)
+
+	key := didInfoPrefix + did
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil || b == nil {
+		log.Printf("DID文档更新失败 - DID不存在: %s", did)
+		return errors.New("did not found")
+	}
+	var info DidInfo
+	_ = json.Unmarshal(b, &info)
+	if info.Deactivated {
+		log.Printf("DID文档更新失败 - DID已注销: %s", did)
+		return errors.New("did is deactivated")
+	}
+	extra, err := authorize(&info)
+	if err != nil {
+		return err
+	}
+
+	txTime, err := common.GetTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	info.DidDocument = didDocument
+	info.Updated = txTime.Unix()
+	info.VersionId++
+	b, _ = json.Marshal(info)
+	if err := ctx.GetStub().PutState(key, b); err != nil {
+		log.Printf("DID文档更新存储失败: %v", err)
+		return err
+	}
+	log.Printf("DID文档更新存储成功 - DID: %s", did)
+
+	// 获取项目配置信息，用于事件通知
+	cfg, err := ctx.GetProjectConfig()
+	if err != nil {
+		log.Printf("获取项目配置失败: %v", err)
+		// 如果获取配置失败，仍然发送事件，但不包含项目信息
+		return common.EmitEvent(ctx, "DidDocumentUpdated", b)
+	}
+
+	// 构建包含项目信息的事件数据
+	eventData := map[string]interface{}{
+		"serviceCode": cfg.ServiceCode,
+		"projectCode": cfg.ProjectCode,
+		"did":         did,
+		"didDocument": info,
+		"sender":      ctx.GetCaller(),
+	}
+	for k, v := range extra {
+		eventData[k] = v
+	}
+	eventPayload, _ := json.Marshal(eventData)
+	log.Printf("触发DID文档更新事件 - DID: %s", did)
+	return common.EmitEvent(ctx, "DidDocumentUpdated", eventPayload)
+}
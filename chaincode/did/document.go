@@ -0,0 +1,487 @@
+package did
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"sbp-did-chaincode/common"
+)
+
+// didContextV1 DID Core JSON-LD表示中@context的第一个值
+const didContextV1 = "https://www.w3.org/ns/did/v1"
+
+// verificationRelationships DID Core定义的验证关系，值为引用（DID URL）或内嵌的验证方法
+var verificationRelationships = []string{
+	"authentication",
+	"assertionMethod",
+	"keyAgreement",
+	"capabilityInvocation",
+	"capabilityDelegation",
+}
+
+// jwkPrivateMembers JWK中的私钥成员，DID文档中的publicKeyJwk不能包含
+var jwkPrivateMembers = []string{"d", "p", "q", "dp", "dq", "qi", "oth", "k"}
+
+// Document 按W3C DID Core数据模型解析后的DID文档，引用均已解析为绝对DID URL
+type Document struct {
+	ID                 string                          // DID标识符
+	Controllers        []string                        // 控制者DID
+	VerificationMethod []VerificationMethod            // verificationMethod中定义的验证方法
+	Relationships      map[string][]VerificationMethod // 验证关系 -> 验证方法（包括引用和内嵌的验证方法）
+	Services           []Service                       // 服务端点
+}
+
+// VerificationMethod DID文档中的验证方法
+type VerificationMethod struct {
+	ID                 string                 `json:"id"`                           // 验证方法ID（绝对DID URL）
+	Type               string                 `json:"type"`                         // 验证方法类型，如 JsonWebKey2020
+	Controller         string                 `json:"controller"`                   // 控制者DID
+	PublicKeyJwk       map[string]interface{} `json:"publicKeyJwk,omitempty"`       // JWK格式公钥
+	PublicKeyMultibase string                 `json:"publicKeyMultibase,omitempty"` // multibase格式公钥
+}
+
+// Service DID文档中的服务端点
+type Service struct {
+	ID              string          `json:"id"`              // 服务ID（绝对URI）
+	Type            json.RawMessage `json:"type"`            // 服务类型，字符串或字符串集合
+	ServiceEndpoint json.RawMessage `json:"serviceEndpoint"` // 服务端点，URI、对象或其集合
+}
+
+// FieldError DID文档中单个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`   // 字段路径，如 verificationMethod[0].controller
+	Message string `json:"message"` // 错误原因
+}
+
+// DocumentError DID文档校验错误，包含所有不符合DID Core的字段
+type DocumentError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *DocumentError) Error() string {
+	parts := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		parts = append(parts, fe.Field+": "+fe.Message)
+	}
+	return "invalid didDocument: " + strings.Join(parts, "; ")
+}
+
+// documentParser 解析DID文档时收集字段错误
+type documentParser struct {
+	doc     *Document
+	errors  []FieldError
+	ids     map[string]string // 验证方法ID -> 首次定义的字段路径
+	methods map[string]VerificationMethod
+}
+
+// ParseDocument 按W3C DID Core数据模型解析并校验DID文档
+// 校验内容：JSON对象格式、@context、id与did一致、controller格式、alsoKnownAs、verificationMethod结构及ID唯一性、
+// 验证关系中的引用（相对DID URL按文档id解析）、service结构及ID唯一性；任一字段不合规时返回*DocumentError
+func ParseDocument(did, didDocument string) (*Document, error) {
+	var raw map[string]json.RawMessage
+	decoder := json.NewDecoder(strings.NewReader(didDocument))
+	if err := decoder.Decode(&raw); err != nil || raw == nil {
+		return nil, &DocumentError{Errors: []FieldError{{Field: "didDocument", Message: "must be a JSON object"}}}
+	}
+	if decoder.More() {
+		return nil, &DocumentError{Errors: []FieldError{{Field: "didDocument", Message: "unexpected data after JSON object"}}}
+	}
+
+	p := &documentParser{
+		doc:     &Document{Relationships: make(map[string][]VerificationMethod)},
+		ids:     make(map[string]string),
+		methods: make(map[string]VerificationMethod),
+	}
+	p.parseContext(raw["@context"])
+	p.parseID(did, raw["id"])
+	p.parseControllers(raw["controller"])
+	p.parseAlsoKnownAs(raw["alsoKnownAs"])
+	p.parseVerificationMethods(raw["verificationMethod"])
+	for _, relationship := range verificationRelationships {
+		p.parseRelationship(relationship, raw[relationship])
+	}
+	p.parseServices(raw["service"])
+
+	if len(p.errors) > 0 {
+		return nil, &DocumentError{Errors: p.errors}
+	}
+	return p.doc, nil
+}
+
+// MethodsFor 返回验证关系下的所有验证方法
+func (d *Document) MethodsFor(relationship string) []VerificationMethod {
+	return d.Relationships[relationship]
+}
+
+// ================== 字段解析 ==================
+
+func (p *documentParser) fail(field, format string, args ...interface{}) {
+	p.errors = append(p.errors, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// parseContext @context必须存在，为字符串或数组，且第一个值为DID Core上下文
+func (p *documentParser) parseContext(raw json.RawMessage) {
+	if raw == nil {
+		p.fail("@context", "is required")
+		return
+	}
+	var first interface{}
+	var single string
+	var list []interface{}
+	if json.Unmarshal(raw, &single) == nil {
+		first = single
+	} else if json.Unmarshal(raw, &list) == nil && len(list) > 0 {
+		first = list[0]
+		for i, item := range list {
+			switch item.(type) {
+			case string, map[string]interface{}:
+			default:
+				p.fail(fmt.Sprintf("@context[%d]", i), "must be a string or an object")
+			}
+		}
+	} else {
+		p.fail("@context", "must be a string or a non-empty array")
+		return
+	}
+	if first != didContextV1 {
+		p.fail("@context", "first value must be %s", didContextV1)
+	}
+}
+
+// parseID id必须为合法DID且与注册/更新的did一致
+func (p *documentParser) parseID(did string, raw json.RawMessage) {
+	if raw == nil {
+		p.fail("id", "is required")
+		return
+	}
+	var id string
+	if err := json.Unmarshal(raw, &id); err != nil {
+		p.fail("id", "must be a string")
+		return
+	}
+	if _, _, err := common.ParseDID(id); err != nil {
+		p.fail("id", "%v", err)
+		return
+	}
+	if id != did {
+		p.fail("id", "must equal did %s", did)
+		return
+	}
+	p.doc.ID = id
+}
+
+// parseControllers controller为DID字符串或DID字符串集合
+func (p *documentParser) parseControllers(raw json.RawMessage) {
+	if raw == nil {
+		return
+	}
+	controllers, ok := stringOrSet(raw)
+	if !ok {
+		p.fail("controller", "must be a DID string or a set of DID strings")
+		return
+	}
+	for i, controller := range controllers {
+		field := "controller"
+		if isJSONArray(raw) {
+			field = fmt.Sprintf("controller[%d]", i)
+		}
+		if _, _, err := common.ParseDID(controller); err != nil {
+			p.fail(field, "%v", err)
+			continue
+		}
+		p.doc.Controllers = append(p.doc.Controllers, controller)
+	}
+}
+
+// parseAlsoKnownAs alsoKnownAs为URI集合
+func (p *documentParser) parseAlsoKnownAs(raw json.RawMessage) {
+	if raw == nil {
+		return
+	}
+	var items []string
+	if err := json.Unmarshal(raw, &items); err != nil {
+		p.fail("alsoKnownAs", "must be a set of URI strings")
+		return
+	}
+	for i, item := range items {
+		if !isAbsoluteURI(item) {
+			p.fail(fmt.Sprintf("alsoKnownAs[%d]", i), "must be an absolute URI")
+		}
+	}
+}
+
+// parseVerificationMethods verificationMethod为验证方法对象集合
+func (p *documentParser) parseVerificationMethods(raw json.RawMessage) {
+	if raw == nil {
+		return
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		p.fail("verificationMethod", "must be a set of verification method objects")
+		return
+	}
+	for i, item := range items {
+		if method, ok := p.parseVerificationMethod(fmt.Sprintf("verificationMethod[%d]", i), item); ok {
+			p.doc.VerificationMethod = append(p.doc.VerificationMethod, method)
+		}
+	}
+}
+
+// parseVerificationMethod 校验单个验证方法：id（DID URL，允许以#开头的相对引用）、type、controller必填，
+// publicKeyJwk与publicKeyMultibase至多一个，publicKeyJwk不能包含私钥成员；ID在文档内唯一
+func (p *documentParser) parseVerificationMethod(field string, raw json.RawMessage) (VerificationMethod, bool) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil || obj == nil {
+		p.fail(field, "must be a verification method object")
+		return VerificationMethod{}, false
+	}
+	before := len(p.errors)
+	var method VerificationMethod
+
+	if id, ok := p.requireString(field+".id", obj["id"]); ok {
+		if absolute, err := p.resolveDIDURL(id); err != nil {
+			p.fail(field+".id", "%v", err)
+		} else if first, exists := p.ids[absolute]; exists {
+			p.fail(field+".id", "duplicate verification method id %s, already defined in %s", absolute, first)
+		} else {
+			p.ids[absolute] = field
+			method.ID = absolute
+		}
+	}
+	method.Type, _ = p.requireString(field+".type", obj["type"])
+	if controller, ok := p.requireString(field+".controller", obj["controller"]); ok {
+		if _, _, err := common.ParseDID(controller); err != nil {
+			p.fail(field+".controller", "%v", err)
+		}
+		method.Controller = controller
+	}
+
+	if obj["publicKeyJwk"] != nil && obj["publicKeyMultibase"] != nil {
+		p.fail(field, "must not contain both publicKeyJwk and publicKeyMultibase")
+	}
+	if raw := obj["publicKeyJwk"]; raw != nil {
+		var jwk map[string]interface{}
+		if err := json.Unmarshal(raw, &jwk); err != nil || jwk == nil {
+			p.fail(field+".publicKeyJwk", "must be a JSON Web Key object")
+		} else {
+			if kty, _ := jwk["kty"].(string); kty == "" {
+				p.fail(field+".publicKeyJwk.kty", "is required")
+			}
+			for _, member := range jwkPrivateMembers {
+				if _, exists := jwk[member]; exists {
+					p.fail(field+".publicKeyJwk."+member, "private key material must not be published")
+				}
+			}
+			method.PublicKeyJwk = jwk
+		}
+	}
+	if raw := obj["publicKeyMultibase"]; raw != nil {
+		if value, ok := p.requireString(field+".publicKeyMultibase", raw); ok {
+			method.PublicKeyMultibase = value
+		}
+	}
+
+	if len(p.errors) > before {
+		return VerificationMethod{}, false
+	}
+	p.methods[method.ID] = method
+	return method, true
+}
+
+// parseRelationship 验证关系为引用或内嵌验证方法的集合，引用本文档的验证方法时必须已在verificationMethod中定义
+func (p *documentParser) parseRelationship(relationship string, raw json.RawMessage) {
+	if raw == nil {
+		return
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		p.fail(relationship, "must be a set of DID URL references or verification method objects")
+		return
+	}
+	for i, item := range items {
+		field := fmt.Sprintf("%s[%d]", relationship, i)
+		var ref string
+		if json.Unmarshal(item, &ref) != nil {
+			if method, ok := p.parseVerificationMethod(field, item); ok {
+				p.doc.Relationships[relationship] = append(p.doc.Relationships[relationship], method)
+			}
+			continue
+		}
+		absolute, err := p.resolveDIDURL(ref)
+		if err != nil {
+			p.fail(field, "%v", err)
+			continue
+		}
+		method, defined := p.methods[absolute]
+		if !defined {
+			if p.doc.ID != "" && didOfURL(absolute) == p.doc.ID {
+				p.fail(field, "references undefined verification method %s", absolute)
+			}
+			// 引用其他DID的验证方法，无法在本文档内解析
+			continue
+		}
+		p.doc.Relationships[relationship] = append(p.doc.Relationships[relationship], method)
+	}
+}
+
+// parseServices service为服务对象集合：id（URI）、type（字符串或字符串集合）、serviceEndpoint（URI、对象或其集合）必填
+func (p *documentParser) parseServices(raw json.RawMessage) {
+	if raw == nil {
+		return
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		p.fail("service", "must be a set of service objects")
+		return
+	}
+	serviceIDs := make(map[string]string)
+	for i, item := range items {
+		field := fmt.Sprintf("service[%d]", i)
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(item, &obj); err != nil || obj == nil {
+			p.fail(field, "must be a service object")
+			continue
+		}
+		before := len(p.errors)
+		service := Service{Type: obj["type"], ServiceEndpoint: obj["serviceEndpoint"]}
+		if id, ok := p.requireString(field+".id", obj["id"]); ok {
+			absolute := id
+			if strings.HasPrefix(id, "#") {
+				absolute = p.doc.ID + id
+			}
+			if !isAbsoluteURI(absolute) {
+				p.fail(field+".id", "must be a URI")
+			} else if first, exists := serviceIDs[absolute]; exists {
+				p.fail(field+".id", "duplicate service id %s, already defined in %s", absolute, first)
+			} else {
+				serviceIDs[absolute] = field
+				service.ID = absolute
+			}
+		}
+		if obj["type"] == nil {
+			p.fail(field+".type", "is required")
+		} else if types, ok := stringOrSet(obj["type"]); !ok || len(types) == 0 || containsEmpty(types) {
+			p.fail(field+".type", "must be a non-empty string or a set of non-empty strings")
+		}
+		p.validateServiceEndpoint(field+".serviceEndpoint", obj["serviceEndpoint"])
+		if len(p.errors) == before {
+			p.doc.Services = append(p.doc.Services, service)
+		}
+	}
+}
+
+// validateServiceEndpoint serviceEndpoint为URI字符串、对象，或由二者组成的非空集合
+func (p *documentParser) validateServiceEndpoint(field string, raw json.RawMessage) {
+	if raw == nil {
+		p.fail(field, "is required")
+		return
+	}
+	var items []json.RawMessage
+	if isJSONArray(raw) {
+		if err := json.Unmarshal(raw, &items); err != nil || len(items) == 0 {
+			p.fail(field, "must be a URI, an object or a non-empty set of them")
+			return
+		}
+	} else {
+		items = []json.RawMessage{raw}
+	}
+	for i, item := range items {
+		itemField := field
+		if isJSONArray(raw) {
+			itemField = fmt.Sprintf("%s[%d]", field, i)
+		}
+		var endpoint string
+		if json.Unmarshal(item, &endpoint) == nil {
+			if !isAbsoluteURI(endpoint) {
+				p.fail(itemField, "must be an absolute URI")
+			}
+			continue
+		}
+		var obj map[string]interface{}
+		if json.Unmarshal(item, &obj) != nil || obj == nil {
+			p.fail(itemField, "must be a URI or an object")
+		}
+	}
+}
+
+// ================== 工具方法 ==================
+
+// requireString 读取必填的非空字符串字段
+func (p *documentParser) requireString(field string, raw json.RawMessage) (string, bool) {
+	if raw == nil {
+		p.fail(field, "is required")
+		return "", false
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		p.fail(field, "must be a string")
+		return "", false
+	}
+	if strings.TrimSpace(value) == "" {
+		p.fail(field, "cannot be empty")
+		return "", false
+	}
+	return value, true
+}
+
+// resolveDIDURL 将以#开头的相对DID URL按文档id解析为绝对DID URL，并校验其DID部分
+func (p *documentParser) resolveDIDURL(ref string) (string, error) {
+	if strings.HasPrefix(ref, "#") {
+		if len(ref) == 1 {
+			return "", fmt.Errorf("empty fragment in DID URL %s", ref)
+		}
+		if p.doc.ID == "" {
+			return "", fmt.Errorf("cannot resolve relative DID URL %s without a valid id", ref)
+		}
+		return p.doc.ID + ref, nil
+	}
+	if _, _, err := common.ParseDID(didOfURL(ref)); err != nil {
+		return "", fmt.Errorf("invalid DID URL %s: %v", ref, err)
+	}
+	return ref, nil
+}
+
+// didOfURL 返回DID URL中路径、查询和片段之前的DID部分
+func didOfURL(didURL string) string {
+	if idx := strings.IndexAny(didURL, "/?#"); idx >= 0 {
+		return didURL[:idx]
+	}
+	return didURL
+}
+
+// stringOrSet 解析字符串或字符串集合
+func stringOrSet(raw json.RawMessage) ([]string, bool) {
+	var single string
+	if json.Unmarshal(raw, &single) == nil {
+		return []string{single}, true
+	}
+	var set []string
+	if json.Unmarshal(raw, &set) == nil {
+		return set, true
+	}
+	return nil, false
+}
+
+// isJSONArray 判断JSON值是否为数组
+func isJSONArray(raw json.RawMessage) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(raw), []byte("["))
+}
+
+// isAbsoluteURI 判断字符串为带scheme的绝对URI
+func isAbsoluteURI(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && u.Scheme != ""
+}
+
+// containsEmpty 判断集合中是否包含空字符串
+func containsEmpty(values []string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(v) == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package did
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseDocument(t *testing.T) {
+	const did = "did:sbp:alice"
+	tests := []struct {
+		name       string
+		document   string
+		wantFields []string // 期望报告的字段路径，为空表示文档合法
+	}{
+		{
+			name: "valid",
+			document: `{
+				"@context": ["https://www.w3.org/ns/did/v1"],
+				"id": "did:sbp:alice",
+				"controller": "did:sbp:bob",
+				"verificationMethod": [
+					{"id": "#key-1", "type": "JsonWebKey2020", "controller": "did:sbp:alice",
+					 "publicKeyJwk": {"kty": "OKP", "crv": "Ed25519", "x": "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}},
+					{"id": "did:sbp:alice#key-2", "type": "Multikey", "controller": "did:sbp:alice",
+					 "publicKeyMultibase": "z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK"}
+				],
+				"authentication": ["#key-1", "did:sbp:bob#key-1"],
+				"assertionMethod": ["did:sbp:alice#key-2"],
+				"service": [{"id": "did:sbp:alice#svc", "type": "LinkedDomains", "serviceEndpoint": "https://example.com"}]
+			}`,
+		},
+		{
+			name:       "id is not a did",
+			document:   `{"@context": "https://www.w3.org/ns/did/v1", "id": "alice"}`,
+			wantFields: []string{"id"},
+		},
+		{
+			name:       "id differs from did",
+			document:   `{"@context": "https://www.w3.org/ns/did/v1", "id": "did:sbp:bob"}`,
+			wantFields: []string{"id"},
+		},
+		{
+			name:       "controller is not a did",
+			document:   `{"@context": "https://www.w3.org/ns/did/v1", "id": "did:sbp:alice", "controller": ["did:sbp:bob", "https://example.com"]}`,
+			wantFields: []string{"controller[1]"},
+		},
+		{
+			name: "duplicate verification method ids",
+			document: `{"@context": "https://www.w3.org/ns/did/v1", "id": "did:sbp:alice", "verificationMethod": [
+				{"id": "#key-1", "type": "Multikey", "controller": "did:sbp:alice", "publicKeyMultibase": "z1"},
+				{"id": "did:sbp:alice#key-1", "type": "Multikey", "controller": "did:sbp:alice", "publicKeyMultibase": "z2"}
+			]}`,
+			wantFields: []string{"verificationMethod[1].id"},
+		},
+		{
+			name: "relative verification method id without fragment",
+			document: `{"@context": "https://www.w3.org/ns/did/v1", "id": "did:sbp:alice", "verificationMethod": [
+				{"id": "key-1", "type": "Multikey", "controller": "did:sbp:alice", "publicKeyMultibase": "z1"}
+			]}`,
+			wantFields: []string{"verificationMethod[0].id"},
+		},
+		{
+			name:       "relative reference to undefined verification method",
+			document:   `{"@context": "https://www.w3.org/ns/did/v1", "id": "did:sbp:alice", "authentication": ["#key-1"]}`,
+			wantFields: []string{"authentication[0]"},
+		},
+		{
+			name: "relative reference without a valid id",
+			document: `{"@context": "https://www.w3.org/ns/did/v1", "id": "alice", "verificationMethod": [
+				{"id": "#key-1", "type": "Multikey", "controller": "did:sbp:alice", "publicKeyMultibase": "z1"}
+			]}`,
+			wantFields: []string{"id", "verificationMethod[0].id"},
+		},
+		{
+			name: "several invalid fields",
+			document: `{"id": "did:sbp:alice", "verificationMethod": [
+				{"id": "#key-1", "controller": "bob", "publicKeyJwk": {"kty": "OKP", "d": "secret"}}
+			]}`,
+			wantFields: []string{
+				"@context",
+				"verificationMethod[0].type",
+				"verificationMethod[0].controller",
+				"verificationMethod[0].publicKeyJwk.d",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := ParseDocument(did, tt.document)
+			if len(tt.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("ParseDocument: %v", err)
+				}
+				if doc.ID != did || len(doc.VerificationMethod) != 2 || len(doc.MethodsFor("authentication")) != 1 {
+					t.Fatalf("unexpected document %+v", doc)
+				}
+				if got := doc.MethodsFor("authentication")[0].ID; got != did+"#key-1" {
+					t.Fatalf("relative reference resolved to %s", got)
+				}
+				return
+			}
+			var docErr *DocumentError
+			if !errors.As(err, &docErr) {
+				t.Fatalf("got err %v, want *DocumentError", err)
+			}
+			fields := make([]string, 0, len(docErr.Errors))
+			for _, fe := range docErr.Errors {
+				fields = append(fields, fe.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Fatalf("got fields %v, want %v (%v)", fields, tt.wantFields, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package did
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// resolutionErrorInvalidOptions 历史版本解析参数非法时的错误码
+const resolutionErrorInvalidOptions = "invalidOptions"
+
+// DidVersion DID记录的一个历史版本，对应GetHistoryForKey中的一次修改
+type DidVersion struct {
+	VersionId   int64           `json:"versionId"`   // 版本号，即该修改在DID记录历史中的序号（从1开始）
+	TxId        string          `json:"txId"`        // 写入该版本的交易ID
+	Timestamp   int64           `json:"timestamp"`   // 写入时间（Unix秒）
+	IsDelete    bool            `json:"isDelete"`    // 该交易删除了DID记录，此时didDocument为null
+	Deactivated bool            `json:"deactivated"` // 该版本DID是否已注销
+	DidDocument json.RawMessage `json:"didDocument"` // 该版本的DID文档，注销后为注销前的最后版本
+	created     int64           // 该版本所属DID的注册时间，用于生成文档元数据
+}
+
+// ================== 历史版本查询相关 ==================
+
+// GetDidHistory 按版本从旧到新查询DID记录的所有历史版本（含注销和删除），返回DidVersion数组的JSON字符串
+func (c *DIDChaincode) GetDidHistory(ctx common.TransactionContextInterface, did string) (string, error) {
+	log.Printf("开始查询DID历史版本 - DID: %s", did)
+	if strings.TrimSpace(did) == "" {
+		log.Printf("参数校验失败 - DID为空")
+		return "", errors.New("did cannot be empty")
+	}
+	versions, err := getDidHistory(ctx, did)
+	if err != nil {
+		log.Printf("查询DID历史版本失败: %v", err)
+		return "", err
+	}
+	if len(versions) == 0 {
+		log.Printf("DID历史版本查询失败 - DID不存在: %s", did)
+		return "", errors.New("did not found")
+	}
+	b, err := json.Marshal(versions)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("DID历史版本查询成功 - DID: %s, 版本数: %d", did, len(versions))
+	return string(b), nil
+}
+
+// ResolveDidAt 按版本号或时间解析DID的历史版本，返回格式与ResolveDid相同
+// 参数说明：
+// - versionId: 版本号（GetDidHistory中的versionId），与versionTime二选一
+// - versionTime: RFC3339格式的时间，如 2024-01-02T15:04:05Z，解析该时间点生效的版本，与versionId二选一
+//
+// 所解析的版本之后还有修改时，didDocumentMetadata中给出nextUpdate和nextVersionId；
+// 参数非法时didResolutionMetadata.error为invalidOptions，版本不存在或该版本DID已被删除时为notFound
+func (c *DIDChaincode) ResolveDidAt(ctx common.TransactionContextInterface, did, versionId, versionTime string) (string, error) {
+	log.Printf("开始解析DID历史版本 - DID: %s, 版本号: %s, 时间: %s", did, versionId, versionTime)
+	if _, _, err := common.ParseDID(did); err != nil {
+		log.Printf("DID解析失败 - DID无效: %v", err)
+		return marshalResolution(resolutionError(resolutionErrorInvalidDid))
+	}
+	match, err := parseVersionSelector(versionId, versionTime)
+	if err != nil {
+		log.Printf("DID解析失败 - 参数非法: %v", err)
+		return marshalResolution(resolutionError(resolutionErrorInvalidOptions))
+	}
+
+	versions, err := getDidHistory(ctx, did)
+	if err != nil {
+		log.Printf("查询DID历史版本失败: %v", err)
+		return "", err
+	}
+	// 按时间解析时取该时间点之前的最后一个版本
+	index := -1
+	for i, version := range versions {
+		if match(version) {
+			index = i
+		}
+	}
+	if index < 0 || versions[index].IsDelete {
+		log.Printf("DID解析失败 - 版本不存在: %s", did)
+		return marshalResolution(resolutionError(resolutionErrorNotFound))
+	}
+
+	version := versions[index]
+	metadata := DocumentMetadata{
+		Created:     formatMetadataTime(version.created),
+		Updated:     formatMetadataTime(version.Timestamp),
+		VersionId:   strconv.FormatInt(version.VersionId, 10),
+		Deactivated: version.Deactivated,
+	}
+	if index+1 < len(versions) {
+		next := versions[index+1]
+		metadata.NextUpdate = formatMetadataTime(next.Timestamp)
+		metadata.NextVersionId = strconv.FormatInt(next.VersionId, 10)
+	}
+	log.Printf("DID历史版本解析成功 - DID: %s, 版本: %d, 已注销: %t", did, version.VersionId, version.Deactivated)
+	return marshalResolution(&ResolutionResult{
+		DidDocument:           version.DidDocument,
+		DidDocumentMetadata:   metadata,
+		DidResolutionMetadata: ResolutionMetadata{ContentType: didContentType},
+	})
+}
+
+// ================== 内部方法 ==================
+
+// getDidHistory 内部方法：通过GetHistoryForKey读取DID记录的所有历史版本，按时间从旧到新排列
+// 版本号按历史顺序编号，对记录版本号后注册的DID与DidInfo.VersionId一致
+func getDidHistory(ctx contractapi.TransactionContextInterface, did string) ([]*DidVersion, error) {
+	iterator, err := ctx.GetStub().GetHistoryForKey(didInfoPrefix + did)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	versions := make([]*DidVersion, 0)
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		version := &DidVersion{TxId: modification.TxId, IsDelete: modification.IsDelete, DidDocument: json.RawMessage("null")}
+		if modification.Timestamp != nil {
+			version.Timestamp = modification.Timestamp.Seconds
+		}
+		if !modification.IsDelete {
+			var info DidInfo
+			if err := json.Unmarshal(modification.Value, &info); err != nil {
+				return nil, err
+			}
+			version.Deactivated = info.Deactivated
+			version.DidDocument = json.RawMessage(info.DidDocument)
+			version.created = info.Created
+		}
+		versions = append([]*DidVersion{version}, versions...)
+	}
+
+	// 早期未记录注册时间的版本，以本次注册（上一次删除之后的第一个版本）的写入时间为注册时间
+	var created int64
+	for i, version := range versions {
+		version.VersionId = int64(i + 1)
+		if version.IsDelete {
+			created = 0
+			continue
+		}
+		if created == 0 {
+			created = version.Timestamp
+		}
+		if version.created == 0 {
+			version.created = created
+		}
+	}
+	return versions, nil
+}
+
+// latestVersionId 内部方法：返回DID记录最新版本在历史中的版本号，没有历史时返回0
+func latestVersionId(ctx contractapi.TransactionContextInterface, did string) (int64, error) {
+	versions, err := getDidHistory(ctx, did)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(versions)), nil
+}
+
+// parseVersionSelector 解析ResolveDidAt的版本参数，versionId与versionTime必须且只能指定一个
+// 返回的匹配函数对按版本号匹配的版本、或写入时间不晚于versionTime的所有版本返回true
+func parseVersionSelector(versionId, versionTime string) (func(*DidVersion) bool, error) {
+	versionId, versionTime = strings.TrimSpace(versionId), strings.TrimSpace(versionTime)
+	if (versionId == "") == (versionTime == "") {
+		return nil, errors.New("exactly one of versionId and versionTime must be specified")
+	}
+	if versionId != "" {
+		id, err := strconv.ParseInt(versionId, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid versionId %s", versionId)
+		}
+		return func(version *DidVersion) bool { return version.VersionId == id }, nil
+	}
+	t, err := time.Parse(time.RFC3339, versionTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid versionTime %s", versionTime)
+	}
+	return func(version *DidVersion) bool { return version.Timestamp <= t.Unix() }, nil
+}
@@ -0,0 +1,197 @@
+package did
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"sbp-did-chaincode/accesscontrol"
+	"sbp-did-chaincode/internal/testutil"
+)
+
+// historyTest 在记录历史的存根上执行DID合约交易
+type historyTest struct {
+	t      *testing.T
+	stub   *testutil.LedgerStub
+	cc     *DIDChaincode
+	caller string
+	txs    int
+}
+
+func newHistoryTest(t *testing.T) *historyTest {
+	mock, caller := testutil.NewMockStub(t, nil, "Org1MSP")
+	return &historyTest{
+		t:      t,
+		stub:   testutil.NewLedgerStub(mock),
+		cc:     new(DIDChaincode),
+		caller: caller,
+	}
+}
+
+// at 开始交易时间为unix的新交易
+func (h *historyTest) at(unix int64) *accesscontrol.TransactionContext {
+	h.txs++
+	testutil.StartTx(h.stub.MockStub, "tx"+strconv.Itoa(h.txs), time.Unix(unix, 0))
+	ctx := accesscontrol.NewTransactionContext(&testutil.FakeChecker{})
+	ctx.SetStub(h.stub)
+	return ctx
+}
+
+// resolve 解析DID并返回解析结果
+func (h *historyTest) resolve(result string, err error) *ResolutionResult {
+	h.t.Helper()
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	var resolution ResolutionResult
+	if err := json.Unmarshal([]byte(result), &resolution); err != nil {
+		h.t.Fatal(err)
+	}
+	return &resolution
+}
+
+func historyDocument(did, service string) string {
+	return `{"@context":"https://www.w3.org/ns/did/v1","id":"` + did +
+		`","service":[{"id":"` + did + `#svc","type":"LinkedDomains","serviceEndpoint":"` + service + `"}]}`
+}
+
+func rfc3339(unix int64) string {
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
+
+func TestResolveDidAt(t *testing.T) {
+	const did = "did:sbp:alice"
+	const (
+		created     int64 = 1700000000
+		updated     int64 = created + 100
+		deactivated int64 = created + 200
+	)
+	v1, v2 := historyDocument(did, "https://example.com/v1"), historyDocument(did, "https://example.com/v2")
+	h := newHistoryTest(t)
+	if err := h.cc.RegisterDid(h.at(created), did, v1); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.cc.UpdateDidDocument(h.at(updated), did, v2); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.cc.DeactivateDid(h.at(deactivated), did); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		versionId     string
+		versionTime   string
+		wantError     string
+		wantDocument  string
+		wantVersionId string
+		wantUpdated   int64
+		wantNext      string
+		wantNextTime  int64
+		deactivated   bool
+	}{
+		{name: "before create", versionTime: rfc3339(created - 1), wantError: resolutionErrorNotFound},
+		{name: "at create", versionTime: rfc3339(created), wantDocument: v1, wantVersionId: "1", wantUpdated: created, wantNext: "2", wantNextTime: updated},
+		{name: "between versions", versionTime: rfc3339(updated - 1), wantDocument: v1, wantVersionId: "1", wantUpdated: created, wantNext: "2", wantNextTime: updated},
+		{name: "exactly at update", versionTime: rfc3339(updated), wantDocument: v2, wantVersionId: "2", wantUpdated: updated, wantNext: "3", wantNextTime: deactivated},
+		{name: "after deactivation", versionTime: rfc3339(deactivated + 3600), wantDocument: v2, wantVersionId: "3", wantUpdated: deactivated, deactivated: true},
+		{name: "by version id", versionId: "2", wantDocument: v2, wantVersionId: "2", wantUpdated: updated, wantNext: "3", wantNextTime: deactivated},
+		{name: "unknown version id", versionId: "4", wantError: resolutionErrorNotFound},
+		{name: "no selector", wantError: resolutionErrorInvalidOptions},
+		{name: "both selectors", versionId: "1", versionTime: rfc3339(created), wantError: resolutionErrorInvalidOptions},
+		{name: "invalid time", versionTime: "2023-11-14 22:13:20", wantError: resolutionErrorInvalidOptions},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := h.resolve(h.cc.ResolveDidAt(h.at(deactivated+7200), did, tt.versionId, tt.versionTime))
+			if result.DidResolutionMetadata.Error != tt.wantError {
+				t.Fatalf("got resolution error %q, want %q", result.DidResolutionMetadata.Error, tt.wantError)
+			}
+			if tt.wantError != "" {
+				return
+			}
+			metadata := result.DidDocumentMetadata
+			if string(result.DidDocument) != tt.wantDocument {
+				t.Fatalf("got document %s, want %s", result.DidDocument, tt.wantDocument)
+			}
+			want := DocumentMetadata{
+				Created:       formatMetadataTime(created),
+				Updated:       formatMetadataTime(tt.wantUpdated),
+				VersionId:     tt.wantVersionId,
+				NextUpdate:    formatMetadataTime(tt.wantNextTime),
+				NextVersionId: tt.wantNext,
+				Deactivated:   tt.deactivated,
+			}
+			if metadata != want {
+				t.Fatalf("got metadata %+v, want %+v", metadata, want)
+			}
+		})
+	}
+
+	t.Run("latest version matches ResolveDid", func(t *testing.T) {
+		latest := h.resolve(h.cc.ResolveDid(h.at(deactivated+7200), did))
+		at := h.resolve(h.cc.ResolveDidAt(h.at(deactivated+7200), did, "", rfc3339(deactivated+7200)))
+		if latest.DidDocumentMetadata != at.DidDocumentMetadata {
+			t.Fatalf("ResolveDid metadata %+v differs from ResolveDidAt %+v", latest.DidDocumentMetadata, at.DidDocumentMetadata)
+		}
+	})
+}
+
+// 早期注册的DID没有记录版本号，ResolveDid报告历史中的版本号；之后的更新从1开始计数，ResolveDid报告DID记录中的版本号
+func TestResolveDidLegacyVersionId(t *testing.T) {
+	const did = "did:sbp:legacy"
+	const created int64 = 1600000000
+	h := newHistoryTest(t)
+	for i, document := range []string{historyDocument(did, "https://example.com/v1"), historyDocument(did, "https://example.com/v2")} {
+		h.at(created + int64(i)*100)
+		legacy, _ := json.Marshal(map[string]interface{}{"didDocument": document, "sender": h.caller})
+		if err := h.stub.PutState(didInfoPrefix+did, legacy); err != nil {
+			t.Fatal(err)
+		}
+	}
+	latest := h.resolve(h.cc.ResolveDid(h.at(created+200), did))
+	at := h.resolve(h.cc.ResolveDidAt(h.at(created+200), did, "", rfc3339(created+200)))
+	if latest.DidDocumentMetadata.VersionId != "2" || latest.DidDocumentMetadata.VersionId != at.DidDocumentMetadata.VersionId {
+		t.Fatalf("ResolveDid metadata %+v, ResolveDidAt metadata %+v", latest.DidDocumentMetadata, at.DidDocumentMetadata)
+	}
+
+	if err := h.cc.UpdateDidDocument(h.at(created+300), did, historyDocument(did, "https://example.com/v3")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := getDidInfo(h.at(created+400), did)
+	if err != nil || info.VersionId != 1 {
+		t.Fatalf("got info %+v, %v", info, err)
+	}
+	latest = h.resolve(h.cc.ResolveDid(h.at(created+400), did))
+	if latest.DidDocumentMetadata.VersionId != "1" {
+		t.Fatalf("ResolveDid metadata %+v, want the stored versionId", latest.DidDocumentMetadata)
+	}
+}
+
+func TestGetDidHistory(t *testing.T) {
+	const did = "did:sbp:alice"
+	const created int64 = 1700000000
+	h := newHistoryTest(t)
+	if err := h.cc.RegisterDid(h.at(created), did, historyDocument(did, "https://example.com/v1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.cc.DeactivateDid(h.at(created+100), did); err != nil {
+		t.Fatal(err)
+	}
+	result, err := h.cc.GetDidHistory(h.at(created+200), did)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []DidVersion
+	if err := json.Unmarshal([]byte(result), &versions); err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[0].VersionId != 1 || versions[0].Timestamp != created || versions[0].Deactivated ||
+		versions[1].VersionId != 2 || versions[1].Timestamp != created+100 || !versions[1].Deactivated {
+		t.Fatalf("unexpected history %s", result)
+	}
+	if _, err := h.cc.GetDidHistory(h.at(created+200), "did:sbp:unknown"); err == nil {
+		t.Fatal("expected error for unknown did")
+	}
+}
@@ -0,0 +1,142 @@
+package did
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// DID解析元数据中的错误码（W3C DID Resolution）
+const (
+	resolutionErrorInvalidDid = "invalidDid"
+	resolutionErrorNotFound   = "notFound"
+)
+
+// didContentType 解析结果中DID文档的媒体类型
+const didContentType = "application/did+ld+json"
+
+// ResolutionResult DID解析结果，格式参照W3C DID Resolution
+type ResolutionResult struct {
+	DidDocument           json.RawMessage    `json:"didDocument"`           // DID文档，DID不存在或无效时为null
+	DidDocumentMetadata   DocumentMetadata   `json:"didDocumentMetadata"`   // DID文档元数据
+	DidResolutionMetadata ResolutionMetadata `json:"didResolutionMetadata"` // 解析过程元数据
+}
+
+// DocumentMetadata DID文档元数据，时间格式为UTC的 yyyy-MM-ddTHH:mm:ssZ
+type DocumentMetadata struct {
+	Created       string `json:"created,omitempty"`       // 注册时间
+	Updated       string `json:"updated,omitempty"`       // 该版本的更新时间
+	VersionId     string `json:"versionId,omitempty"`     // 该版本的版本号
+	NextUpdate    string `json:"nextUpdate,omitempty"`    // 下一版本的更新时间，仅解析历史版本时存在
+	NextVersionId string `json:"nextVersionId,omitempty"` // 下一版本的版本号，仅解析历史版本时存在
+	Deactivated   bool   `json:"deactivated"`             // 是否已注销
+}
+
+// ResolutionMetadata DID解析过程元数据
+type ResolutionMetadata struct {
+	ContentType string `json:"contentType,omitempty"` // 成功时为DID文档的媒体类型
+	Error       string `json:"error,omitempty"`       // 解析失败时的错误码：invalidDid/notFound
+}
+
+// ResolveDid 解析DID，返回包含didDocument、didDocumentMetadata和didResolutionMetadata的JSON字符串
+// DID无效或不存在时不返回错误，而是在didResolutionMetadata.error中给出invalidDid/notFound；
+// DID已注销时返回注销前的最后版本文档，并在didDocumentMetadata中报告deactivated: true
+func (c *DIDChaincode) ResolveDid(ctx common.TransactionContextInterface, did string) (string, error) {
+	log.Printf("开始解析DID - DID: %s", did)
+	if _, _, err := common.ParseDID(did); err != nil {
+		log.Printf("DID解析失败 - DID无效: %v", err)
+		return marshalResolution(resolutionError(resolutionErrorInvalidDid))
+	}
+	info, err := getDidInfo(ctx, did)
+	if err != nil {
+		return "", err
+	}
+	if info == nil {
+		log.Printf("DID解析失败 - DID不存在: %s", did)
+		return marshalResolution(resolutionError(resolutionErrorNotFound))
+	}
+	metadata := info.metadata()
+	// 早期未记录版本号的DID，版本号取DID记录修改历史中的序号，与ResolveDidAt/GetDidHistory中最新版本的版本号一致
+	if info.VersionId == 0 {
+		versionId, err := latestVersionId(ctx, did)
+		if err != nil {
+			log.Printf("查询DID历史版本失败 - DID: %s, 错误: %v", did, err)
+			return "", err
+		}
+		if versionId > 0 {
+			metadata.VersionId = strconv.FormatInt(versionId, 10)
+		}
+	}
+	log.Printf("DID解析成功 - DID: %s, 版本: %s, 已注销: %t", did, metadata.VersionId, info.Deactivated)
+	return marshalResolution(&ResolutionResult{
+		DidDocument:           json.RawMessage(info.DidDocument),
+		DidDocumentMetadata:   metadata,
+		DidResolutionMetadata: ResolutionMetadata{ContentType: didContentType},
+	})
+}
+
+// ================== 内部方法 ==================
+
+// getDidInfo 内部方法：读取DID信息，DID不存在时返回nil
+func getDidInfo(ctx contractapi.TransactionContextInterface, did string) (*DidInfo, error) {
+	b, err := ctx.GetStub().GetState(didInfoPrefix + did)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var info DidInfo
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// metadata 由DID信息生成文档元数据，早期未记录时间和版本的DID省略对应字段
+func (info *DidInfo) metadata() DocumentMetadata {
+	metadata := DocumentMetadata{
+		Created:     formatMetadataTime(info.Created),
+		Updated:     formatMetadataTime(info.Updated),
+		Deactivated: info.Deactivated,
+	}
+	if info.VersionId > 0 {
+		metadata.VersionId = strconv.FormatInt(info.VersionId, 10)
+	}
+	return metadata
+}
+
+// resolutionError 生成解析失败的结果
+func resolutionError(code string) *ResolutionResult {
+	return &ResolutionResult{
+		DidDocument:           json.RawMessage("null"),
+		DidResolutionMetadata: ResolutionMetadata{Error: code},
+	}
+}
+
+// marshalResolution 序列化解析结果；文档以原始JSON嵌入，存储内容非法时返回错误
+func marshalResolution(result *ResolutionResult) (string, error) {
+	if strings.TrimSpace(string(result.DidDocument)) == "" || !json.Valid(result.DidDocument) {
+		return "", errors.New("stored did document is not valid JSON")
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// formatMetadataTime 将Unix秒格式化为DID Core要求的UTC时间字符串，0表示未记录
+func formatMetadataTime(unix int64) string {
+	if unix <= 0 {
+		return ""
+	}
+	return time.Unix(unix, 0).UTC().Format("2006-01-02T15:04:05Z")
+}
@@ -0,0 +1,123 @@
+package did
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// signatureRelationships 可以授权签名更新DID文档的验证关系
+var signatureRelationships = []string{"authentication", "capabilityInvocation"}
+
+// updateSigningPrefix 签名更新DID文档时待签名消息的固定前缀
+const updateSigningPrefix = "sbp-did:UpdateDidDocument"
+
+// UpdateSigningInput 生成签名更新DID文档时的待签名消息，各字段以换行分隔：
+// sbp-did:UpdateDidDocument、通道ID、项目编码（默认项目为空）、DID、nonce、新的DID文档原文
+// 消息绑定通道、项目和nonce，签名不能在其他通道、项目或之后的更新中重放
+func UpdateSigningInput(channelID, projectCode, did string, nonce int64, didDocument string) []byte {
+	return []byte(strings.Join([]string{
+		updateSigningPrefix,
+		channelID,
+		projectCode,
+		did,
+		strconv.FormatInt(nonce, 10),
+		didDocument,
+	}, "\n"))
+}
+
+// findSigningMethod 在当前DID文档的authentication/capabilityInvocation中查找签名使用的验证方法
+// methodID为DID URL，以#开头时按文档id解析
+func findSigningMethod(doc *Document, methodID string) (*VerificationMethod, error) {
+	if strings.HasPrefix(methodID, "#") {
+		methodID = doc.ID + methodID
+	}
+	for _, relationship := range signatureRelationships {
+		for _, method := range doc.MethodsFor(relationship) {
+			if method.ID == methodID {
+				return &method, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("verification method %s is not authorized for authentication or capabilityInvocation", methodID)
+}
+
+// verifyMethodSignature 使用验证方法的publicKeyJwk验证签名
+// 支持的密钥：Ed25519（kty=OKP, crv=Ed25519，EdDSA签名）和secp256k1（kty=EC, crv=secp256k1，ES256K签名，r||s共64字节）
+// 签名为base64url编码（可带填充）
+func verifyMethodSignature(method *VerificationMethod, message []byte, signature string) error {
+	if method.PublicKeyJwk == nil {
+		return fmt.Errorf("verification method %s has no publicKeyJwk", method.ID)
+	}
+	sig, err := decodeBase64URL(signature)
+	if err != nil {
+		return errors.New("signature must be base64url encoded")
+	}
+	kty, _ := method.PublicKeyJwk["kty"].(string)
+	crv, _ := method.PublicKeyJwk["crv"].(string)
+	switch {
+	case kty == "OKP" && crv == "Ed25519":
+		x, err := jwkCoordinate(method.PublicKeyJwk, "x", ed25519.PublicKeySize)
+		if err != nil {
+			return err
+		}
+		if len(sig) != ed25519.SignatureSize || !ed25519.Verify(ed25519.PublicKey(x), message, sig) {
+			return errors.New("invalid signature")
+		}
+	case kty == "EC" && crv == "secp256k1":
+		x, err := jwkCoordinate(method.PublicKeyJwk, "x", 32)
+		if err != nil {
+			return err
+		}
+		y, err := jwkCoordinate(method.PublicKeyJwk, "y", 32)
+		if err != nil {
+			return err
+		}
+		if !verifySecp256k1(x, y, message, sig) {
+			return errors.New("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported key type kty=%s crv=%s, only Ed25519 and secp256k1 JWK are supported", kty, crv)
+	}
+	return nil
+}
+
+// verifySecp256k1 按ES256K验证签名：消息取SHA-256摘要，签名为32字节r与32字节s的拼接
+// 公钥点及r、s的取值范围由decred secp256k1库校验，不在曲线上的点或r、s不在[1, n-1]内时验证失败
+func verifySecp256k1(x, y, message, signature []byte) bool {
+	if len(signature) != 64 {
+		return false
+	}
+	pub, err := secp256k1.ParsePubKey(append(append([]byte{0x04}, x...), y...))
+	if err != nil {
+		return false
+	}
+	var r, s secp256k1.ModNScalar
+	if r.SetByteSlice(signature[:32]) || s.SetByteSlice(signature[32:]) {
+		return false
+	}
+	digest := sha256.Sum256(message)
+	return secpecdsa.NewSignature(&r, &s).Verify(digest[:], pub)
+}
+
+// jwkCoordinate 读取JWK中base64url编码的定长成员
+func jwkCoordinate(jwk map[string]interface{}, member string, size int) ([]byte, error) {
+	value, _ := jwk[member].(string)
+	b, err := decodeBase64URL(value)
+	if err != nil || len(b) != size {
+		return nil, fmt.Errorf("publicKeyJwk.%s must be a base64url encoded %d-byte value", member, size)
+	}
+	return b, nil
+}
+
+// decodeBase64URL 解码base64url，兼容带填充的形式
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
@@ -0,0 +1,354 @@
+package did
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"sbp-did-chaincode/accesscontrol"
+	"sbp-did-chaincode/common"
+	"sbp-did-chaincode/internal/testutil"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+func TestUpdateSigningInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		channelID   string
+		projectCode string
+		nonce       int64
+		want        string
+	}{
+		{
+			name:        "project",
+			channelID:   "mychannel",
+			projectCode: "p1",
+			nonce:       3,
+			want:        "sbp-did:UpdateDidDocument\nmychannel\np1\ndid:sbp:alice\n3\n{\"id\":\"did:sbp:alice\"}",
+		},
+		{
+			name:      "default project",
+			channelID: "mychannel",
+			nonce:     1,
+			want:      "sbp-did:UpdateDidDocument\nmychannel\n\ndid:sbp:alice\n1\n{\"id\":\"did:sbp:alice\"}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UpdateSigningInput(tt.channelID, tt.projectCode, "did:sbp:alice", tt.nonce, `{"id":"did:sbp:alice"}`)
+			if string(got) != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// RFC 8032 第7.1节 Ed25519测试向量 TEST 1-3
+func TestVerifyMethodSignatureEd25519(t *testing.T) {
+	tests := []struct {
+		name    string
+		pub     string
+		msg     string
+		sig     string
+		wantErr bool
+	}{
+		{
+			name: "rfc8032 test 1",
+			pub:  "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+			msg:  "",
+			sig:  "e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b",
+		},
+		{
+			name: "rfc8032 test 2",
+			pub:  "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+			msg:  "72",
+			sig:  "92a009a9f0d4cab8720e820b5f642540a2b27b5416503f8fb3762223ebdb69da085ac1e43e15996e458f3613d0f11d8c387b2eaeb4302aeeb00d291612bb0c00",
+		},
+		{
+			name: "rfc8032 test 3",
+			pub:  "fc51cd8e6218a1a38da47ed00230f0580816ed13ba3303ac5deb911548908025",
+			msg:  "af82",
+			sig:  "6291d657deec24024827e69c3abe01a30ce548a284743a445e3680d7db5ac3ac18ff9b538d16f290ae67f760984dc6594a7c15e9716ed28dc027beceea1ec40a",
+		},
+		{
+			name:    "tampered message",
+			pub:     "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+			msg:     "73",
+			sig:     "92a009a9f0d4cab8720e820b5f642540a2b27b5416503f8fb3762223ebdb69da085ac1e43e15996e458f3613d0f11d8c387b2eaeb4302aeeb00d291612bb0c00",
+			wantErr: true,
+		},
+		{
+			name:    "truncated signature",
+			pub:     "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+			msg:     "72",
+			sig:     "92a009a9f0d4cab8720e820b5f642540a2b27b5416503f8fb3762223ebdb69da085ac1e43e15996e458f3613d0f11d8c387b2eaeb4302aeeb00d291612bb0c",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := &VerificationMethod{ID: "did:sbp:alice#key-1", PublicKeyJwk: map[string]interface{}{
+				"kty": "OKP", "crv": "Ed25519", "x": b64(mustHex(t, tt.pub)),
+			}}
+			err := verifyMethodSignature(method, mustHex(t, tt.msg), b64(mustHex(t, tt.sig)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// secp256k1 ES256K测试向量：私钥1与n-1的RFC 6979确定性签名（与bitcoin-core/python-ecdsa公开向量一致），
+// 以及OpenSSL生成的签名；无效用例覆盖r/s越界、公钥不在曲线上和消息篡改
+func TestVerifyMethodSignatureSecp256k1(t *testing.T) {
+	const (
+		pubKey1  = "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"
+		pubKeyN1 = "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798b7c52588d95c3b9aa25b0403f1eef75702e84bb7597aabe663b82f6f04ef2777"
+		pubOSSL  = "045b719b603e581c9bc9f81d6b4186a1eb173681c3a2166c16ff9bbd9236979672b78cbd5f056e2280adecfd48c2b873fc2f821a8105c6a01ed620ad55ff59ca62"
+		n        = "fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141"
+		zero     = "0000000000000000000000000000000000000000000000000000000000000000"
+		r1       = "934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d8"
+		s1       = "2442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5"
+	)
+	tests := []struct {
+		name    string
+		pub     string
+		msg     string
+		sig     string
+		wantErr bool
+	}{
+		{name: "rfc6979 key 1", pub: pubKey1, msg: "Satoshi Nakamoto", sig: r1 + s1},
+		{
+			name: "rfc6979 key 1 long message",
+			pub:  pubKey1,
+			msg:  "All those moments will be lost in time, like tears in rain. Time to die...",
+			sig:  "8600dbd41e348fe5c9465ab92d23e3db8b98b873beecd930736488696438cb6b547fe64427496db33bf66019dacbf0039c04199abb0122918601db38a72cfc21",
+		},
+		{
+			name: "rfc6979 key n-1",
+			pub:  pubKeyN1,
+			msg:  "Satoshi Nakamoto",
+			sig:  "fd567d121db66e382991534ada77a6bd3106f0a1098c231e47993447cd6af2d06b39cd0eb1bc8603e159ef5c20a5c8ad685a45b06ce9bebed3f153d10d93bed5",
+		},
+		{
+			name: "openssl",
+			pub:  pubOSSL,
+			msg:  "hello world message",
+			sig:  "e27c53f0766c6c77849de9997137a9df9a30eb9136d386fc44ee7284b8bcd3bb6276ba328daa0757c730d4f134d101414eac7819ab83935f7d594ecec31a51c3",
+		},
+		{name: "tampered message", pub: pubKey1, msg: "Satoshi Nakamoto!", sig: r1 + s1, wantErr: true},
+		{name: "wrong key", pub: pubKeyN1, msg: "Satoshi Nakamoto", sig: r1 + s1, wantErr: true},
+		{name: "r is zero", pub: pubKey1, msg: "Satoshi Nakamoto", sig: zero + s1, wantErr: true},
+		{name: "s is zero", pub: pubKey1, msg: "Satoshi Nakamoto", sig: r1 + zero, wantErr: true},
+		{name: "r equals n", pub: pubKey1, msg: "Satoshi Nakamoto", sig: n + s1, wantErr: true},
+		{name: "s equals n", pub: pubKey1, msg: "Satoshi Nakamoto", sig: r1 + n, wantErr: true},
+		{name: "s above n", pub: pubKey1, msg: "Satoshi Nakamoto", sig: r1 + n[:62] + "42", wantErr: true},
+		{name: "short signature", pub: pubKey1, msg: "Satoshi Nakamoto", sig: r1 + s1[:62], wantErr: true},
+		{
+			name:    "point not on curve",
+			pub:     pubKey1[:len(pubKey1)-2] + "b9",
+			msg:     "Satoshi Nakamoto",
+			sig:     r1 + s1,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub := mustHex(t, tt.pub)
+			method := &VerificationMethod{ID: "did:sbp:alice#key-2", PublicKeyJwk: map[string]interface{}{
+				"kty": "EC", "crv": "secp256k1", "x": b64(pub[1:33]), "y": b64(pub[33:]),
+			}}
+			err := verifyMethodSignature(method, []byte(tt.msg), b64(mustHex(t, tt.sig)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyMethodSignatureUnsupportedKey(t *testing.T) {
+	method := &VerificationMethod{ID: "did:sbp:alice#key-1", PublicKeyJwk: map[string]interface{}{
+		"kty": "EC", "crv": "P-256", "x": b64(make([]byte, 32)), "y": b64(make([]byte, 32)),
+	}}
+	err := verifyMethodSignature(method, []byte("message"), b64(make([]byte, 64)))
+	if err == nil || !strings.Contains(err.Error(), "unsupported key type") {
+		t.Fatalf("got err %v, want unsupported key type", err)
+	}
+}
+
+// signatureTestKeys 签名更新测试使用的密钥：key-1（Ed25519，authentication）、key-2（secp256k1，capabilityInvocation）、
+// key-3（Ed25519，仅assertionMethod，不能授权更新）
+type signatureTestKeys struct {
+	ed25519   ed25519.PrivateKey
+	secp256k1 *secp256k1.PrivateKey
+	assertion ed25519.PrivateKey
+}
+
+func newSignatureTestKeys(t *testing.T) *signatureTestKeys {
+	t.Helper()
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, assertionKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secpKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &signatureTestKeys{ed25519: edKey, secp256k1: secpKey, assertion: assertionKey}
+}
+
+// document 生成包含三个密钥的DID文档，service用于区分不同版本
+func (k *signatureTestKeys) document(t *testing.T, did, service string) string {
+	t.Helper()
+	secpPub := k.secp256k1.PubKey().SerializeUncompressed()
+	method := func(id string, jwk map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"id": id, "type": "JsonWebKey2020", "controller": did, "publicKeyJwk": jwk}
+	}
+	doc := map[string]interface{}{
+		"@context": []string{didContextV1},
+		"id":       did,
+		"verificationMethod": []interface{}{
+			method("#key-1", map[string]interface{}{"kty": "OKP", "crv": "Ed25519", "x": b64(k.ed25519.Public().(ed25519.PublicKey))}),
+			method("#key-2", map[string]interface{}{"kty": "EC", "crv": "secp256k1", "x": b64(secpPub[1:33]), "y": b64(secpPub[33:])}),
+			method("#key-3", map[string]interface{}{"kty": "OKP", "crv": "Ed25519", "x": b64(k.assertion.Public().(ed25519.PublicKey))}),
+		},
+		"authentication":       []string{"#key-1"},
+		"capabilityInvocation": []string{"#key-2"},
+		"assertionMethod":      []string{"#key-3"},
+		"service": []interface{}{
+			map[string]interface{}{"id": did + "#svc", "type": "LinkedDomains", "serviceEndpoint": service},
+		},
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// sign 使用验证方法对应的私钥对待签名消息签名，返回base64url编码的签名
+func (k *signatureTestKeys) sign(methodID string, message []byte) string {
+	switch methodID {
+	case "#key-1":
+		return b64(ed25519.Sign(k.ed25519, message))
+	case "#key-2":
+		digest := sha256.Sum256(message)
+		sig := secpecdsa.Sign(k.secp256k1, digest[:])
+		r, s := sig.R(), sig.S()
+		rb, sb := r.Bytes(), s.Bytes()
+		return b64(append(rb[:], sb[:]...))
+	default:
+		return b64(ed25519.Sign(k.assertion, message))
+	}
+}
+
+func TestUpdateDidDocumentWithSignature(t *testing.T) {
+	const did = "did:sbp:alice"
+	const project = "p1"
+	stub, _ := testutil.NewMockStub(t, nil, "Org1MSP")
+	keys := newSignatureTestKeys(t)
+	cc := new(DIDChaincode)
+	at := time.Unix(1700000000, 0)
+
+	// newCtx 开始一个新交易，projectCode非空时在该项目下执行
+	txCount := 0
+	newCtx := func(projectCode string) *accesscontrol.TransactionContext {
+		txCount++
+		testutil.StartTx(stub, "tx"+strconv.Itoa(txCount), at.Add(time.Duration(txCount)*time.Minute))
+		ctx := accesscontrol.NewTransactionContext(&testutil.FakeChecker{})
+		if projectCode == "" {
+			ctx.SetStub(stub)
+		} else {
+			ctx.SetStub(common.NewProjectStub(stub, projectCode))
+		}
+		return ctx
+	}
+
+	// 同一DID以相同密钥分别注册在默认项目和项目p1中，用于验证签名不能跨项目重放
+	for _, projectCode := range []string{"", project} {
+		if err := cc.RegisterDid(newCtx(projectCode), did, keys.document(t, did, "https://example.com/v1")); err != nil {
+			t.Fatalf("RegisterDid in project %q: %v", projectCode, err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		nonce       int64
+		signChannel string // 签名绑定的通道，为空时使用存根的通道
+		signProject string // 签名绑定的项目
+		project     string // 提交交易的项目
+		wantErr     string
+		wantNonce   int64
+	}{
+		{name: "ed25519 authentication key", method: "#key-1", nonce: 1, wantNonce: 1},
+		{name: "replayed nonce", method: "#key-1", nonce: 1, wantErr: "invalid nonce 1, expected 2"},
+		{name: "skipped nonce", method: "#key-1", nonce: 3, wantErr: "invalid nonce 3, expected 2"},
+		{name: "secp256k1 capabilityInvocation key", method: "#key-2", nonce: 2, wantNonce: 2},
+		{name: "assertionMethod key is not authorized", method: "#key-3", nonce: 3, wantErr: "is not authorized"},
+		{name: "signed for another channel", method: "#key-1", nonce: 3, signChannel: "otherchannel", wantErr: "invalid signature"},
+		// 为默认项目签名的更新不能在项目p1中重放，即使两个项目中的DID使用相同的密钥和nonce
+		{name: "signed for another project", method: "#key-1", nonce: 1, project: project, wantErr: "invalid signature"},
+		{name: "signed for the project", method: "#key-2", nonce: 1, signProject: project, project: project, wantNonce: 1},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document := keys.document(t, did, "https://example.com/v"+strconv.Itoa(i+2))
+			channel := tt.signChannel
+			if channel == "" {
+				channel = stub.GetChannelID()
+			}
+			signature := keys.sign(tt.method, UpdateSigningInput(channel, tt.signProject, did, tt.nonce, document))
+
+			ctx := newCtx(tt.project)
+			before, _ := getDidInfo(ctx, did)
+			err := cc.UpdateDidDocumentWithSignature(ctx, did, document, tt.method, strconv.FormatInt(tt.nonce, 10), signature)
+			after, _ := getDidInfo(ctx, did)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got err %v, want %q", err, tt.wantErr)
+				}
+				if after.DidDocument != before.DidDocument || after.Nonce != before.Nonce {
+					t.Fatalf("rejected update must not change the did")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateDidDocumentWithSignature: %v", err)
+			}
+			if after.DidDocument != document || after.Nonce != tt.wantNonce || after.VersionId != before.VersionId+1 {
+				t.Fatalf("got document updated %t, nonce %d, version %d", after.DidDocument == document, after.Nonce, after.VersionId)
+			}
+			nonce, err := cc.GetDidNonce(ctx, did)
+			if err != nil || nonce != tt.wantNonce {
+				t.Fatalf("GetDidNonce got %d, %v, want %d", nonce, err, tt.wantNonce)
+			}
+		})
+	}
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,139 @@
+package testutil
+
+import (
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// LedgerStub 在MockStub上补充shimtest.MockStub未实现的历史查询和分页查询
+// 每次写入/删除都记录为一次修改，GetHistoryForKey按从新到旧的顺序返回；
+// 分页查询返回的bookmark为下一页第一条记录的键，没有下一页时为空
+type LedgerStub struct {
+	*shimtest.MockStub
+	history map[string][]*queryresult.KeyModification
+}
+
+// NewLedgerStub 包装模拟存根，交易仍通过StartTx在被包装的MockStub上开始
+func NewLedgerStub(stub *shimtest.MockStub) *LedgerStub {
+	return &LedgerStub{MockStub: stub, history: make(map[string][]*queryresult.KeyModification)}
+}
+
+func (s *LedgerStub) PutState(key string, value []byte) error {
+	if err := s.MockStub.PutState(key, value); err != nil {
+		return err
+	}
+	// MockStub写入空值时删除键
+	return s.record(key, value, len(value) == 0)
+}
+
+func (s *LedgerStub) DelState(key string) error {
+	if err := s.MockStub.DelState(key); err != nil {
+		return err
+	}
+	return s.record(key, nil, true)
+}
+
+func (s *LedgerStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &historyIterator{modifications: s.history[key]}, nil
+}
+
+func (s *LedgerStub) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	// MockStub只在起止键都为空时做无界查询，结束键为空时按Fabric的语义查询到末尾
+	if endKey == "" {
+		endKey = string(utf8.MaxRune)
+	}
+	if bookmark != "" {
+		startKey = bookmark
+	}
+	iterator, err := s.MockStub.GetStateByRange(startKey, endKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return page(iterator, pageSize)
+}
+
+func (s *LedgerStub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	startKey, err := s.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	endKey := startKey + string(utf8.MaxRune)
+	if bookmark != "" {
+		startKey = bookmark
+	}
+	iterator := shimtest.NewMockStateRangeQueryIterator(s.MockStub, startKey, endKey)
+	return page(iterator, pageSize)
+}
+
+// record 记录当前交易对key的一次修改
+func (s *LedgerStub) record(key string, value []byte, isDelete bool) error {
+	timestamp, err := s.GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	modification := &queryresult.KeyModification{TxId: s.GetTxID(), Value: value, Timestamp: timestamp, IsDelete: isDelete}
+	s.history[key] = append([]*queryresult.KeyModification{modification}, s.history[key]...)
+	return nil
+}
+
+// page 从iterator中读取一页记录，多读一条用于确定下一页的bookmark
+func page(iterator shim.StateQueryIteratorInterface, pageSize int32) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	defer iterator.Close()
+	kvs := make([]*queryresult.KV, 0)
+	bookmark := ""
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if pageSize > 0 && int32(len(kvs)) == pageSize {
+			bookmark = kv.Key
+			break
+		}
+		kvs = append(kvs, kv)
+	}
+	metadata := &pb.QueryResponseMetadata{FetchedRecordsCount: int32(len(kvs)), Bookmark: bookmark}
+	return &stateIterator{kvs: kvs}, metadata, nil
+}
+
+// stateIterator 模拟的状态查询迭代器
+type stateIterator struct {
+	kvs []*queryresult.KV
+}
+
+func (it *stateIterator) HasNext() bool {
+	return len(it.kvs) > 0
+}
+
+func (it *stateIterator) Next() (*queryresult.KV, error) {
+	kv := it.kvs[0]
+	it.kvs = it.kvs[1:]
+	return kv, nil
+}
+
+func (it *stateIterator) Close() error {
+	return nil
+}
+
+// historyIterator 模拟的历史查询迭代器
+type historyIterator struct {
+	modifications []*queryresult.KeyModification
+}
+
+func (it *historyIterator) HasNext() bool {
+	return len(it.modifications) > 0
+}
+
+func (it *historyIterator) Next() (*queryresult.KeyModification, error) {
+	modification := it.modifications[0]
+	it.modifications = it.modifications[1:]
+	return modification, nil
+}
+
+func (it *historyIterator) Close() error {
+	return nil
+}
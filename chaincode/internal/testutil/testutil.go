@@ -0,0 +1,171 @@
+// Package testutil 单元测试使用的模拟交易环境：带调用者证书的MockStub、可注入的伪造权限检查器
+package testutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"sbp-did-chaincode/common"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// NewMockStub 创建模拟存根，调用者为mspID下新生成的证书，返回存根及调用者账户标识（{mspId}:{ski}）
+// cc为nil时只能直接调用合约方法，不能使用MockInvoke
+func NewMockStub(t testing.TB, cc shim.Chaincode, mspID string) (*shimtest.MockStub, string) {
+	t.Helper()
+	stub := shimtest.NewMockStub("sbp-did", cc)
+	stub.ChannelID = "mychannel"
+	return stub, SetCaller(t, stub, mspID)
+}
+
+// attrsOID Fabric CA在证书中存放属性的扩展OID，扩展值为 {"attrs":{...}} 格式的JSON
+var attrsOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+// SetCaller 将模拟存根的调用者替换为mspID下新生成的证书，返回调用者账户标识
+func SetCaller(t testing.TB, stub *shimtest.MockStub, mspID string) string {
+	t.Helper()
+	return SetCallerWithAttributes(t, stub, mspID, nil)
+}
+
+// SetCallerWithAttributes 与SetCaller相同，证书中包含attrs指定的Fabric CA属性
+func SetCallerWithAttributes(t testing.TB, stub *shimtest.MockStub, mspID string, attrs map[string]string) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ski := make([]byte, 20)
+	if _, err := rand.Read(ski); err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "user", Organization: []string{mspID}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		SubjectKeyId: ski,
+	}
+	if len(attrs) > 0 {
+		value, err := json.Marshal(map[string]interface{}{"attrs": attrs})
+		if err != nil {
+			t.Fatal(err)
+		}
+		template.ExtraExtensions = []pkix.Extension{{Id: attrsOID, Value: value}}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creator, err := proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   mspID,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub.Creator = creator
+	return common.FormatAccount(mspID, hex.EncodeToString(ski))
+}
+
+// StartTx 在模拟存根上开始一个交易，交易时间戳为at，用于不经过MockInvoke直接调用合约方法
+func StartTx(stub *shimtest.MockStub, txID string, at time.Time) {
+	stub.MockTransactionStart(txID)
+	stub.TxTimestamp = timestamppb.New(at)
+}
+
+// FakeChecker 伪造的权限检查器，记录每次调用，按Denied拒绝函数选择器，按Config.Admins判断管理员
+type FakeChecker struct {
+	Config common.ProjectConfig // GetProjectConfig返回的项目配置
+	Denied map[string]bool      // 被拒绝的函数选择器
+	Calls  []string             // 调用记录，格式：{方法名}:{参数}
+}
+
+var _ common.PermissionChecker = (*FakeChecker)(nil)
+
+// CheckWriteFuncSelectorPermission 函数选择器在Denied中时返回false
+func (f *FakeChecker) CheckWriteFuncSelectorPermission(ctx contractapi.TransactionContextInterface, account, funcName string) (bool, error) {
+	f.Calls = append(f.Calls, "CheckWriteFuncSelectorPermission:"+funcName)
+	return !f.Denied[funcName], nil
+}
+
+// CheckQueryFuncSelectorPermission 函数选择器在Denied中时返回false
+func (f *FakeChecker) CheckQueryFuncSelectorPermission(ctx contractapi.TransactionContextInterface, account, funcName string) (bool, error) {
+	f.Calls = append(f.Calls, "CheckQueryFuncSelectorPermission:"+funcName)
+	return !f.Denied[funcName], nil
+}
+
+// ConsumeWriteQuota 只记录调用
+func (f *FakeChecker) ConsumeWriteQuota(ctx contractapi.TransactionContextInterface, funcName string) error {
+	f.Calls = append(f.Calls, "ConsumeWriteQuota:"+funcName)
+	return nil
+}
+
+// CheckMethod 只校验DID语法
+func (f *FakeChecker) CheckMethod(ctx contractapi.TransactionContextInterface, did string) error {
+	f.Calls = append(f.Calls, "CheckMethod:"+did)
+	_, _, err := common.ParseDID(did)
+	return err
+}
+
+// CheckIssuerVerificationEnabled 始终通过
+func (f *FakeChecker) CheckIssuerVerificationEnabled(ctx contractapi.TransactionContextInterface, account string) (bool, error) {
+	f.Calls = append(f.Calls, "CheckIssuerVerificationEnabled:"+account)
+	return true, nil
+}
+
+// CheckVCTemplateVerificationEnabled 始终通过
+func (f *FakeChecker) CheckVCTemplateVerificationEnabled(ctx contractapi.TransactionContextInterface, account string) (bool, error) {
+	f.Calls = append(f.Calls, "CheckVCTemplateVerificationEnabled:"+account)
+	return true, nil
+}
+
+// CheckNotPaused 始终通过
+func (f *FakeChecker) CheckNotPaused(ctx contractapi.TransactionContextInterface, module, direction string) error {
+	f.Calls = append(f.Calls, "CheckNotPaused:"+module+":"+direction)
+	return nil
+}
+
+// CheckAdminRole 账户在Config.Admins中时通过
+func (f *FakeChecker) CheckAdminRole(ctx contractapi.TransactionContextInterface, account string) error {
+	f.Calls = append(f.Calls, "CheckAdminRole:"+account)
+	for _, admin := range f.Config.Admins {
+		if common.MatchAccount(admin, account) {
+			return nil
+		}
+	}
+	return errors.New("account is not admin")
+}
+
+// GetProjectConfig 返回Config的副本
+func (f *FakeChecker) GetProjectConfig(ctx contractapi.TransactionContextInterface) (*common.ProjectConfig, error) {
+	f.Calls = append(f.Calls, "GetProjectConfig")
+	cfg := f.Config
+	return &cfg, nil
+}
+
+// Called 判断是否有指定的调用记录
+func (f *FakeChecker) Called(call string) bool {
+	for _, c := range f.Calls {
+		if c == call {
+			return true
+		}
+	}
+	return false
+}
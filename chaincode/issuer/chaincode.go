@@ -49,7 +49,6 @@ type VcTemplateInfoMataDate struct {
 // IssuerChaincode 结构体
 type IssuerChaincode struct {
 	contractapi.Contract
-	common.PermissionChecker
 }
 
 const (
@@ -61,128 +60,25 @@ const (
 	vcTemplateInfoPrefix = "issuer:template:"
 )
 
-// ================== 内部合约调用辅助方法 ==================
-//
-//// getProjectConfig 获取项目配置信息
-//func (c *IssuerChaincode) getProjectConfig(ctx contractapi.TransactionContextInterface) (*common.ProjectConfig, error) {
-//	permissionChecker := common.GetGlobalPermissionChecker()
-//	if permissionChecker == nil {
-//		return nil, fmt.Errorf("global permission checker not initialized")
-//	}
-//
-//	return permissionChecker.GetProjectConfig(ctx)
-//}
-//
-//// checkWriteFuncSelectorPermission 调用Permission合约的权限检查方法
-//func (c *IssuerChaincode) checkWriteFuncSelectorPermission(ctx contractapi.TransactionContextInterface, caller, funcName string) (bool, error) {
-//	//// 获取调用者账户
-//	//caller := common.GetCaller(ctx)
-//
-//	// 通过全局权限检查器调用Permission合约的方法
-//	permissionChecker := common.GetGlobalPermissionChecker()
-//	if permissionChecker == nil {
-//		return false, fmt.Errorf("global permission checker not initialized")
-//	}
-//
-//	return permissionChecker.CheckWriteFuncSelectorPermission(ctx, caller, funcName)
-//}
-//
-//// CheckQueryFuncSelectorPermission 调用Permission合约的查询权限检查
-//func (c *IssuerChaincode) checkQueryFuncSelectorPermission(ctx contractapi.TransactionContextInterface, caller, funcName string) (bool, error) {
-//	//// 获取调用者账户
-//	//caller := common.GetCaller(ctx)
-//
-//	// 通过全局权限检查器调用Permission合约的方法
-//	permissionChecker := common.GetGlobalPermissionChecker()
-//	if permissionChecker == nil {
-//		return false, fmt.Errorf("global permission checker not initialized")
-//	}
-//
-//	return permissionChecker.CheckQueryFuncSelectorPermission(ctx, caller, funcName)
-//}
-//
-//// checkIssuerVerificationEnabled 检查发证方审核状态是否启用
-//// 返回值说明：
-//// - true: 账户可以进行颁发者（注册/更新等操作）
-//// - false: 账户不可以进行颁发者（注册/更新等操作）
-//// - error: 检查过程中发生错误
-//func (c *IssuerChaincode) checkIssuerVerificationEnabled(ctx contractapi.TransactionContextInterface, caller string) (bool, error) {
-//	// 通过全局权限检查器调用Permission合约的方法
-//	permissionChecker := common.GetGlobalPermissionChecker()
-//	if permissionChecker == nil {
-//		return false, fmt.Errorf("permission checker not initialized, please check system configuration")
-//	}
-//
-//	// 调用Permission合约检查发证方审核状态
-//	isEnabled, err := permissionChecker.CheckIssuerVerificationEnabled(ctx, caller)
-//	if err != nil {
-//		// 提供更详细的错误信息
-//		return false, fmt.Errorf("failed to check issuer verification status: %v", err)
-//	}
-//
-//	return isEnabled, nil
-//}
-//
-//func (c *IssuerChaincode) CheckMethod(ctx contractapi.TransactionContextInterface, issuerDid string) error {
-//	// 通过全局权限检查器调用Permission合约的方法
-//	permissionChecker := common.GetGlobalPermissionChecker()
-//	if permissionChecker == nil {
-//		return fmt.Errorf("permission checker not initialized, cannot verify DID method")
-//	}
-//
-//	// 验证DID的method部分是否符合项目配置
-//	err := permissionChecker.CheckMethod(ctx, issuerDid)
-//	if err != nil {
-//		return err
-//	}
-//	return nil
-//}
-
-func (c *IssuerChaincode) CheckDid(ctx contractapi.TransactionContextInterface, didId string) (bool, error) {
+// ================== 主要业务方法 ==================
+// 调用者权限、审核开关、项目/模块停用状态及DID method校验由BeforeTransaction按策略表统一完成
+
+// CheckDid 校验DID是否存在
+func (c *IssuerChaincode) CheckDid(ctx common.TransactionContextInterface, didId string) (bool, error) {
 	return new(did.DIDChaincode).CheckDid(ctx, didId)
 }
 
 // RegisterIssuer 注册发证方
-func (c *IssuerChaincode) RegisterIssuer(ctx contractapi.TransactionContextInterface, issuerDid, name string) error {
+func (c *IssuerChaincode) RegisterIssuer(ctx common.TransactionContextInterface, issuerDid, name string) error {
 	log.Printf("开始注册发证方 - 发证方DID: %s, 名称: %s", issuerDid, name)
 	if strings.TrimSpace(issuerDid) == "" || strings.TrimSpace(name) == "" {
 		log.Printf("参数校验失败 - 发证方DID或名称为空")
 		return errors.New("issuerDid and name cannot be empty")
 	}
 	// 获取调用者账户
-	caller := common.GetCaller(ctx)
+	caller := ctx.GetCaller()
 	log.Printf("发证方注册 - 调用者: %s", caller)
 
-	// 检查发证方审核状态
-	// 如果审核已启用，只有管理员可以注册发证方
-	// 如果审核未启用，普通用户也可以注册发证方
-	issuerVerificationEnabled, err := c.CheckIssuerVerificationEnabled(ctx, caller)
-	if err != nil || !issuerVerificationEnabled {
-		log.Printf("发证方审核状态检查失败: %v", err)
-		return fmt.Errorf("failed to check issuer verification status: %v", err)
-	}
-	log.Printf("发证方审核状态检查通过 - 审核已启用: %t", issuerVerificationEnabled)
-
-	// 检查写权限
-	hasPermission, err := c.CheckWriteFuncSelectorPermission(ctx, caller, "RegisterIssuer")
-	if err != nil {
-		log.Printf("写权限检查失败: %v", err)
-		return fmt.Errorf("failed to check write permission: %v", err)
-	}
-	if !hasPermission {
-		log.Printf("权限校验失败 - 调用者: %s, 操作: RegisterIssuer", caller)
-		return errors.New("no permission to register issuer")
-	}
-	log.Printf("权限校验通过 - 调用者: %s, 操作: RegisterIssuer", caller)
-
-	// 4. 调用PermissionChaincode合约checkMethod方法，验证DID的method部分
-	err = c.CheckMethod(ctx, issuerDid)
-	if err != nil {
-		log.Printf("DID方法校验失败: %v", err)
-		return fmt.Errorf("DID method validation failed: %v", err)
-	}
-	log.Printf("DID方法校验通过 - 发证方DID: %s", issuerDid)
-
 	// 5. 调用DIDChaincode合约的CheckDid方法，验证DID是否已存在
 	existDid, err := c.CheckDid(ctx, issuerDid)
 	if err != nil {
@@ -241,7 +137,7 @@ func (c *IssuerChaincode) RegisterIssuer(ctx contractapi.TransactionContextInter
 	log.Printf("发证方信息存储成功 - 发证方DID: %s, 名称: %s", issuerDid, name)
 
 	// 获取项目配置信息，用于事件通知
-	cfg, err := c.GetProjectConfig(ctx)
+	cfg, err := ctx.GetProjectConfig()
 	if err != nil {
 		log.Printf("获取项目配置失败: %v", err)
 		// 如果获取配置失败，仍然发送事件，但不包含项目信息
@@ -263,38 +159,16 @@ func (c *IssuerChaincode) RegisterIssuer(ctx contractapi.TransactionContextInter
 }
 
 // UpdateIssuer 更新发证方
-func (c *IssuerChaincode) UpdateIssuer(ctx contractapi.TransactionContextInterface, issuerDid, name string) error {
+func (c *IssuerChaincode) UpdateIssuer(ctx common.TransactionContextInterface, issuerDid, name string) error {
 	log.Printf("开始更新发证方 - 发证方DID: %s, 新名称: %s", issuerDid, name)
 	if strings.TrimSpace(issuerDid) == "" || strings.TrimSpace(name) == "" {
 		log.Printf("参数校验失败 - 发证方DID或名称为空")
 		return errors.New("issuerDid and name cannot be empty")
 	}
 	// 获取调用者账户
-	caller := common.GetCaller(ctx)
+	caller := ctx.GetCaller()
 	log.Printf("发证方更新 - 调用者: %s", caller)
 
-	// 检查发证方审核状态
-	// 如果审核已启用，只有管理员可以注册发证方
-	// 如果审核未启用，普通用户也可以注册发证方
-	issuerVerificationEnabled, err := c.CheckIssuerVerificationEnabled(ctx, caller)
-	if err != nil || !issuerVerificationEnabled {
-		log.Printf("发证方审核状态检查失败: %v", err)
-		return fmt.Errorf("failed to check issuer verification status: %v", err)
-	}
-	log.Printf("发证方审核状态检查通过 - 审核已启用: %t", issuerVerificationEnabled)
-
-	// 2. 检查写权限
-	hasPermission, err := c.CheckWriteFuncSelectorPermission(ctx, caller, "UpdateIssuer")
-	if err != nil {
-		log.Printf("写权限检查失败: %v", err)
-		return fmt.Errorf("failed to check write permission: %v", err)
-	}
-	if !hasPermission {
-		log.Printf("权限校验失败 - 调用者: %s, 操作: UpdateIssuer", caller)
-		return errors.New("no permission to update issuer")
-	}
-	log.Printf("权限校验通过 - 调用者: %s, 操作: UpdateIssuer", caller)
-
 	// 1. 校验发证方信息是否存在
 	key := issuerInfoPrefix + issuerDid
 	b, err := ctx.GetStub().GetState(key)
@@ -343,7 +217,7 @@ func (c *IssuerChaincode) UpdateIssuer(ctx contractapi.TransactionContextInterfa
 	log.Printf("发证方信息更新成功 - 发证方DID: %s, 新名称: %s", issuerDid, name)
 
 	// 6. 获取项目配置信息，用于事件通知
-	cfg, err := c.GetProjectConfig(ctx)
+	cfg, err := ctx.GetProjectConfig()
 	if err != nil {
 		log.Printf("获取项目配置失败: %v", err)
 		// 如果获取配置失败，仍然发送事件，但不包含项目信息
@@ -365,38 +239,16 @@ func (c *IssuerChaincode) UpdateIssuer(ctx contractapi.TransactionContextInterfa
 }
 
 // ChangeIssuerStatus 启停发证方
-func (c *IssuerChaincode) ChangeIssuerStatus(ctx contractapi.TransactionContextInterface, issuerDid string, isDisabled bool) error {
+func (c *IssuerChaincode) ChangeIssuerStatus(ctx common.TransactionContextInterface, issuerDid string, isDisabled bool) error {
 	log.Printf("开始变更发证方状态 - 发证方DID: %s, 新状态: %t", issuerDid, isDisabled)
 	if strings.TrimSpace(issuerDid) == "" {
 		log.Printf("参数校验失败 - 发证方DID为空")
 		return errors.New("issuerDid cannot be empty")
 	}
 	// 获取调用者账户
-	caller := common.GetCaller(ctx)
+	caller := ctx.GetCaller()
 	log.Printf("发证方状态变更 - 调用者: %s", caller)
 
-	// 检查发证方审核状态
-	// 如果审核已启用，只有管理员可以注册发证方
-	// 如果审核未启用，普通用户也可以注册发证方
-	issuerVerificationEnabled, err := c.CheckIssuerVerificationEnabled(ctx, caller)
-	if err != nil || !issuerVerificationEnabled {
-		log.Printf("发证方审核状态检查失败: %v", err)
-		return fmt.Errorf("failed to check issuer verification status: %v", err)
-	}
-	log.Printf("发证方审核状态检查通过 - 审核已启用: %t", issuerVerificationEnabled)
-
-	// 2. 检查写权限
-	hasPermission, err := c.CheckWriteFuncSelectorPermission(ctx, caller, "ChangeIssuerStatus")
-	if err != nil {
-		log.Printf("写权限检查失败: %v", err)
-		return fmt.Errorf("failed to check write permission: %v", err)
-	}
-	if !hasPermission {
-		log.Printf("权限校验失败 - 调用者: %s, 操作: ChangeIssuerStatus", caller)
-		return errors.New("no permission to change issuer status")
-	}
-	log.Printf("权限校验通过 - 调用者: %s, 操作: ChangeIssuerStatus", caller)
-
 	// 1. 校验发证方信息是否存在
 	key := issuerInfoPrefix + issuerDid
 	b, err := ctx.GetStub().GetState(key)
@@ -425,7 +277,7 @@ func (c *IssuerChaincode) ChangeIssuerStatus(ctx contractapi.TransactionContextI
 	log.Printf("发证方状态更新成功 - 发证方DID: %s, 新状态: %t", issuerDid, isDisabled)
 
 	// 4. 获取项目配置信息，用于事件通知
-	cfg, err := c.GetProjectConfig(ctx)
+	cfg, err := ctx.GetProjectConfig()
 	if err != nil {
 		log.Printf("获取项目配置失败: %v", err)
 		// 如果获取配置失败，仍然发送事件，但不包含项目信息
@@ -446,28 +298,10 @@ func (c *IssuerChaincode) ChangeIssuerStatus(ctx contractapi.TransactionContextI
 }
 
 // GetIssuerInfo 查询发证方信息
-func (c *IssuerChaincode) GetIssuerInfo(ctx contractapi.TransactionContextInterface, issuerDid string) (info IssuerInfo, err error) {
+func (c *IssuerChaincode) GetIssuerInfo(ctx common.TransactionContextInterface, issuerDid string) (info IssuerInfo, err error) {
 	if strings.TrimSpace(issuerDid) == "" {
 		return info, errors.New("issuerDid cannot be empty")
 	}
-	// 获取调用者账户
-	caller := common.GetCaller(ctx)
-	// 检查发证方审核状态
-	// 如果审核已启用，只有管理员可以注册发证方
-	// 如果审核未启用，普通用户也可以注册发证方
-	issuerVerificationEnabled, err := c.CheckIssuerVerificationEnabled(ctx, caller)
-	if err != nil || !issuerVerificationEnabled {
-		return info, fmt.Errorf("failed to check issuer verification status: %v", err)
-	}
-
-	// 2. 检查读权限
-	hasPermission, err := c.CheckQueryFuncSelectorPermission(ctx, caller, "GetIssuerInfo")
-	if err != nil {
-		return info, fmt.Errorf("failed to check query permission: %v", err)
-	}
-	if !hasPermission {
-		return info, errors.New("no permission to get issuer info")
-	}
 	key := issuerInfoPrefix + issuerDid
 	b, err := ctx.GetStub().GetState(key)
 	if err != nil || b == nil {
@@ -479,7 +313,7 @@ func (c *IssuerChaincode) GetIssuerInfo(ctx contractapi.TransactionContextInterf
 }
 
 // CheckIssuer 校验发证方是否存在,且状态正常
-func (c *IssuerChaincode) getIssuer(ctx contractapi.TransactionContextInterface, issuerDid string) (info IssuerInfo, err error) {
+func (c *IssuerChaincode) getIssuer(ctx common.TransactionContextInterface, issuerDid string) (info IssuerInfo, err error) {
 	if strings.TrimSpace(issuerDid) == "" {
 		return info, errors.New("issuerDid cannot be empty")
 	}
@@ -493,8 +327,8 @@ func (c *IssuerChaincode) getIssuer(ctx contractapi.TransactionContextInterface,
 	return info, err
 }
 
-// CheckIssuer 校验发证方是否存在,且状态正常
-func (c *IssuerChaincode) CheckIssuer(ctx contractapi.TransactionContextInterface, issuerDid string) error {
+// CheckIssuer 校验发证方是否存在,且状态正常（发证方DID已注销时视为停用）
+func (c *IssuerChaincode) CheckIssuer(ctx common.TransactionContextInterface, issuerDid string) error {
 	//key := issuerInfoPrefix + issuerDid
 	//b, err := ctx.GetStub().GetState(key)
 	//if err != nil || b == nil {
@@ -510,35 +344,31 @@ func (c *IssuerChaincode) CheckIssuer(ctx contractapi.TransactionContextInterfac
 	if info.IsDisabled == true {
 		return errors.New("issuer is disabled")
 	}
+	// 发证方DID已注销时视为已停用
+	deactivated, err := did.IsDidDeactivated(ctx, issuerDid)
+	if err != nil {
+		return err
+	}
+	if deactivated {
+		return errors.New("issuer is disabled: issuer did is deactivated")
+	}
 	return nil
 }
 
 // RegisterVCTemplate 注册VC模板
-func (c *IssuerChaincode) RegisterVCTemplate(ctx contractapi.TransactionContextInterface, vcTemplateId, vcTemplateData, issuerDid string) error {
+func (c *IssuerChaincode) RegisterVCTemplate(ctx common.TransactionContextInterface, vcTemplateId, vcTemplateData, issuerDid string) error {
 	if strings.TrimSpace(vcTemplateId) == "" || strings.TrimSpace(vcTemplateData) == "" || strings.TrimSpace(issuerDid) == "" {
 		return errors.New("vcTemplateId and vcTemplateData and issuerDid cannot be empty")
 	}
 	// 获取调用者账户
-	caller := common.GetCaller(ctx)
+	caller := ctx.GetCaller()
 	// 4. 获取项目配置信息，用于事件通知
-	cfg, err := c.GetProjectConfig(ctx)
+	cfg, err := ctx.GetProjectConfig()
 	if err != nil {
 		// 如果获取配置失败，仍然发送事件，但不包含项目信息
 		return err
 	}
-	vcTemplateVerificationEnabled, err := c.CheckVCTemplateVerificationEnabled(ctx, caller)
-	if err != nil || !vcTemplateVerificationEnabled {
-		return fmt.Errorf("failed to check vc template verification status: %v", err)
-	}
 
-	// 2. 检查写权限
-	hasPermission, err := c.CheckWriteFuncSelectorPermission(ctx, caller, "RegisterVCTemplate")
-	if err != nil {
-		return fmt.Errorf("failed to check write permission: %v", err)
-	}
-	if !hasPermission {
-		return errors.New("no permission to register vc template")
-	}
 	// 检查当前issuerDid是否已注册为颁发者
 	err = c.CheckIssuer(ctx, issuerDid)
 	if err != nil {
@@ -597,29 +427,17 @@ func (c *IssuerChaincode) RegisterVCTemplate(ctx contractapi.TransactionContextI
 }
 
 // UpdateVCTemplate 更新VC模板
-func (c *IssuerChaincode) UpdateVCTemplate(ctx contractapi.TransactionContextInterface, vcTemplateId, vcTemplateData string) error {
+func (c *IssuerChaincode) UpdateVCTemplate(ctx common.TransactionContextInterface, vcTemplateId, vcTemplateData string) error {
 	if strings.TrimSpace(vcTemplateId) == "" || strings.TrimSpace(vcTemplateData) == "" {
 		return errors.New("vcTemplateId and vcTemplateData cannot be empty")
 	}
 	// 获取调用者账户
-	caller := common.GetCaller(ctx)
+	caller := ctx.GetCaller()
 	// 4. 获取项目配置信息，用于事件通知
-	cfg, err := c.GetProjectConfig(ctx)
+	cfg, err := ctx.GetProjectConfig()
 	if err != nil {
 		return err
 	}
-	vcTemplateVerificationEnabled, err := c.CheckVCTemplateVerificationEnabled(ctx, caller)
-	if err != nil || !vcTemplateVerificationEnabled {
-		return fmt.Errorf("failed to check vc template verification status: %v", err)
-	}
-	// 2. 检查写权限
-	hasPermission, err := c.CheckWriteFuncSelectorPermission(ctx, caller, "UpdateVCTemplate")
-	if err != nil {
-		return fmt.Errorf("failed to check write permission: %v", err)
-	}
-	if !hasPermission {
-		return errors.New("no permission to update vc template")
-	}
 	// 1. 校验VC模板是否存在
 	key := vcTemplateInfoPrefix + vcTemplateId
 	b, err := ctx.GetStub().GetState(key)
@@ -658,29 +476,17 @@ func (c *IssuerChaincode) UpdateVCTemplate(ctx contractapi.TransactionContextInt
 }
 
 // ChangeVCTemplateStatus 启停VC模板
-func (c *IssuerChaincode) ChangeVCTemplateStatus(ctx contractapi.TransactionContextInterface, vcTemplateId string, isDisabled bool) error {
+func (c *IssuerChaincode) ChangeVCTemplateStatus(ctx common.TransactionContextInterface, vcTemplateId string, isDisabled bool) error {
 	if strings.TrimSpace(vcTemplateId) == "" {
 		return errors.New("vcTemplateId cannot be empty")
 	}
 	// 获取调用者账户
-	caller := common.GetCaller(ctx)
+	caller := ctx.GetCaller()
 	// 4. 获取项目配置信息，用于事件通知
-	cfg, err := c.GetProjectConfig(ctx)
+	cfg, err := ctx.GetProjectConfig()
 	if err != nil {
 		return err
 	}
-	vcTemplateVerificationEnabled, err := c.CheckVCTemplateVerificationEnabled(ctx, caller)
-	if err != nil || !vcTemplateVerificationEnabled {
-		return fmt.Errorf("failed to check vc template verification status: %v", err)
-	}
-	// 检查写权限
-	hasPermission, err := c.CheckWriteFuncSelectorPermission(ctx, caller, "ChangeVCTemplateStatus")
-	if err != nil {
-		return fmt.Errorf("permission check failed: %v", err)
-	}
-	if !hasPermission {
-		return errors.New("no permission to change vc template status")
-	}
 	//校验VC模版信息是否存在
 	key := vcTemplateInfoPrefix + vcTemplateId
 	b, err := ctx.GetStub().GetState(key)
@@ -716,24 +522,10 @@ func (c *IssuerChaincode) ChangeVCTemplateStatus(ctx contractapi.TransactionCont
 }
 
 // GetVCTemplateInfo 查询VC模板信息
-func (c *IssuerChaincode) GetVCTemplateInfo(ctx contractapi.TransactionContextInterface, vcTemplateId string) (tpl VcTemplateInfo, err error) {
+func (c *IssuerChaincode) GetVCTemplateInfo(ctx common.TransactionContextInterface, vcTemplateId string) (tpl VcTemplateInfo, err error) {
 	if strings.TrimSpace(vcTemplateId) == "" {
 		return tpl, errors.New("vcTemplateId cannot be empty")
 	}
-	// 获取调用者账户
-	caller := common.GetCaller(ctx)
-	vcTemplateVerificationEnabled, err := c.CheckVCTemplateVerificationEnabled(ctx, caller)
-	if err != nil || !vcTemplateVerificationEnabled {
-		return tpl, fmt.Errorf("failed to check vc template verification status: %v", err)
-	}
-	// 检查写权限
-	hasPermission, err := c.CheckQueryFuncSelectorPermission(ctx, caller, "GetVCTemplateInfo")
-	if err != nil {
-		return tpl, fmt.Errorf("permission check failed: %v", err)
-	}
-	if !hasPermission {
-		return tpl, errors.New("no permission to change vc template status")
-	}
 	key := vcTemplateInfoPrefix + vcTemplateId
 	b, err := ctx.GetStub().GetState(key)
 	if err != nil || b == nil {
@@ -745,7 +537,7 @@ func (c *IssuerChaincode) GetVCTemplateInfo(ctx contractapi.TransactionContextIn
 }
 
 // CheckVCTemplate 校验VC模板是否存在
-func (c *IssuerChaincode) CheckVCTemplate(ctx contractapi.TransactionContextInterface, vcTemplateId string) (bool, error) {
+func (c *IssuerChaincode) CheckVCTemplate(ctx common.TransactionContextInterface, vcTemplateId string) (bool, error) {
 	if strings.TrimSpace(vcTemplateId) == "" {
 		return false, errors.New("vcTemplateId cannot be empty")
 	}
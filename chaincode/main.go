@@ -15,18 +15,28 @@ func main() {
 	// 创建权限控制合约实例
 	permissionChaincode := new(accesscontrol.PermissionChaincode)
 	permissionChaincode.Name = "permission"
-	// 设置全局权限检查器
-	common.SetGlobalPermissionChecker(permissionChaincode)
 
 	didChaincode := new(did.DIDChaincode)
 	didChaincode.Name = "did"
 
 	issuerChaincode := new(issuer.IssuerChaincode)
-	issuerChaincode.PermissionChecker = permissionChaincode
 	issuerChaincode.Name = "issuer"
 
 	vcChaincode := new(vc.VCChaincode)
 	vcChaincode.Name = "vc"
+
+	// 各合约共用自定义交易上下文，由上下文提供权限检查器、调用者身份和项目配置
+	permissionChaincode.TransactionContextHandler = new(accesscontrol.TransactionContext)
+	didChaincode.TransactionContextHandler = new(accesscontrol.TransactionContext)
+	issuerChaincode.TransactionContextHandler = new(accesscontrol.TransactionContext)
+	vcChaincode.TransactionContextHandler = new(accesscontrol.TransactionContext)
+
+	// 各合约在交易开始前根据transient字段projectCode选择操作的项目
+	// did/issuer/vc合约同时按策略表统一校验调用者权限
+	permissionChaincode.BeforeTransaction = common.SelectProject
+	didChaincode.BeforeTransaction = accesscontrol.BeforeTransaction(didChaincode.Name)
+	issuerChaincode.BeforeTransaction = accesscontrol.BeforeTransaction(issuerChaincode.Name)
+	vcChaincode.BeforeTransaction = accesscontrol.BeforeTransaction(vcChaincode.Name)
 	chaincode, err := contractapi.NewChaincode(
 		permissionChaincode,
 		didChaincode,
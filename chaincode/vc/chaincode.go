@@ -26,51 +26,31 @@ type VCInfo struct {
 // VCChaincode 结构体
 type VCChaincode struct {
 	contractapi.Contract
-	common.PermissionChecker
 }
 
 const vcInfoPrefix = "vc:info:"
 
-// ================== 内部合约调用辅助方法 ==================
+// ================== 主要业务方法 ==================
+// 调用者权限及项目/模块停用状态校验由BeforeTransaction按策略表统一完成
 
-// getProjectConfig 获取项目配置信息
-func (c *VCChaincode) getProjectConfig(ctx contractapi.TransactionContextInterface) (*common.ProjectConfig, error) {
-	permissionChecker := common.GetGlobalPermissionChecker()
-	if permissionChecker == nil {
-		return nil, fmt.Errorf("global permission checker not initialized")
-	}
-
-	return permissionChecker.GetProjectConfig(ctx)
-}
-
-// checkWriteFuncSelectorPermission 调用Permission合约的权限检查方法
-func (c *VCChaincode) checkWriteFuncSelectorPermission(ctx contractapi.TransactionContextInterface, caller, funcName string) (bool, error) {
-	// 通过全局权限检查器调用Permission合约的方法
-	permissionChecker := common.GetGlobalPermissionChecker()
-	if permissionChecker == nil {
-		return false, fmt.Errorf("global permission checker not initialized")
-	}
-
-	return permissionChecker.CheckWriteFuncSelectorPermission(ctx, caller, funcName)
-}
-
-func (c *VCChaincode) CheckIssuer(ctx contractapi.TransactionContextInterface, didId string) error {
+// CheckIssuer 校验发证方是否存在且状态正常
+func (c *VCChaincode) CheckIssuer(ctx common.TransactionContextInterface, didId string) error {
 	return new(issuer.IssuerChaincode).CheckIssuer(ctx, didId)
 }
 
 // StoreVCHash 创建VC存证
-func (c *VCChaincode) StoreVCHash(ctx contractapi.TransactionContextInterface, vcId, vcInfoStr string) error {
+func (c *VCChaincode) StoreVCHash(ctx common.TransactionContextInterface, vcId, vcInfoStr string) error {
 	log.Printf("开始创建VC存证 - VC ID: %s", vcId)
 	if strings.TrimSpace(vcId) == "" || strings.TrimSpace(vcInfoStr) == "" {
 		log.Printf("参数校验失败 - VC ID或VC信息为空")
 		return errors.New("vcId, vcInfo cannot be empty")
 	}
 	// 获取调用者账户
-	caller := common.GetCaller(ctx)
+	caller := ctx.GetCaller()
 	log.Printf("VC存证创建 - 调用者: %s", caller)
 
 	// 获取项目配置信息，用于事件通知
-	cfg, err := c.getProjectConfig(ctx)
+	cfg, err := ctx.GetProjectConfig()
 	if err != nil {
 		log.Printf("获取项目配置失败: %v", err)
 		return err
@@ -92,18 +72,6 @@ func (c *VCChaincode) StoreVCHash(ctx contractapi.TransactionContextInterface, v
 	}
 	log.Printf("VC信息校验通过")
 
-	// 检查写权限
-	hasPermission, err := c.checkWriteFuncSelectorPermission(ctx, caller, "StoreVCHash")
-	if err != nil {
-		log.Printf("权限检查失败: %v", err)
-		return fmt.Errorf("permission check failed: %v", err)
-	}
-	if !hasPermission {
-		log.Printf("权限校验失败 - 调用者: %s, 操作: StoreVCHash", caller)
-		return errors.New("no permission to create vc")
-	}
-	log.Printf("权限校验通过 - 调用者: %s, 操作: StoreVCHash", caller)
-
 	key := vcInfoPrefix + vcId
 	b, err := ctx.GetStub().GetState(key)
 	if err != nil {
@@ -148,28 +116,16 @@ func (c *VCChaincode) StoreVCHash(ctx contractapi.TransactionContextInterface, v
 }
 
 // GetVCInfo 查询VC哈希
-func (c *VCChaincode) GetVCInfo(ctx contractapi.TransactionContextInterface, vcId string) (info VCInfo, err error) {
+func (c *VCChaincode) GetVCInfo(ctx common.TransactionContextInterface, vcId string) (info VCInfo, err error) {
 	log.Printf("开始查询VC信息 - VC ID: %s", vcId)
 	if strings.TrimSpace(vcId) == "" {
 		log.Printf("参数校验失败 - VC ID为空")
 		return info, errors.New("vcId cannot be empty")
 	}
 	// 获取调用者账户
-	caller := common.GetCaller(ctx)
+	caller := ctx.GetCaller()
 	log.Printf("VC信息查询 - 调用者: %s", caller)
 
-	// 检查写权限
-	hasPermission, err := c.CheckQueryFuncSelectorPermission(ctx, caller, "GetVCInfo")
-	if err != nil {
-		log.Printf("权限检查失败: %v", err)
-		return info, fmt.Errorf("permission check failed: %v", err)
-	}
-	if !hasPermission {
-		log.Printf("权限校验失败 - 调用者: %s, 操作: GetVCInfo", caller)
-		return info, errors.New("no permission to get vc")
-	}
-	log.Printf("权限校验通过 - 调用者: %s, 操作: GetVCInfo", caller)
-
 	key := vcInfoPrefix + vcId
 	b, err := ctx.GetStub().GetState(key)
 	if err != nil || b == nil {
@@ -183,35 +139,23 @@ func (c *VCChaincode) GetVCInfo(ctx contractapi.TransactionContextInterface, vcI
 }
 
 // RevokeVC 吊销VC
-func (c *VCChaincode) RevokedVC(ctx contractapi.TransactionContextInterface, vcId string, isRevoked bool) error {
+func (c *VCChaincode) RevokedVC(ctx common.TransactionContextInterface, vcId string, isRevoked bool) error {
 	log.Printf("开始吊销VC - VC ID: %s, 吊销状态: %t", vcId, isRevoked)
 	if strings.TrimSpace(vcId) == "" {
 		log.Printf("参数校验失败 - VC ID为空")
 		return errors.New("vcId cannot be empty")
 	}
 	// 获取调用者账户
-	caller := common.GetCaller(ctx)
+	caller := ctx.GetCaller()
 	log.Printf("VC吊销操作 - 调用者: %s", caller)
 
 	// 获取项目配置信息，用于事件通知
-	cfg, err := c.getProjectConfig(ctx)
+	cfg, err := ctx.GetProjectConfig()
 	if err != nil {
 		log.Printf("获取项目配置失败: %v", err)
 		return err
 	}
 
-	// 检查写权限
-	hasPermission, err := c.checkWriteFuncSelectorPermission(ctx, caller, "RevokedVC")
-	if err != nil {
-		log.Printf("权限检查失败: %v", err)
-		return fmt.Errorf("permission check failed: %v", err)
-	}
-	if !hasPermission {
-		log.Printf("权限校验失败 - 调用者: %s, 操作: RevokedVC", caller)
-		return errors.New("no permission to revoke vc")
-	}
-	log.Printf("权限校验通过 - 调用者: %s, 操作: RevokedVC", caller)
-
 	key := vcInfoPrefix + vcId
 	b, err := ctx.GetStub().GetState(key)
 	if err != nil || b == nil {
@@ -244,7 +188,7 @@ func (c *VCChaincode) RevokedVC(ctx contractapi.TransactionContextInterface, vcI
 }
 
 // GetVCRevokedStatus 查询VC吊销状态
-func (c *VCChaincode) GetVCRevokedStatus(ctx contractapi.TransactionContextInterface, vcId string) (bool, error) {
+func (c *VCChaincode) GetVCRevokedStatus(ctx common.TransactionContextInterface, vcId string) (bool, error) {
 	log.Printf("开始查询VC吊销状态 - VC ID: %s", vcId)
 	if strings.TrimSpace(vcId) == "" {
 		log.Printf("参数校验失败 - VC ID为空")